@@ -0,0 +1,82 @@
+package main
+
+import (
+	"chatbtc/config"
+	"chatbtc/models"
+	"chatbtc/services"
+	"flag"
+	"log"
+	"strings"
+	"time"
+)
+
+// runBackfill xử lý lệnh: cryptobot backfill -from 2024-01-01 [-to 2024-06-01] [-symbols BTCUSDT,ETHUSDT] [-intervals 1h,4h]
+// Chạy lại cùng tham số sẽ tiếp tục từ checkpoint đã lưu.
+func runBackfill(args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	symbolsFlag := flags.String("symbols", "", "Danh sách symbol, phân tách bằng dấu phẩy (mặc định: tất cả symbol đang giao dịch)")
+	intervalsFlag := flags.String("intervals", "1h", "Danh sách interval, phân tách bằng dấu phẩy")
+	fromFlag := flags.String("from", "", "Ngày bắt đầu (UTC), định dạng 2006-01-02")
+	toFlag := flags.String("to", "", "Ngày kết thúc (UTC, không bao gồm), mặc định là hôm nay")
+	workers := flags.Int("workers", 2, "Số symbol backfill song song")
+	flags.Parse(args)
+
+	config.LoadConfig()
+
+	from, err := time.Parse("2006-01-02", *fromFlag)
+	if err != nil {
+		log.Fatalf("❌ -from không hợp lệ (định dạng 2006-01-02): %q", *fromFlag)
+	}
+	// Mặc định lấy 00:00 UTC hôm nay để chạy lại trong ngày vẫn dùng được checkpoint
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if *toFlag != "" {
+		if to, err = time.Parse("2006-01-02", *toFlag); err != nil {
+			log.Fatalf("❌ -to không hợp lệ (định dạng 2006-01-02): %q", *toFlag)
+		}
+	}
+
+	if err := models.InitDatabase(); err != nil {
+		log.Fatalf("❌ Lỗi kết nối database: %v", err)
+	}
+	defer models.CloseDatabase()
+	if err := models.MigrateDatabase(); err != nil {
+		log.Fatalf("❌ Lỗi migrate database: %v", err)
+	}
+
+	repos := models.NewRepositories()
+	symbols := splitList(*symbolsFlag)
+	if len(symbols) == 0 {
+		if symbols, err = repos.Symbol.GetAllSymbols(config.AppConfig.QuoteAssets); err != nil {
+			log.Fatalf("❌ Lỗi lấy danh sách symbol: %v", err)
+		}
+	}
+	intervals := splitList(*intervalsFlag)
+
+	_, exchangeClient := newExchangeClient(repos.KlineQuarantine)
+	summary, err := services.NewBackfillService(exchangeClient, repos, *workers).Run(symbols, intervals, from, to)
+	if err != nil {
+		log.Fatalf("❌ Lỗi backfill: %v", err)
+	}
+	log.Println(summary)
+	log.Printf("Rate limit Binance: %s", exchangeClient.RateLimitBudget())
+
+	// Nến cũ được ghi vào partition default, chuyển chúng sang partition tháng tương ứng (không xóa partition nào)
+	partitions, err := models.NewPartitionManager(config.AppConfig.PartitionMonthsAhead).Maintain(time.Now(), 0)
+	if err != nil {
+		log.Fatalf("❌ Lỗi tạo partition: %v", err)
+	}
+	if len(partitions.Created) > 0 {
+		log.Printf("🗂️ Đã tạo %d partition: %s", len(partitions.Created), strings.Join(partitions.Created, ", "))
+	}
+}
+
+// splitList tách chuỗi phân tách bằng dấu phẩy, bỏ phần tử rỗng
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -5,27 +5,56 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	TelegramBotToken string
-	TelegramChatID   string
-	ProxyEnabled     bool
-	ProxyType        string
-	ProxyURL         string
-	ProxyUsername    string
-	ProxyPassword    string
-	CryptoAPIKey     string
-	CryptoAPIURL     string
-	ServerPort       string
-	LogLevel         string
-	DBHost           string
-	DBPort           string
-	DBName           string
-	DBUser           string
-	DBPassword       string
+	TelegramBotToken     string
+	TelegramChatID       string
+	ProxyEnabled         bool
+	ProxyType            string
+	ProxyURL             string
+	ProxyUsername        string
+	ProxyPassword        string
+	CryptoAPIKey         string
+	CryptoAPIURL         string
+	BinanceAPIURL        string
+	BinanceWSURL         string
+	BinanceWeight        int
+	VolumeWorkers        int
+	SymbolRefresh        time.Duration
+	QuoteAssets          []string
+	VolumeMinUSD         float64
+	VolumeInterval       string
+	VolumePatternCandles string
+	KlineStream          bool
+	ServerPort           string
+	LogLevel             string
+	DBDriver             string // postgres hoặc sqlite
+	DBPath               string // File database khi dùng sqlite
+	DBSSLMode            string
+	DBHost               string
+	DBPort               string
+	DBName               string
+	DBUser               string
+	DBPassword           string
+
+	// Thời gian giữ dữ liệu (số ngày, 0 là giữ mãi) và chu kỳ chạy dọn dẹp
+	RetentionInterval     time.Duration
+	RetentionAnalysisDays int
+	RetentionNotifyDays   int
+	RetentionCandleDays   int
+	CompactMinuteDays     int // Nến 1m cũ hơn số ngày này được gộp thành 1h rồi xóa, 0 là không gộp
+	PartitionMonthsAhead  int // Số tháng tới được tạo sẵn partition price_histories (chỉ PostgreSQL)
+
+	// Cache nến trong bộ nhớ dùng chung cho /analyze và volume screener
+	CandleCacheSize   int // Số nến tối đa mỗi (symbol, interval), 0 là tắt cache
+	CandleCacheMaxAge time.Duration
+
+	// Điều kiện Stochastic/StochRSI bắt buộc để gửi cảnh báo volume (phân tách bằng dấu phẩy), rỗng là không lọc
+	VolumeAlertConditions string
 }
 
 var AppConfig *Config
@@ -50,22 +79,47 @@ func LoadConfig() {
 	}
 
 	AppConfig = &Config{
-		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
-		TelegramChatID:   getEnv("TELEGRAM_CHAT_ID", ""),
-		ProxyEnabled:     getEnvAsBool("PROXY_ENABLED", false),
-		ProxyType:        proxyType,
-		ProxyURL:         proxyURL,
-		ProxyUsername:    getEnv("PROXY_USERNAME", ""),
-		ProxyPassword:    getEnv("PROXY_PASSWORD", ""),
-		CryptoAPIKey:     getEnv("CRYPTO_API_KEY", ""),
-		CryptoAPIURL:     getEnv("CRYPTO_API_URL", "https://api.coingecko.com/api/v3"),
-		ServerPort:       getEnv("SERVER_PORT", "8080"),
-		LogLevel:         getEnv("LOG_LEVEL", "info"),
-		DBHost:           getEnv("DB_HOST", ""),
-		DBPort:           getEnv("DB_PORT", "5432"),
-		DBName:           getEnv("DB_NAME", "cryptobot"),
-		DBUser:           getEnv("DB_USER", "postgres"),
-		DBPassword:       getEnv("DB_PASSWORD", ""),
+		TelegramBotToken:     getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:       getEnv("TELEGRAM_CHAT_ID", ""),
+		ProxyEnabled:         getEnvAsBool("PROXY_ENABLED", false),
+		ProxyType:            proxyType,
+		ProxyURL:             proxyURL,
+		ProxyUsername:        getEnv("PROXY_USERNAME", ""),
+		ProxyPassword:        getEnv("PROXY_PASSWORD", ""),
+		CryptoAPIKey:         getEnv("CRYPTO_API_KEY", ""),
+		CryptoAPIURL:         getEnv("CRYPTO_API_URL", "https://api.coingecko.com/api/v3"),
+		BinanceAPIURL:        strings.TrimSuffix(getEnv("BINANCE_API_URL", "https://api.binance.com"), "/"),
+		BinanceWSURL:         strings.TrimSuffix(getEnv("BINANCE_WS_URL", "wss://stream.binance.com:9443"), "/"),
+		KlineStream:          getEnvAsBool("KLINE_STREAM_ENABLED", false),
+		BinanceWeight:        getEnvAsInt("BINANCE_WEIGHT_LIMIT", 6000),
+		VolumeWorkers:        getEnvAsInt("VOLUME_WORKERS", 8),
+		SymbolRefresh:        getEnvAsDuration("SYMBOL_REFRESH_INTERVAL", 5*time.Minute),
+		QuoteAssets:          getEnvAsList("QUOTE_ASSETS", []string{"USDT"}),
+		VolumeMinUSD:         getEnvAsFloat("VOLUME_MIN_USD", 0),
+		VolumeInterval:       getEnv("VOLUME_INTERVAL", "1h"),
+		VolumePatternCandles: getEnv("VOLUME_PATTERN_CANDLES", ""),
+		ServerPort:           getEnv("SERVER_PORT", "8080"),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		DBDriver:             getEnv("DB_DRIVER", "postgres"),
+		DBPath:               getEnv("DB_PATH", "cryptobot.db"),
+		DBSSLMode:            getEnv("DB_SSLMODE", "disable"),
+		DBHost:               getEnv("DB_HOST", ""),
+		DBPort:               getEnv("DB_PORT", "5432"),
+		DBName:               getEnv("DB_NAME", "cryptobot"),
+		DBUser:               getEnv("DB_USER", "postgres"),
+		DBPassword:           getEnv("DB_PASSWORD", ""),
+
+		RetentionInterval:     getEnvAsDuration("RETENTION_INTERVAL", 24*time.Hour),
+		RetentionAnalysisDays: getEnvAsInt("RETENTION_ANALYSIS_DAYS", 90),
+		RetentionNotifyDays:   getEnvAsInt("RETENTION_NOTIFICATION_DAYS", 30),
+		RetentionCandleDays:   getEnvAsInt("RETENTION_CANDLE_DAYS", 0),
+		CompactMinuteDays:     getEnvAsInt("COMPACT_1M_CANDLE_DAYS", 30),
+		PartitionMonthsAhead:  getEnvAsInt("PARTITION_MONTHS_AHEAD", 3),
+
+		CandleCacheSize:   getEnvAsInt("CANDLE_CACHE_SIZE", 1000),
+		CandleCacheMaxAge: getEnvAsDuration("CANDLE_CACHE_MAX_AGE", 30*time.Second),
+
+		VolumeAlertConditions: getEnv("VOLUME_ALERT_CONDITIONS", ""),
 	}
 }
 
@@ -84,3 +138,48 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvAsInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList đọc danh sách phân tách bằng dấu phẩy, chuẩn hoá về chữ hoa
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			result = append(result, item)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
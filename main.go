@@ -4,6 +4,7 @@ import (
 	"chatbtc/config"
 	"chatbtc/models"
 	"chatbtc/services"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
@@ -13,6 +14,18 @@ import (
 )
 
 func main() {
+	// Lệnh phụ chạy một lần rồi thoát, ví dụ: cryptobot backfill -from 2024-01-01
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			runBackfill(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		}
+	}
+
 	http.HandleFunc("/health", healthCheck)
 	go func() {
 		log.Println("Healthcheck server running at :8080/health")
@@ -34,27 +47,64 @@ func main() {
 	}
 	defer models.CloseDatabase()
 
-	// Auto migrate database
-	if err := models.AutoMigrate(); err != nil {
+	// Kiểm tra phiên bản schema và chạy các migration còn thiếu
+	if err := models.MigrateDatabase(); err != nil {
 		log.Fatalf("❌ Lỗi migrate database: %v", err)
 	}
 
+	repos := models.NewRepositories()
+	rateLimiter, exchangeClient := newExchangeClient(repos.KlineQuarantine)
+	http.HandleFunc("/metrics/ratelimit", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rateLimiter.RateLimitBudget())
+	})
+
+	// Cache nến dùng chung cho /analyze, volume screener và stream
+	candleCache := services.NewCandleCache(config.AppConfig.CandleCacheSize, config.AppConfig.CandleCacheMaxAge)
+	http.HandleFunc("/metrics/candlecache", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(candleCache.Stats())
+	})
+
+	// Chỉ báo tính dần theo nến đã đóng, trạng thái lưu trong indicator_snapshots
+	indicatorEngine := services.NewIndicatorEngine(repos)
+
 	// Khởi tạo Telegram bot service
-	botService, err := services.NewTelegramBotService()
+	botService, err := services.NewTelegramBotService(exchangeClient, repos, candleCache)
 	if err != nil {
 		log.Fatalf("❌ Lỗi khởi tạo bot: %v", err)
 	}
+	botService.SetIndicatorEngine(indicatorEngine)
 
-	fetchService := services.NewFetcherService()
-	scheduler := services.NewScheduler(fetchService)
+	fetchService := services.NewFetcherService(exchangeClient, botService, repos.Symbol)
+	scheduler := services.NewScheduler(fetchService, config.AppConfig.SymbolRefresh)
 	go scheduler.Start()
 
-	autoVolumeService := services.NewAutoVolumeService(botService)
-	scheduler2 := services.NewScheduler2(autoVolumeService)
-	go scheduler2.Start()
+	autoVolumeService := services.NewAutoVolumeService(botService, exchangeClient, repos, candleCache)
+	autoVolumeService.SetIndicatorEngine(indicatorEngine)
+	// Nhận nến qua WebSocket nếu được bật, ngược lại poll REST mỗi giờ
+	var scheduler2 *services.Scheduler2
+	var klineStream *services.KlineStreamService
+	if config.AppConfig.KlineStream {
+		klineStream = services.NewKlineStreamService(config.AppConfig.BinanceWSURL, autoVolumeService, repos, candleCache)
+		go klineStream.Start()
+	} else {
+		scheduler2 = services.NewScheduler2(autoVolumeService)
+		go scheduler2.Start()
+	}
 	scheduler3 := services.NewScheduler3(autoVolumeService, botService.GetChannelID())
 	go scheduler3.Start()
 
+	// Dọn dữ liệu hết hạn và gộp nến 1m cũ thành 1h theo RETENTION_*
+	retentionService := services.NewRetentionService(repos, services.RetentionPolicyFromConfig(config.AppConfig))
+	retentionService.SetPartitionManager(models.NewPartitionManager(config.AppConfig.PartitionMonthsAhead))
+	retentionScheduler := services.NewRetentionScheduler(retentionService, config.AppConfig.RetentionInterval)
+	go retentionScheduler.Start()
+	http.HandleFunc("/metrics/retention", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(retentionScheduler.LastReport())
+	})
+
 	// Tạo channel để nhận tín hiệu dừng
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
@@ -70,13 +120,31 @@ func main() {
 	log.Println("🛑 Đang dừng bot...")
 	// Gọi Stop cho các service nếu có
 	scheduler.Stop()
-	scheduler2.Stop()
+	if klineStream != nil {
+		klineStream.Stop()
+	} else {
+		scheduler2.Stop()
+	}
 	scheduler3.Stop()
+	retentionScheduler.Stop()
 	time.Sleep(2 * time.Second)
 	log.Println("🛑 Bot đã dừng")
 
 }
 
+// newExchangeClient khởi tạo client gọi API sàn (base URL cấu hình qua BINANCE_API_URL),
+// mọi request dùng chung một transport theo dõi rate limit. Nến không hợp lệ được lưu vào quarantine.
+func newExchangeClient(quarantine models.KlineQuarantineStore) (*services.RateLimitedTransport, *services.BinanceClient) {
+	rateLimiter := services.NewRateLimitedTransport(http.DefaultTransport, config.AppConfig.BinanceWeight)
+	exchangeClient := services.NewBinanceClient(config.AppConfig.BinanceAPIURL, &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: rateLimiter,
+	})
+	// Nến không hợp lệ từ sàn được lưu vào bảng quarantined_klines
+	exchangeClient.SetQuarantine(quarantine)
+	return rateLimiter, exchangeClient
+}
+
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
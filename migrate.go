@@ -0,0 +1,76 @@
+package main
+
+import (
+	"chatbtc/config"
+	"chatbtc/models"
+	"flag"
+	"log"
+	"strings"
+)
+
+// runMigrate xử lý lệnh: cryptobot migrate [up|down|status] [-to 3] [-steps 1] [-dry-run]
+// up chạy các migration còn thiếu (tới version -to nếu có), down hoàn tác -steps migration gần nhất.
+func runMigrate(args []string) {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	target := flags.Int("to", 0, "Version đích khi chạy up (mặc định: mới nhất)")
+	steps := flags.Int("steps", 1, "Số migration hoàn tác khi chạy down")
+	dryRun := flags.Bool("dry-run", false, "Chỉ in SQL sẽ chạy, không thay đổi database")
+	flags.Parse(args)
+
+	config.LoadConfig()
+	if err := models.InitDatabase(); err != nil {
+		log.Fatalf("❌ Lỗi kết nối database: %v", err)
+	}
+	defer models.CloseDatabase()
+
+	migrator, err := models.NewMigrator()
+	if err != nil {
+		log.Fatalf("❌ Lỗi đọc migration: %v", err)
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(*target, *dryRun)
+		if err != nil {
+			log.Fatalf("❌ Lỗi migrate: %v", err)
+		}
+		log.Printf("✅ %d migration đã chạy%s", len(applied), dryRunSuffix(*dryRun))
+	case "down":
+		if *steps <= 0 {
+			log.Fatalf("❌ -steps phải lớn hơn 0")
+		}
+		reverted, err := migrator.Down(*steps, *dryRun)
+		if err != nil {
+			log.Fatalf("❌ Lỗi hoàn tác migration: %v", err)
+		}
+		log.Printf("✅ %d migration đã hoàn tác%s", len(reverted), dryRunSuffix(*dryRun))
+	case "status":
+		current, err := migrator.Check()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("📊 Schema version %d/%d, %d migration chưa chạy", current, migrator.LatestVersion(), len(pending))
+		for _, migration := range pending {
+			log.Printf("  - %s", migration)
+		}
+	default:
+		log.Fatalf("❌ Lệnh migrate không hợp lệ: %q (dùng up, down hoặc status)", action)
+	}
+}
+
+// dryRunSuffix ghi chú kết quả khi chạy ở chế độ dry-run
+func dryRunSuffix(dryRun bool) string {
+	if dryRun {
+		return " (dry-run, database không thay đổi)"
+	}
+	return ""
+}
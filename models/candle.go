@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// NewPriceHistoryFromKline chuyển một nến từ Binance sang PriceHistory để lưu vào kho nến
+func NewPriceHistoryFromKline(symbol, interval string, k KlineData) (PriceHistory, error) {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"open", k.Open}, {"high", k.High}, {"low", k.Low}, {"close", k.Close},
+		{"volume", k.Volume}, {"quote volume", k.QuoteAssetVolume},
+		{"taker buy base volume", k.TakerBuyBaseAssetVolume}, {"taker buy quote volume", k.TakerBuyQuoteAssetVolume},
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field.value, 64)
+		if err != nil {
+			return PriceHistory{}, fmt.Errorf("nến %s %s %d có %s không hợp lệ: %q", symbol, interval, k.OpenTime, field.name, field.value)
+		}
+		values[i] = value
+	}
+
+	return PriceHistory{
+		Symbol:              symbol,
+		Interval:            interval,
+		OpenTime:            time.UnixMilli(k.OpenTime).UTC(),
+		CloseTime:           time.UnixMilli(k.CloseTime).UTC(),
+		Open:                values[0],
+		High:                values[1],
+		Low:                 values[2],
+		Close:               values[3],
+		Volume:              values[4],
+		QuoteVolume:         values[5],
+		Trades:              k.NumberOfTrades,
+		TakerBuyBaseVolume:  values[6],
+		TakerBuyQuoteVolume: values[7],
+	}, nil
+}
+
+// ToKline chuyển PriceHistory về dạng KlineData để dùng chung với các hàm phân tích
+func (p PriceHistory) ToKline() KlineData {
+	return KlineData{
+		OpenTime:                 p.OpenTime.UnixMilli(),
+		Open:                     formatFloat(p.Open),
+		High:                     formatFloat(p.High),
+		Low:                      formatFloat(p.Low),
+		Close:                    formatFloat(p.Close),
+		Volume:                   formatFloat(p.Volume),
+		CloseTime:                p.CloseTime.UnixMilli(),
+		QuoteAssetVolume:         formatFloat(p.QuoteVolume),
+		NumberOfTrades:           p.Trades,
+		TakerBuyBaseAssetVolume:  formatFloat(p.TakerBuyBaseVolume),
+		TakerBuyQuoteAssetVolume: formatFloat(p.TakerBuyQuoteVolume),
+		Ignore:                   "0",
+	}
+}
+
+// PriceHistoriesToKlines chuyển danh sách nến đã lưu về KlineData, giữ nguyên thứ tự
+func PriceHistoriesToKlines(histories []PriceHistory) []KlineData {
+	klines := make([]KlineData, 0, len(histories))
+	for _, history := range histories {
+		klines = append(klines, history.ToKline())
+	}
+	return klines
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Validate kiểm tra nến có hợp lệ không: số liệu parse được, high >= low,
+// open/close nằm trong [low, high], volume không âm và close_time sau open_time
+func (k KlineData) Validate() error {
+	openPrice, err := parseKlineNumber("open", k.Open)
+	if err != nil {
+		return err
+	}
+	highPrice, err := parseKlineNumber("high", k.High)
+	if err != nil {
+		return err
+	}
+	lowPrice, err := parseKlineNumber("low", k.Low)
+	if err != nil {
+		return err
+	}
+	closePrice, err := parseKlineNumber("close", k.Close)
+	if err != nil {
+		return err
+	}
+	if lowPrice <= 0 {
+		return fmt.Errorf("low phải lớn hơn 0: %q", k.Low)
+	}
+	if highPrice < lowPrice {
+		return fmt.Errorf("high (%s) nhỏ hơn low (%s)", k.High, k.Low)
+	}
+	if openPrice < lowPrice || openPrice > highPrice {
+		return fmt.Errorf("open (%s) nằm ngoài khoảng low-high (%s - %s)", k.Open, k.Low, k.High)
+	}
+	if closePrice < lowPrice || closePrice > highPrice {
+		return fmt.Errorf("close (%s) nằm ngoài khoảng low-high (%s - %s)", k.Close, k.Low, k.High)
+	}
+
+	volumes := []struct {
+		name  string
+		value string
+	}{
+		{"volume", k.Volume}, {"quote volume", k.QuoteAssetVolume},
+		{"taker buy base volume", k.TakerBuyBaseAssetVolume}, {"taker buy quote volume", k.TakerBuyQuoteAssetVolume},
+	}
+	for _, volume := range volumes {
+		value, err := parseKlineNumber(volume.name, volume.value)
+		if err != nil {
+			return err
+		}
+		if value < 0 {
+			return fmt.Errorf("%s âm: %q", volume.name, volume.value)
+		}
+	}
+
+	if k.NumberOfTrades < 0 {
+		return fmt.Errorf("số giao dịch âm: %d", k.NumberOfTrades)
+	}
+	if k.CloseTime <= k.OpenTime {
+		return fmt.Errorf("close_time (%d) không sau open_time (%d)", k.CloseTime, k.OpenTime)
+	}
+	return nil
+}
+
+// parseKlineNumber parse một trường số dạng chuỗi của nến, từ chối NaN/Inf
+func parseKlineNumber(name, raw string) (float64, error) {
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%s không hợp lệ: %q", name, raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestKlineDataValidate(t *testing.T) {
+	valid := KlineData{
+		OpenTime: 1700000000000, CloseTime: 1700003599999,
+		Open: "100", High: "102", Low: "99", Close: "101",
+		Volume: "10", QuoteAssetVolume: "1000", NumberOfTrades: 5,
+		TakerBuyBaseAssetVolume: "5", TakerBuyQuoteAssetVolume: "500",
+	}
+	tests := []struct {
+		name    string
+		modify  func(k *KlineData)
+		wantErr bool
+	}{
+		{"valid", func(k *KlineData) {}, false},
+		{"close on high", func(k *KlineData) { k.Close = "102" }, false},
+		{"high below low", func(k *KlineData) { k.High, k.Low = "98", "99" }, true},
+		{"zero low", func(k *KlineData) { k.Low, k.Open = "0", "0" }, true},
+		{"negative volume", func(k *KlineData) { k.Volume = "-1" }, true},
+		{"negative taker volume", func(k *KlineData) { k.TakerBuyQuoteAssetVolume = "-0.5" }, true},
+		{"close above high", func(k *KlineData) { k.Close = "103" }, true},
+		{"close below low", func(k *KlineData) { k.Close = "98.5" }, true},
+		{"open outside range", func(k *KlineData) { k.Open = "110" }, true},
+		{"unparsable price", func(k *KlineData) { k.Open = "abc" }, true},
+		{"NaN price", func(k *KlineData) { k.High = "NaN" }, true},
+		{"negative trades", func(k *KlineData) { k.NumberOfTrades = -1 }, true},
+		{"close time before open time", func(k *KlineData) { k.CloseTime = k.OpenTime }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kline := valid
+			tt.modify(&kline)
+			if err := kline.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -16,6 +16,35 @@ const (
 	EMA_MEDIUM = 21 // EMA trung hạn
 	EMA_LONG   = 50 // EMA dài hạn
 
+	// MACD
+	MACD_FAST   = 12 // EMA nhanh
+	MACD_SLOW   = 26 // EMA chậm
+	MACD_SIGNAL = 9  // EMA của đường MACD
+
+	// ATR
+	ATR_PERIOD = 14 // Average True Range (Wilder)
+
+	// Bollinger Bands & Keltner Channels
+	BB_PERIOD        = 20  // SMA giữa của Bollinger Bands
+	BB_STDDEV        = 2.0 // Số độ lệch chuẩn của dải Bollinger
+	KELTNER_PERIOD   = 20  // EMA giữa của Keltner Channels
+	KELTNER_ATR_MULT = 1.5 // Dải Keltner = EMA ± 1.5 x ATR
+	SQUEEZE_LOOKBACK = 50  // Số nến so sánh bandwidth khi tìm squeeze
+	EXPANSION_RATIO  = 1.5 // Bandwidth gấp 1.5 lần trung bình là biến động mở rộng
+
+	// ADX/DMI
+	ADX_PERIOD = 14 // Số kỳ làm mượt DI và ADX (Wilder)
+	ADX_WEAK   = 20 // ADX dưới 20: không có xu hướng rõ
+	ADX_STRONG = 40 // ADX từ 40: xu hướng mạnh
+
+	// Stochastic & Stochastic RSI
+	STOCH_PERIOD     = 14 // Số nến tìm đỉnh/đáy của %K
+	STOCH_SMOOTH     = 3  // SMA làm mượt %K
+	STOCH_D_PERIOD   = 3  // %D là SMA của %K
+	STOCH_RSI_PERIOD = 14 // RSI dùng cho Stochastic RSI
+	STOCH_OVERBOUGHT = 80 // %K trên 80: quá mua
+	STOCH_OVERSOLD   = 20 // %K dưới 20: quá bán
+
 	// Volume Analysis
 	VOLUME_SMA_PERIOD = 21  // SMA của Volume (21 kỳ)
 	VOLUME_SPIKE_1_5X = 1.5 // Volume spike 1.5x
@@ -52,6 +81,18 @@ type KlineData struct {
 	Ignore                   string `json:"ignore"`
 }
 
+// BinanceTicker24h đại diện cho dữ liệu ticker 24h từ Binance API
+type BinanceTicker24h struct {
+	Symbol             string `json:"symbol"`
+	PriceChange        string `json:"priceChange"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	LastPrice          string `json:"lastPrice"`
+	Volume             string `json:"volume"`
+	QuoteVolume        string `json:"quoteVolume"`
+	OpenTime           int64  `json:"openTime"`
+	CloseTime          int64  `json:"closeTime"`
+}
+
 // PriceData đại diện cho dữ liệu giá theo thời gian
 type PriceData struct {
 	Timestamp time.Time       `json:"timestamp"`
@@ -87,9 +128,13 @@ type UserSession struct {
 
 type TrendAnalysis struct {
 	Direction      string // "bullish", "bearish", "sideways"
-	Strength       string // "strong", "moderate", "weak"
+	Strength       string // "strong", "moderate", "weak" theo ADX, "unknown" nếu chưa đủ nến
 	Signals        []string
 	Recommendation string
+
+	ADX     float64 // ADX(14), 0 nếu chưa đủ nến
+	PlusDI  float64 // +DI(14)
+	MinusDI float64 // −DI(14)
 }
 
 // Volume Analysis Structure
@@ -102,6 +147,32 @@ type VolumeAnalysis struct {
 	Confirmation   string
 }
 
+// VolatilityAnalysis chứa Bollinger Bands, ATR, Keltner Channels của nến cuối và trạng thái biến động
+type VolatilityAnalysis struct {
+	BBUpper       float64
+	BBMiddle      float64
+	BBLower       float64
+	PercentB      float64 // Vị trí giá trong dải Bollinger: 0 là dải dưới, 1 là dải trên
+	Bandwidth     float64 // (BBUpper - BBLower) / BBMiddle
+	ATR           float64
+	KeltnerUpper  float64
+	KeltnerMiddle float64
+	KeltnerLower  float64
+	Squeeze       bool   // Bollinger nằm trong Keltner hoặc bandwidth thấp nhất SQUEEZE_LOOKBACK nến
+	Expansion     bool   // Bandwidth đang tăng, vừa thoát squeeze hoặc gấp EXPANSION_RATIO lần trung bình
+	State         string // "squeeze", "expansion", "normal", rỗng nếu không đủ dữ liệu
+}
+
+// OscillatorAnalysis chứa Stochastic và Stochastic RSI (thang 0-100) của nến cuối cùng các điều kiện đang khớp
+type OscillatorAnalysis struct {
+	StochK     float64
+	StochD     float64
+	StochRSIK  float64
+	StochRSID  float64
+	Ready      bool     // Đủ nến để tính cả Stochastic và Stochastic RSI
+	Conditions []string // Các điều kiện khớp ở nến cuối, ví dụ stoch_bullish_cross
+}
+
 // AnalysisData chứa dữ liệu phân tích chi tiết
 type AnalysisData struct {
 	Symbol         string
@@ -120,4 +191,7 @@ type AnalysisData struct {
 	Recommendation string
 	VolumeSignal   string
 	VolumeAnalysis VolumeAnalysis
+
+	VolatilityAnalysis VolatilityAnalysis
+	OscillatorAnalysis OscillatorAnalysis
 }
@@ -14,18 +14,14 @@ import (
 
 var DB *gorm.DB
 
-// InitDatabase khởi tạo kết nối database
+// InitDatabase khởi tạo kết nối database theo DB_DRIVER (postgres hoặc sqlite)
 func InitDatabase() error {
 	cfg := config.AppConfig
 
-	// Tạo connection string
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable ",
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBName,
-	)
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return err
+	}
 
 	// Cấu hình GORM logger
 	gormConfig := &gorm.Config{
@@ -33,8 +29,7 @@ func InitDatabase() error {
 	}
 
 	// Kết nối database
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), gormConfig)
+	DB, err = gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return fmt.Errorf("không thể kết nối database: %v", err)
 	}
@@ -46,14 +41,39 @@ func InitDatabase() error {
 	}
 
 	// Cấu hình connection pool
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	if cfg.DBDriver == "sqlite" {
+		// SQLite chỉ cho một connection ghi tại một thời điểm
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+	}
 
-	log.Println("✅ Kết nối database PostgreSQL thành công")
+	log.Printf("✅ Kết nối database %s thành công", cfg.DBDriver)
 	return nil
 }
 
+// openDialector tạo dialector GORM cho driver được cấu hình
+func openDialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case "postgres":
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			cfg.DBHost,
+			cfg.DBPort,
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBName,
+			cfg.DBSSLMode,
+		)
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		return openSQLite(cfg.DBPath)
+	default:
+		return nil, fmt.Errorf("DB_DRIVER không hỗ trợ: %q (dùng postgres hoặc sqlite)", cfg.DBDriver)
+	}
+}
+
 // CloseDatabase đóng kết nối database
 func CloseDatabase() error {
 	if DB != nil {
@@ -66,23 +86,25 @@ func CloseDatabase() error {
 	return nil
 }
 
-// AutoMigrate tự động tạo/migrate các bảng
-func AutoMigrate() error {
-	log.Println("🔄 Đang migrate database...")
+// MigrateDatabase kiểm tra phiên bản schema rồi chạy các migration còn thiếu.
+// Trả về lỗi nếu database có schema mới hơn bản build đang chạy.
+func MigrateDatabase() error {
+	migrator, err := NewMigrator()
+	if err != nil {
+		return err
+	}
 
-	err := DB.AutoMigrate(
-		&AnalysisRecord{},
-		&PriceHistory{},
-		&Symbol{},
-		&DataUpdate{},
-		&AutoVolumeRecord{},
-		&NotificationLog{},
-	)
+	current, err := migrator.Check()
+	if err != nil {
+		return err
+	}
+	log.Printf("🔄 Schema database ở version %d, mới nhất là %d", current, migrator.LatestVersion())
 
+	applied, err := migrator.Up(0, false)
 	if err != nil {
-		return fmt.Errorf("lỗi migrate database: %v", err)
+		return err
 	}
 
-	log.Println("✅ Migrate database hoàn thành")
+	log.Printf("✅ Migrate database hoàn thành (%d migration mới)", len(applied))
 	return nil
 }
@@ -0,0 +1,740 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Các repository lưu trong bộ nhớ, cùng hành vi với repository dùng database
+// (thứ tự sắp xếp, unique key, gorm.ErrRecordNotFound), an toàn khi dùng từ nhiều goroutine.
+
+// limitRecords cắt danh sách theo limit như LIMIT của GORM (limit âm là không giới hạn)
+func limitRecords[T any](records []T, limit int) []T {
+	if limit >= 0 && len(records) > limit {
+		return records[:limit]
+	}
+	return records
+}
+
+// stampTimes gán CreatedAt/UpdatedAt khi còn trống như GORM làm lúc tạo bản ghi
+func stampTimes(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt != nil && createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt != nil && updatedAt.IsZero() {
+		*updatedAt = now
+	}
+}
+
+// MemoryAnalysisRepository lưu lịch sử phân tích trong bộ nhớ
+type MemoryAnalysisRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	records []AnalysisRecord
+}
+
+// NewMemoryAnalysisRepository tạo instance mới
+func NewMemoryAnalysisRepository() *MemoryAnalysisRepository {
+	return &MemoryAnalysisRepository{}
+}
+
+// Create lưu record phân tích mới
+func (r *MemoryAnalysisRepository) Create(record *AnalysisRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	record.ID = r.nextID
+	stampTimes(&record.CreatedAt, &record.UpdatedAt)
+	r.records = append(r.records, *record)
+	return nil
+}
+
+// GetBySymbolAndInterval lấy lịch sử phân tích theo symbol và interval, mới nhất trước
+func (r *MemoryAnalysisRepository) GetBySymbolAndInterval(symbol, interval string, limit int) ([]AnalysisRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []AnalysisRecord
+	for _, record := range r.records {
+		if record.Symbol == symbol && record.Interval == interval {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	return limitRecords(records, limit), nil
+}
+
+// GetLatestAnalysis lấy phân tích mới nhất
+func (r *MemoryAnalysisRepository) GetLatestAnalysis(symbol, interval string) (*AnalysisRecord, error) {
+	records, _ := r.GetBySymbolAndInterval(symbol, interval, 1)
+	if len(records) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &records[0], nil
+}
+
+// DeleteOlderThan xóa các phân tích tạo trước cutoff, trả về số dòng đã xóa
+func (r *MemoryAnalysisRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.records[:0]
+	for _, record := range r.records {
+		if !record.CreatedAt.Before(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := int64(len(r.records) - len(kept))
+	r.records = kept
+	return deleted, nil
+}
+
+// PurgeDeleted không có gì để làm vì kho trong bộ nhớ không xóa mềm
+func (r *MemoryAnalysisRepository) PurgeDeleted() (int64, error) {
+	return 0, nil
+}
+
+// candleKey là unique key (symbol, interval, open_time) của kho nến
+type candleKey struct {
+	symbol   string
+	interval string
+	openTime int64
+}
+
+// MemoryPriceHistoryRepository là kho nến trong bộ nhớ
+type MemoryPriceHistoryRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	candles map[candleKey]PriceHistory
+}
+
+// NewMemoryPriceHistoryRepository tạo instance mới
+func NewMemoryPriceHistoryRepository() *MemoryPriceHistoryRepository {
+	return &MemoryPriceHistoryRepository{candles: make(map[candleKey]PriceHistory)}
+}
+
+func newCandleKey(history PriceHistory) candleKey {
+	return candleKey{symbol: history.Symbol, interval: history.Interval, openTime: history.OpenTime.UnixNano()}
+}
+
+// Create lưu một nến, lỗi nếu nến đã tồn tại
+func (r *MemoryPriceHistoryRepository) Create(history *PriceHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := newCandleKey(*history)
+	if _, exists := r.candles[key]; exists {
+		return fmt.Errorf("nến %s %s %v đã tồn tại", history.Symbol, history.Interval, history.OpenTime)
+	}
+	r.nextID++
+	history.ID = r.nextID
+	stampTimes(&history.CreatedAt, &history.UpdatedAt)
+	r.candles[key] = *history
+	return nil
+}
+
+// filter lấy các nến của symbol/interval thỏa keep, sắp xếp tăng dần theo open_time
+func (r *MemoryPriceHistoryRepository) filter(symbol, interval string, keep func(PriceHistory) bool) []PriceHistory {
+	var histories []PriceHistory
+	for _, history := range r.candles {
+		if history.Symbol == symbol && history.Interval == interval && keep(history) {
+			histories = append(histories, history)
+		}
+	}
+	sort.Slice(histories, func(i, j int) bool { return histories[i].OpenTime.Before(histories[j].OpenTime) })
+	return histories
+}
+
+// GetBySymbolAndInterval lấy các nến mới nhất trước
+func (r *MemoryPriceHistoryRepository) GetBySymbolAndInterval(symbol, interval string, limit int) ([]PriceHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	histories := r.filter(symbol, interval, func(PriceHistory) bool { return true })
+	for i, j := 0, len(histories)-1; i < j; i, j = i+1, j-1 {
+		histories[i], histories[j] = histories[j], histories[i]
+	}
+	return limitRecords(histories, limit), nil
+}
+
+// GetLatestPrice lấy nến mới nhất
+func (r *MemoryPriceHistoryRepository) GetLatestPrice(symbol, interval string) (*PriceHistory, error) {
+	histories, _ := r.GetBySymbolAndInterval(symbol, interval, 1)
+	if len(histories) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &histories[0], nil
+}
+
+// DeleteOldData xóa các nến cũ hơn olderThan, trả về số nến đã xóa
+func (r *MemoryPriceHistoryRepository) DeleteOldData(symbol, interval string, olderThan time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for key, history := range r.candles {
+		if history.Symbol == symbol && history.Interval == interval && history.OpenTime.Before(olderThan) {
+			delete(r.candles, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteRange xóa các nến có open_time trong [from, to), trả về số nến đã xóa
+func (r *MemoryPriceHistoryRepository) DeleteRange(symbol, interval string, from, to time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for key, history := range r.candles {
+		if history.Symbol == symbol && history.Interval == interval && !history.OpenTime.Before(from) && history.OpenTime.Before(to) {
+			delete(r.candles, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// PurgeDeleted không có gì để làm vì kho trong bộ nhớ không xóa mềm
+func (r *MemoryPriceHistoryRepository) PurgeDeleted() (int64, error) {
+	return 0, nil
+}
+
+// ListSeries lấy các cặp (symbol, interval) đang có nến, sắp xếp theo symbol rồi interval
+func (r *MemoryPriceHistoryRepository) ListSeries() ([]CandleSeries, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[CandleSeries]bool)
+	var series []CandleSeries
+	for _, history := range r.candles {
+		item := CandleSeries{Symbol: history.Symbol, Interval: history.Interval}
+		if !seen[item] {
+			seen[item] = true
+			series = append(series, item)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Symbol != series[j].Symbol {
+			return series[i].Symbol < series[j].Symbol
+		}
+		return series[i].Interval < series[j].Interval
+	})
+	return series, nil
+}
+
+// GetOldest lấy nến cũ nhất của symbol/interval
+func (r *MemoryPriceHistoryRepository) GetOldest(symbol, interval string) (*PriceHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	histories := r.filter(symbol, interval, func(PriceHistory) bool { return true })
+	if len(histories) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &histories[0], nil
+}
+
+// GetCount đếm số nến của symbol/interval
+func (r *MemoryPriceHistoryRepository) GetCount(symbol, interval string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.filter(symbol, interval, func(PriceHistory) bool { return true }))), nil
+}
+
+// UpsertCandles lưu danh sách nến, nến đã tồn tại được cập nhật giá trị (giữ ID và CreatedAt)
+func (r *MemoryPriceHistoryRepository) UpsertCandles(candles []PriceHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, candle := range candles {
+		key := newCandleKey(candle)
+		if existing, ok := r.candles[key]; ok {
+			candle.ID = existing.ID
+			candle.CreatedAt = existing.CreatedAt
+			candle.UpdatedAt = now
+		} else {
+			r.nextID++
+			candle.ID = r.nextID
+			stampTimes(&candle.CreatedAt, &candle.UpdatedAt)
+		}
+		r.candles[key] = candle
+	}
+	return nil
+}
+
+// GetRange lấy các nến có open_time trong [from, to), tăng dần
+func (r *MemoryPriceHistoryRepository) GetRange(symbol, interval string, from, to time.Time) ([]PriceHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filter(symbol, interval, func(history PriceHistory) bool {
+		return !history.OpenTime.Before(from) && history.OpenTime.Before(to)
+	}), nil
+}
+
+// GetOpenTimes lấy open_time của các nến trong [from, to), tăng dần
+func (r *MemoryPriceHistoryRepository) GetOpenTimes(symbol, interval string, from, to time.Time) ([]time.Time, error) {
+	histories, _ := r.GetRange(symbol, interval, from, to)
+	openTimes := make([]time.Time, 0, len(histories))
+	for _, history := range histories {
+		openTimes = append(openTimes, history.OpenTime)
+	}
+	return openTimes, nil
+}
+
+// MemorySymbolRepository lưu danh sách symbol và lịch sử niêm yết trong bộ nhớ
+type MemorySymbolRepository struct {
+	mu         sync.Mutex
+	nextID     uint
+	symbols    map[string]Symbol
+	histories  []SymbolHistory
+	lastUpdate time.Time
+}
+
+// NewMemorySymbolRepository tạo instance mới
+func NewMemorySymbolRepository() *MemorySymbolRepository {
+	return &MemorySymbolRepository{symbols: make(map[string]Symbol)}
+}
+
+// sorted trả về các symbol thỏa keep theo thứ tự ID
+func (r *MemorySymbolRepository) sorted(keep func(Symbol) bool) []Symbol {
+	var symbols []Symbol
+	for _, symbol := range r.symbols {
+		if keep(symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].ID < symbols[j].ID })
+	return symbols
+}
+
+// insert thêm symbol mới, giống hook BeforeCreate của Symbol
+func (r *MemorySymbolRepository) insert(symbol *Symbol, now time.Time) {
+	r.nextID++
+	symbol.ID = r.nextID
+	symbol.CreatedAt = now
+	symbol.UpdatedAt = now
+	r.symbols[symbol.Symbol] = *symbol
+}
+
+// Create thêm symbol mới, lỗi nếu symbol đã tồn tại
+func (r *MemorySymbolRepository) Create(symbol *Symbol) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.symbols[symbol.Symbol]; exists {
+		return fmt.Errorf("symbol %s đã tồn tại", symbol.Symbol)
+	}
+	r.insert(symbol, time.Now())
+	return nil
+}
+
+// UpdateLastUpdateTime ghi nhận thời điểm cập nhật danh sách symbol
+func (r *MemorySymbolRepository) UpdateLastUpdateTime() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastUpdate = time.Now()
+	return nil
+}
+
+// GetAllSymbols lấy danh sách symbol đang giao dịch thuộc các quote asset (rỗng là mọi quote asset)
+func (r *MemorySymbolRepository) GetAllSymbols(quoteAssets []string) ([]string, error) {
+	symbols, _ := r.GetTradingSymbols(quoteAssets)
+	var result []string
+	for _, s := range symbols {
+		result = append(result, s.Symbol)
+	}
+	return result, nil
+}
+
+// GetTradingSymbols lấy thông tin đầy đủ của các symbol đang giao dịch thuộc các quote asset (rỗng là mọi quote asset)
+func (r *MemorySymbolRepository) GetTradingSymbols(quoteAssets []string) ([]Symbol, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inQuoteAssets := quoteAssetFilter(quoteAssets)
+	return r.sorted(func(s Symbol) bool { return s.Status == SymbolStatusTrading && inQuoteAssets(s) }), nil
+}
+
+// quoteAssetFilter trả về hàm lọc symbol theo quote asset như whereQuoteAssets, rỗng là không lọc
+func quoteAssetFilter(quoteAssets []string) func(Symbol) bool {
+	return func(s Symbol) bool {
+		return len(quoteAssets) == 0 || slices.Contains(quoteAssets, s.QuoteAsset)
+	}
+}
+
+// GetBySymbol lấy thông tin của một symbol
+func (r *MemorySymbolRepository) GetBySymbol(symbol string) (*Symbol, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.symbols[symbol]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &result, nil
+}
+
+// GetSymbolByBaseAsset lấy symbol đầu tiên có base asset tương ứng
+func (r *MemorySymbolRepository) GetSymbolByBaseAsset(baseAsset string) ([]Symbol, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	symbols := r.sorted(func(s Symbol) bool { return s.BaseAsset == baseAsset })
+	if len(symbols) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return symbols[:1], nil
+}
+
+// SyncSymbols đồng bộ danh sách symbol, trả về các sự kiện niêm yết/huỷ niêm yết như SymbolRepository
+func (r *MemorySymbolRepository) SyncSymbols(symbols []Symbol, quoteAssets []string) ([]SymbolHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	upserts, events := diffSymbols(r.sorted(quoteAssetFilter(quoteAssets)), symbols, now)
+	for _, s := range upserts {
+		existing, ok := r.symbols[s.Symbol]
+		if !ok {
+			r.insert(&s, now)
+			continue
+		}
+		existing.Status = s.Status
+		existing.BaseAsset = s.BaseAsset
+		existing.QuoteAsset = s.QuoteAsset
+		existing.TickSize = s.TickSize
+		existing.StepSize = s.StepSize
+		existing.MinQty = s.MinQty
+		existing.MinNotional = s.MinNotional
+		existing.UpdatedAt = now
+		r.symbols[s.Symbol] = existing
+	}
+	for i := range events {
+		events[i].ID = uint(len(r.histories) + 1)
+		r.histories = append(r.histories, events[i])
+	}
+	return events, nil
+}
+
+// GetSymbolHistory lấy lịch sử thay đổi gần nhất của một symbol
+func (r *MemorySymbolRepository) GetSymbolHistory(symbol string, limit int) ([]SymbolHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var histories []SymbolHistory
+	for i := len(r.histories) - 1; i >= 0; i-- {
+		if r.histories[i].Symbol == symbol {
+			histories = append(histories, r.histories[i])
+		}
+	}
+	sort.SliceStable(histories, func(i, j int) bool { return histories[i].CreatedAt.After(histories[j].CreatedAt) })
+	return limitRecords(histories, limit), nil
+}
+
+// ShouldUpdate kiểm tra lần cập nhật symbol gần nhất đã cũ hơn updateInterval hay chưa
+func (r *MemorySymbolRepository) ShouldUpdate(updateInterval time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastUpdate.IsZero() || time.Since(r.lastUpdate) > updateInterval
+}
+
+// HasSymbols kiểm tra đã có symbol nào của quoteAsset chưa
+func (r *MemorySymbolRepository) HasSymbols(quoteAsset string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sorted(func(s Symbol) bool { return s.QuoteAsset == quoteAsset })) > 0, nil
+}
+
+// MemoryAutoVolumeRecordRepository lưu nến của volume screener trong bộ nhớ
+type MemoryAutoVolumeRecordRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	records map[string][]AutoVolumeRecord // Theo symbol, sắp xếp giảm dần theo open_time
+}
+
+// NewMemoryAutoVolumeRecordRepository tạo instance mới
+func NewMemoryAutoVolumeRecordRepository() *MemoryAutoVolumeRecordRepository {
+	return &MemoryAutoVolumeRecordRepository{records: make(map[string][]AutoVolumeRecord)}
+}
+
+// insert thêm record và giữ thứ tự giảm dần theo open_time
+func (r *MemoryAutoVolumeRecordRepository) insert(record *AutoVolumeRecord) {
+	r.nextID++
+	record.ID = r.nextID
+	stampTimes(&record.CreatedAt, &record.UpdatedAt)
+	records := append(r.records[record.Symbol], *record)
+	sort.Slice(records, func(i, j int) bool { return records[i].OpenTime.After(records[j].OpenTime) })
+	r.records[record.Symbol] = records
+}
+
+// find trả về vị trí của nến (symbol, open_time), -1 nếu chưa có
+func (r *MemoryAutoVolumeRecordRepository) find(symbol string, openTime time.Time) int {
+	for i, record := range r.records[symbol] {
+		if record.OpenTime.Equal(openTime) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Create lưu record mới, lỗi nếu nến (symbol, open_time) đã tồn tại
+func (r *MemoryAutoVolumeRecordRepository) Create(record *AutoVolumeRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(record.Symbol, record.OpenTime) >= 0 {
+		return fmt.Errorf("nến %s %v đã tồn tại", record.Symbol, record.OpenTime)
+	}
+	r.insert(record)
+	return nil
+}
+
+// ReplaceAllForSymbol thay toàn bộ nến của symbol. Dữ liệu mới được kiểm tra trùng trước khi xóa dữ liệu cũ,
+// lỗi thì dữ liệu cũ giữ nguyên như khi transaction của AutoVolumeRecordRepository bị rollback.
+func (r *MemoryAutoVolumeRecordRepository) ReplaceAllForSymbol(symbol string, records []AutoVolumeRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	type candleKey struct {
+		symbol   string
+		openTime int64
+	}
+	seen := make(map[candleKey]bool, len(records))
+	for _, record := range records {
+		key := candleKey{record.Symbol, record.OpenTime.UnixNano()}
+		// Nến của symbol khác không bị xóa nên cũng phải kiểm tra với dữ liệu đang có
+		if seen[key] || (record.Symbol != symbol && r.find(record.Symbol, record.OpenTime) >= 0) {
+			return fmt.Errorf("nến %s %v bị trùng", record.Symbol, record.OpenTime)
+		}
+		seen[key] = true
+	}
+
+	delete(r.records, symbol)
+	for i := range records {
+		r.insert(&records[i])
+	}
+	return nil
+}
+
+// SaveClosedCandle thêm hoặc cập nhật một nến đã đóng và chỉ giữ lại keep nến mới nhất của symbol
+func (r *MemoryAutoVolumeRecordRepository) SaveClosedCandle(record *AutoVolumeRecord, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.find(record.Symbol, record.OpenTime); i >= 0 {
+		existing := r.records[record.Symbol][i]
+		record.ID = existing.ID
+		record.CreatedAt = existing.CreatedAt
+		record.UpdatedAt = time.Now()
+		r.records[record.Symbol][i] = *record
+	} else {
+		r.insert(record)
+	}
+	if records := r.records[record.Symbol]; len(records) > keep {
+		r.records[record.Symbol] = records[:keep]
+	}
+	return nil
+}
+
+// GetLastNBySymbol lấy n nến mới nhất của symbol, giảm dần theo open_time
+func (r *MemoryAutoVolumeRecordRepository) GetLastNBySymbol(symbol string, n int) ([]AutoVolumeRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := append([]AutoVolumeRecord(nil), r.records[symbol]...)
+	return limitRecords(records, n), nil
+}
+
+// MemoryNotificationLogRepository lưu log gửi cảnh báo trong bộ nhớ
+type MemoryNotificationLogRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	logs   []NotificationLog
+}
+
+// NewMemoryNotificationLogRepository tạo instance mới
+func NewMemoryNotificationLogRepository() *MemoryNotificationLogRepository {
+	return &MemoryNotificationLogRepository{}
+}
+
+// Create lưu log thông báo mới
+func (r *MemoryNotificationLogRepository) Create(log *NotificationLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	log.ID = r.nextID
+	stampTimes(&log.CreatedAt, nil)
+	r.logs = append(r.logs, *log)
+	return nil
+}
+
+// CountBySymbolToday đếm số lần gửi tin nhắn cho một symbol trong ngày hôm nay
+func (r *MemoryNotificationLogRepository) CountBySymbolToday(symbol string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	today := notificationDayStart(time.Now())
+	var count int64
+	for _, log := range r.logs {
+		if log.Symbol == symbol && !log.CreatedAt.Before(today) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteOlderThan xóa các log gửi trước cutoff, trả về số dòng đã xóa
+func (r *MemoryNotificationLogRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.logs[:0]
+	for _, log := range r.logs {
+		if !log.CreatedAt.Before(cutoff) {
+			kept = append(kept, log)
+		}
+	}
+	deleted := int64(len(r.logs) - len(kept))
+	r.logs = kept
+	return deleted, nil
+}
+
+// MemoryIndicatorSnapshotRepository lưu trạng thái chỉ báo trong bộ nhớ
+type MemoryIndicatorSnapshotRepository struct {
+	mu        sync.Mutex
+	nextID    uint
+	snapshots map[CandleSeries]IndicatorSnapshot
+}
+
+// NewMemoryIndicatorSnapshotRepository tạo instance mới
+func NewMemoryIndicatorSnapshotRepository() *MemoryIndicatorSnapshotRepository {
+	return &MemoryIndicatorSnapshotRepository{snapshots: make(map[CandleSeries]IndicatorSnapshot)}
+}
+
+// Get lấy snapshot của symbol/interval, trả về gorm.ErrRecordNotFound nếu chưa có
+func (r *MemoryIndicatorSnapshotRepository) Get(symbol, interval string) (*IndicatorSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot, ok := r.snapshots[CandleSeries{Symbol: symbol, Interval: interval}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &snapshot, nil
+}
+
+// Save ghi đè snapshot của symbol/interval
+func (r *MemoryIndicatorSnapshotRepository) Save(snapshot *IndicatorSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := CandleSeries{Symbol: snapshot.Symbol, Interval: snapshot.Interval}
+	if existing, ok := r.snapshots[key]; ok {
+		snapshot.ID = existing.ID
+	} else {
+		r.nextID++
+		snapshot.ID = r.nextID
+	}
+	stampTimes(nil, &snapshot.UpdatedAt)
+	r.snapshots[key] = *snapshot
+	return nil
+}
+
+// backfillJobKey là unique key (symbol, interval, start_time, end_time) của checkpoint backfill
+type backfillJobKey struct {
+	symbol    string
+	interval  string
+	startTime int64
+	endTime   int64
+}
+
+// MemoryBackfillCheckpointRepository lưu tiến độ backfill trong bộ nhớ
+type MemoryBackfillCheckpointRepository struct {
+	mu          sync.Mutex
+	nextID      uint
+	checkpoints map[backfillJobKey]BackfillCheckpoint
+}
+
+// NewMemoryBackfillCheckpointRepository tạo instance mới
+func NewMemoryBackfillCheckpointRepository() *MemoryBackfillCheckpointRepository {
+	return &MemoryBackfillCheckpointRepository{checkpoints: make(map[backfillJobKey]BackfillCheckpoint)}
+}
+
+func newBackfillJobKey(checkpoint BackfillCheckpoint) backfillJobKey {
+	return backfillJobKey{
+		symbol:    checkpoint.Symbol,
+		interval:  checkpoint.Interval,
+		startTime: checkpoint.StartTime.UnixNano(),
+		endTime:   checkpoint.EndTime.UnixNano(),
+	}
+}
+
+// GetOrCreate lấy checkpoint của job backfill, tạo mới với Cursor = endTime nếu chưa có
+func (r *MemoryBackfillCheckpointRepository) GetOrCreate(symbol, interval string, startTime, endTime time.Time) (*BackfillCheckpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	checkpoint := BackfillCheckpoint{Symbol: symbol, Interval: interval, StartTime: startTime, EndTime: endTime}
+	key := newBackfillJobKey(checkpoint)
+	if existing, ok := r.checkpoints[key]; ok {
+		return &existing, nil
+	}
+	r.nextID++
+	checkpoint.ID = r.nextID
+	checkpoint.Cursor = endTime
+	stampTimes(&checkpoint.CreatedAt, &checkpoint.UpdatedAt)
+	r.checkpoints[key] = checkpoint
+	return &checkpoint, nil
+}
+
+// Save cập nhật tiến độ của checkpoint
+func (r *MemoryBackfillCheckpointRepository) Save(checkpoint *BackfillCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := newBackfillJobKey(*checkpoint)
+	if existing, ok := r.checkpoints[key]; ok && existing.ID != checkpoint.ID {
+		return fmt.Errorf("checkpoint %s %s đã tồn tại", checkpoint.Symbol, checkpoint.Interval)
+	}
+	if checkpoint.ID == 0 {
+		r.nextID++
+		checkpoint.ID = r.nextID
+	}
+	stampTimes(&checkpoint.CreatedAt, nil)
+	checkpoint.UpdatedAt = time.Now()
+	r.checkpoints[key] = *checkpoint
+	return nil
+}
+
+// MemoryKlineQuarantineRepository lưu các nến bị loại trong bộ nhớ
+type MemoryKlineQuarantineRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	records []QuarantinedKline
+}
+
+// NewMemoryKlineQuarantineRepository tạo instance mới
+func NewMemoryKlineQuarantineRepository() *MemoryKlineQuarantineRepository {
+	return &MemoryKlineQuarantineRepository{}
+}
+
+// CreateBatch lưu danh sách nến bị loại
+func (r *MemoryKlineQuarantineRepository) CreateBatch(records []QuarantinedKline) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range records {
+		r.nextID++
+		records[i].ID = r.nextID
+		stampTimes(&records[i].CreatedAt, nil)
+		r.records = append(r.records, records[i])
+	}
+	return nil
+}
+
+// GetRecent lấy các nến bị loại gần nhất
+func (r *MemoryKlineQuarantineRepository) GetRecent(limit int) ([]QuarantinedKline, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []QuarantinedKline
+	for i := len(r.records) - 1; i >= 0; i-- {
+		records = append(records, r.records[i])
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	return limitRecords(records, limit), nil
+}
+
+var (
+	_ AnalysisStore          = (*MemoryAnalysisRepository)(nil)
+	_ PriceHistoryStore      = (*MemoryPriceHistoryRepository)(nil)
+	_ SymbolStore            = (*MemorySymbolRepository)(nil)
+	_ AutoVolumeStore        = (*MemoryAutoVolumeRecordRepository)(nil)
+	_ NotificationLogStore   = (*MemoryNotificationLogRepository)(nil)
+	_ IndicatorSnapshotStore = (*MemoryIndicatorSnapshotRepository)(nil)
+
+	_ BackfillCheckpointStore = (*MemoryBackfillCheckpointRepository)(nil)
+	_ KlineQuarantineStore    = (*MemoryKlineQuarantineRepository)(nil)
+)
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Mỗi driver database có thư mục migration riêng (migrations/postgres, migrations/sqlite)
+// với cùng danh sách version
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern khớp tên file migration dạng 0001_ten_migration.up.sql / .down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration là một bước thay đổi schema, gồm SQL chạy lên (Up) và SQL hoàn tác (Down)
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String trả về tên dạng 0002_auto_volume_open_time
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// SchemaVersion lưu các migration đã chạy, phiên bản schema hiện tại là version lớn nhất
+type SchemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName định nghĩa tên bảng cho SchemaVersion
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Migrator chạy các migration SQL được nhúng trong thư mục migrations theo thứ tự version
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator tạo migrator với các migration nhúng trong binary cho driver đang dùng
+func NewMigrator() (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, path.Join("migrations", DB.Dialector.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: DB, migrations: migrations}, nil
+}
+
+// loadMigrations đọc và sắp xếp các migration, mỗi version phải có đủ file up và down
+func loadMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("không đọc được thư mục migration: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("tên file migration không hợp lệ: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("không đọc được migration %s: %v", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d bị trùng version: %s và %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %s thiếu file up hoặc down", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion trả về version của migration mới nhất trong binary
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion trả về phiên bản schema của database, 0 nếu chưa chạy migration nào
+func (m *Migrator) CurrentVersion() (int, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := m.db.Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("lỗi đọc phiên bản schema: %v", err)
+	}
+	return version, nil
+}
+
+// Check từ chối chạy khi database có schema mới hơn binary (ví dụ chạy lại bản build cũ sau khi đã migrate)
+func (m *Migrator) Check() (int, error) {
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return 0, err
+	}
+	if current > m.LatestVersion() {
+		return current, fmt.Errorf("schema database ở version %d, mới hơn version %d của bản build này", current, m.LatestVersion())
+	}
+	return current, nil
+}
+
+// Pending trả về các migration chưa chạy, tăng dần theo version
+func (m *Migrator) Pending() ([]Migration, error) {
+	current, err := m.Check()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up chạy các migration chưa chạy tới version target (0 là mới nhất), mỗi migration trong một transaction.
+// Với dryRun chỉ in SQL sẽ chạy, không thay đổi database.
+func (m *Migrator) Up(target int, dryRun bool) ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if dryRun {
+			log.Printf("📝 [dry-run] Migration %s (up):\n%s", migration, migration.Up)
+		} else {
+			log.Printf("🔄 Chạy migration %s...", migration)
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&SchemaVersion{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return applied, fmt.Errorf("lỗi chạy migration %s: %v", migration, err)
+			}
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down hoàn tác steps migration gần nhất, mới nhất trước. Với dryRun chỉ in SQL sẽ chạy.
+func (m *Migrator) Down(steps int, dryRun bool) ([]Migration, error) {
+	current, err := m.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current {
+			continue
+		}
+		if dryRun {
+			log.Printf("📝 [dry-run] Migration %s (down):\n%s", migration, migration.Down)
+		} else {
+			log.Printf("↩️ Hoàn tác migration %s...", migration)
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Where("version = ?", migration.Version).Delete(&SchemaVersion{}).Error
+			})
+			if err != nil {
+				return reverted, fmt.Errorf("lỗi hoàn tác migration %s: %v", migration, err)
+			}
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// ensureVersionTable tạo bảng schema_version nếu chưa có
+func (m *Migrator) ensureVersionTable() error {
+	timeType := "TIMESTAMPTZ"
+	if m.db.Dialector.Name() == "sqlite" {
+		// Driver SQLite chỉ đọc cột DATETIME thành time.Time
+		timeType = "DATETIME"
+	}
+	err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at %s NOT NULL
+	)`, timeType)).Error
+	if err != nil {
+		return fmt.Errorf("lỗi tạo bảng schema_version: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigrationsDownAndUp(t *testing.T) {
+	setupTestDB(t)
+	migrator, err := NewMigrator()
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	latest := migrator.LatestVersion()
+	if current, _ := migrator.CurrentVersion(); current != latest {
+		t.Fatalf("version after MigrateDatabase = %d, want %d", current, latest)
+	}
+
+	reverted, err := migrator.Down(latest, false)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(reverted) != latest {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), latest)
+	}
+	if current, _ := migrator.CurrentVersion(); current != 0 {
+		t.Fatalf("version after Down = %d, want 0", current)
+	}
+	for _, table := range []string{"price_histories", "analysis_records", "symbols", "indicator_snapshots"} {
+		if DB.Migrator().HasTable(table) {
+			t.Errorf("table %s still exists after Down", table)
+		}
+	}
+
+	applied, err := migrator.Up(0, false)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != latest {
+		t.Fatalf("applied %d migrations, want %d", len(applied), latest)
+	}
+	if current, _ := migrator.CurrentVersion(); current != latest {
+		t.Fatalf("version after Up = %d, want %d", current, latest)
+	}
+	// Schema sau khi chạy lại phải dùng được như bình thường
+	if err := NewPriceHistoryRepository().UpsertCandles([]PriceHistory{testCandle("BTCUSDT", "1h", time.Now().UTC().Truncate(time.Hour), 1)}); err != nil {
+		t.Errorf("UpsertCandles after re-migration: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+-- Schema ban đầu, tương ứng các bảng AutoMigrate đã tạo trước khi chuyển sang migration.
+-- Dùng IF NOT EXISTS để database đang chạy nhận migration này mà không mất dữ liệu.
+
+CREATE TABLE IF NOT EXISTS analysis_records (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    close_price DECIMAL NOT NULL,
+    volume DECIMAL NOT NULL,
+    rsi DECIMAL,
+    ema9 DECIMAL,
+    ema21 DECIMAL,
+    ema50 DECIMAL,
+    macd DECIMAL,
+    macd_signal DECIMAL,
+    volume_sma DECIMAL,
+    trend TEXT,
+    power TEXT,
+    signal TEXT,
+    recommendation TEXT,
+    volume_signal TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_analysis_records_symbol ON analysis_records (symbol);
+CREATE INDEX IF NOT EXISTS idx_analysis_records_deleted_at ON analysis_records (deleted_at);
+
+CREATE TABLE IF NOT EXISTS price_histories (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time TIMESTAMPTZ NOT NULL,
+    open DECIMAL NOT NULL,
+    high DECIMAL NOT NULL,
+    low DECIMAL NOT NULL,
+    close DECIMAL NOT NULL,
+    volume DECIMAL NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+-- Các cột kho nến thêm sau, bảng cũ chưa có
+ALTER TABLE price_histories ADD COLUMN IF NOT EXISTS close_time TIMESTAMPTZ;
+ALTER TABLE price_histories ADD COLUMN IF NOT EXISTS quote_volume DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE price_histories ADD COLUMN IF NOT EXISTS trades BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE price_histories ADD COLUMN IF NOT EXISTS taker_buy_base_volume DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE price_histories ADD COLUMN IF NOT EXISTS taker_buy_quote_volume DECIMAL NOT NULL DEFAULT 0;
+-- Bảng cũ có thể có nến trùng, giữ lại bản ghi mới nhất trước khi tạo unique index
+DELETE FROM price_histories p
+USING price_histories newer
+WHERE p.symbol = newer.symbol
+  AND p."interval" = newer."interval"
+  AND p.open_time = newer.open_time
+  AND p.id < newer.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_histories_candle ON price_histories (symbol, "interval", open_time);
+CREATE INDEX IF NOT EXISTS idx_price_histories_deleted_at ON price_histories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS symbols (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    status TEXT NOT NULL,
+    base_asset TEXT NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT uni_symbols_symbol UNIQUE (symbol)
+);
+ALTER TABLE symbols ADD COLUMN IF NOT EXISTS quote_asset TEXT NOT NULL DEFAULT 'USDT';
+ALTER TABLE symbols ADD COLUMN IF NOT EXISTS tick_size TEXT NOT NULL DEFAULT '';
+ALTER TABLE symbols ADD COLUMN IF NOT EXISTS step_size TEXT NOT NULL DEFAULT '';
+ALTER TABLE symbols ADD COLUMN IF NOT EXISTS min_qty TEXT NOT NULL DEFAULT '';
+ALTER TABLE symbols ADD COLUMN IF NOT EXISTS min_notional TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_symbols_quote_asset ON symbols (quote_asset);
+
+CREATE TABLE IF NOT EXISTS symbol_histories (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    event TEXT NOT NULL,
+    old_status TEXT NOT NULL DEFAULT '',
+    new_status TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_symbol_histories_symbol ON symbol_histories (symbol);
+CREATE INDEX IF NOT EXISTS idx_symbol_histories_created_at ON symbol_histories (created_at);
+
+CREATE TABLE IF NOT EXISTS data_updates (
+    id BIGSERIAL PRIMARY KEY,
+    table_name TEXT NOT NULL,
+    last_update TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT uni_data_updates_table_name UNIQUE (table_name)
+);
+
+-- open_time của bảng volume vẫn là số mili giây, migration 0002 chuyển sang timestamp
+CREATE TABLE IF NOT EXISTS auto_volume_record (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    open_time DECIMAL NOT NULL,
+    quote_asset_volume DECIMAL NOT NULL,
+    open_price DECIMAL NOT NULL,
+    close_price DECIMAL NOT NULL,
+    high_price DECIMAL NOT NULL,
+    low_price DECIMAL NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_auto_volume_record_symbol ON auto_volume_record (symbol);
+CREATE INDEX IF NOT EXISTS idx_auto_volume_record_deleted_at ON auto_volume_record (deleted_at);
+
+CREATE TABLE IF NOT EXISTS notification_logs (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_logs_symbol ON notification_logs (symbol);
+
+CREATE TABLE IF NOT EXISTS backfill_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    cursor TIMESTAMPTZ NOT NULL,
+    candles BIGINT NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_backfill_checkpoints_job ON backfill_checkpoints (symbol, "interval", start_time, end_time);
+
+CREATE TABLE IF NOT EXISTS quarantined_klines (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    raw_payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_quarantined_klines_symbol ON quarantined_klines (symbol);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_auto_volume_record_candle;
+
+ALTER TABLE auto_volume_record
+    ALTER COLUMN open_time TYPE DECIMAL
+    USING (EXTRACT(EPOCH FROM open_time) * 1000);
@@ -0,0 +1,12 @@
+-- Chuyển open_time của bảng volume từ số mili giây sang timestamp
+ALTER TABLE auto_volume_record
+    ALTER COLUMN open_time TYPE TIMESTAMPTZ
+    USING to_timestamp((open_time / 1000)::DOUBLE PRECISION);
+
+-- Mỗi symbol chỉ có một nến cho mỗi open_time, giữ lại bản ghi mới nhất nếu bị trùng
+DELETE FROM auto_volume_record r
+USING auto_volume_record newer
+WHERE r.symbol = newer.symbol
+  AND r.open_time = newer.open_time
+  AND r.id < newer.id;
+CREATE UNIQUE INDEX idx_auto_volume_record_candle ON auto_volume_record (symbol, open_time);
@@ -0,0 +1,38 @@
+-- Gộp các partition về lại một bảng price_histories thường
+ALTER TABLE price_histories RENAME TO price_histories_partitioned;
+ALTER TABLE price_histories_partitioned RENAME CONSTRAINT price_histories_pkey TO price_histories_partitioned_pkey;
+ALTER INDEX idx_price_histories_candle RENAME TO idx_price_histories_partitioned_candle;
+ALTER INDEX idx_price_histories_deleted_at RENAME TO idx_price_histories_partitioned_deleted_at;
+
+CREATE TABLE price_histories (
+    id BIGINT PRIMARY KEY DEFAULT nextval('price_histories_id_seq'),
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time TIMESTAMPTZ NOT NULL,
+    close_time TIMESTAMPTZ,
+    open DECIMAL NOT NULL,
+    high DECIMAL NOT NULL,
+    low DECIMAL NOT NULL,
+    close DECIMAL NOT NULL,
+    volume DECIMAL NOT NULL,
+    quote_volume DECIMAL NOT NULL DEFAULT 0,
+    trades BIGINT NOT NULL DEFAULT 0,
+    taker_buy_base_volume DECIMAL NOT NULL DEFAULT 0,
+    taker_buy_quote_volume DECIMAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+ALTER SEQUENCE price_histories_id_seq OWNED BY price_histories.id;
+CREATE UNIQUE INDEX idx_price_histories_candle ON price_histories (symbol, "interval", open_time);
+CREATE INDEX idx_price_histories_deleted_at ON price_histories (deleted_at);
+
+INSERT INTO price_histories (
+    id, symbol, "interval", open_time, close_time, open, high, low, close, volume,
+    quote_volume, trades, taker_buy_base_volume, taker_buy_quote_volume, created_at, updated_at, deleted_at
+)
+SELECT id, symbol, "interval", open_time, close_time, open, high, low, close, volume,
+       quote_volume, trades, taker_buy_base_volume, taker_buy_quote_volume, created_at, updated_at, deleted_at
+FROM price_histories_partitioned;
+-- Xóa bảng cha xóa luôn mọi partition
+DROP TABLE price_histories_partitioned;
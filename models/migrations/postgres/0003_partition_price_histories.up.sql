@@ -0,0 +1,59 @@
+-- Chuyển price_histories sang bảng partition theo tháng của open_time (UTC), tên partition dạng price_histories_2024_01.
+-- Partition tháng mới do PartitionManager tạo khi bot chạy, nến ngoài các tháng đã có partition rơi vào price_histories_default.
+ALTER TABLE price_histories RENAME TO price_histories_unpartitioned;
+ALTER TABLE price_histories_unpartitioned RENAME CONSTRAINT price_histories_pkey TO price_histories_unpartitioned_pkey;
+ALTER INDEX idx_price_histories_candle RENAME TO idx_price_histories_unpartitioned_candle;
+ALTER INDEX idx_price_histories_deleted_at RENAME TO idx_price_histories_unpartitioned_deleted_at;
+
+-- Khóa chính và unique index của bảng partition phải chứa cột partition (open_time)
+CREATE TABLE price_histories (
+    id BIGINT NOT NULL DEFAULT nextval('price_histories_id_seq'),
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time TIMESTAMPTZ NOT NULL,
+    close_time TIMESTAMPTZ,
+    open DECIMAL NOT NULL,
+    high DECIMAL NOT NULL,
+    low DECIMAL NOT NULL,
+    close DECIMAL NOT NULL,
+    volume DECIMAL NOT NULL,
+    quote_volume DECIMAL NOT NULL DEFAULT 0,
+    trades BIGINT NOT NULL DEFAULT 0,
+    taker_buy_base_volume DECIMAL NOT NULL DEFAULT 0,
+    taker_buy_quote_volume DECIMAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    PRIMARY KEY (id, open_time)
+) PARTITION BY RANGE (open_time);
+ALTER SEQUENCE price_histories_id_seq OWNED BY price_histories.id;
+CREATE UNIQUE INDEX idx_price_histories_candle ON price_histories (symbol, "interval", open_time);
+CREATE INDEX idx_price_histories_deleted_at ON price_histories (deleted_at);
+CREATE TABLE price_histories_default PARTITION OF price_histories DEFAULT;
+
+-- Tạo partition cho các tháng đã có nến
+DO $$
+DECLARE
+    month_start TIMESTAMP;
+BEGIN
+    FOR month_start IN
+        SELECT DISTINCT date_trunc('month', open_time AT TIME ZONE 'UTC')
+        FROM price_histories_unpartitioned
+    LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF price_histories FOR VALUES FROM (%L) TO (%L)',
+            'price_histories_' || to_char(month_start, 'YYYY_MM'),
+            month_start::TEXT || '+00',
+            (month_start + INTERVAL '1 month')::TEXT || '+00'
+        );
+    END LOOP;
+END $$;
+
+INSERT INTO price_histories (
+    id, symbol, "interval", open_time, close_time, open, high, low, close, volume,
+    quote_volume, trades, taker_buy_base_volume, taker_buy_quote_volume, created_at, updated_at, deleted_at
+)
+SELECT id, symbol, "interval", open_time, close_time, open, high, low, close, volume,
+       quote_volume, trades, taker_buy_base_volume, taker_buy_quote_volume, created_at, updated_at, deleted_at
+FROM price_histories_unpartitioned;
+DROP TABLE price_histories_unpartitioned;
@@ -0,0 +1,11 @@
+-- Bollinger Bands, ATR, Keltner Channels và trạng thái biến động của nến cuối
+ALTER TABLE analysis_records ADD COLUMN bb_upper DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN bb_middle DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN bb_lower DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN bb_percent_b DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN bb_bandwidth DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN atr DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_upper DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_middle DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_lower DECIMAL;
+ALTER TABLE analysis_records ADD COLUMN volatility TEXT;
@@ -0,0 +1,10 @@
+-- Xóa toàn bộ schema, mất hết dữ liệu
+DROP TABLE IF EXISTS quarantined_klines;
+DROP TABLE IF EXISTS backfill_checkpoints;
+DROP TABLE IF EXISTS notification_logs;
+DROP TABLE IF EXISTS auto_volume_record;
+DROP TABLE IF EXISTS data_updates;
+DROP TABLE IF EXISTS symbol_histories;
+DROP TABLE IF EXISTS symbols;
+DROP TABLE IF EXISTS price_histories;
+DROP TABLE IF EXISTS analysis_records;
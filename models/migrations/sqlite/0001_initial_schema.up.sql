@@ -0,0 +1,134 @@
+-- Schema ban đầu cho SQLite, cùng cấu trúc với migrations/postgres/0001_initial_schema.up.sql
+
+CREATE TABLE IF NOT EXISTS analysis_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    close_price REAL NOT NULL,
+    volume REAL NOT NULL,
+    rsi REAL,
+    ema9 REAL,
+    ema21 REAL,
+    ema50 REAL,
+    macd REAL,
+    macd_signal REAL,
+    volume_sma REAL,
+    trend TEXT,
+    power TEXT,
+    signal TEXT,
+    recommendation TEXT,
+    volume_signal TEXT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_analysis_records_symbol ON analysis_records (symbol);
+CREATE INDEX IF NOT EXISTS idx_analysis_records_deleted_at ON analysis_records (deleted_at);
+
+CREATE TABLE IF NOT EXISTS price_histories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time DATETIME NOT NULL,
+    close_time DATETIME,
+    open REAL NOT NULL,
+    high REAL NOT NULL,
+    low REAL NOT NULL,
+    close REAL NOT NULL,
+    volume REAL NOT NULL,
+    quote_volume REAL NOT NULL DEFAULT 0,
+    trades INTEGER NOT NULL DEFAULT 0,
+    taker_buy_base_volume REAL NOT NULL DEFAULT 0,
+    taker_buy_quote_volume REAL NOT NULL DEFAULT 0,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_histories_candle ON price_histories (symbol, "interval", open_time);
+CREATE INDEX IF NOT EXISTS idx_price_histories_deleted_at ON price_histories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS symbols (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    status TEXT NOT NULL,
+    base_asset TEXT NOT NULL,
+    quote_asset TEXT NOT NULL DEFAULT 'USDT',
+    tick_size TEXT NOT NULL DEFAULT '',
+    step_size TEXT NOT NULL DEFAULT '',
+    min_qty TEXT NOT NULL DEFAULT '',
+    min_notional TEXT NOT NULL DEFAULT '',
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT uni_symbols_symbol UNIQUE (symbol)
+);
+CREATE INDEX IF NOT EXISTS idx_symbols_quote_asset ON symbols (quote_asset);
+
+CREATE TABLE IF NOT EXISTS symbol_histories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    event TEXT NOT NULL,
+    old_status TEXT NOT NULL DEFAULT '',
+    new_status TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_symbol_histories_symbol ON symbol_histories (symbol);
+CREATE INDEX IF NOT EXISTS idx_symbol_histories_created_at ON symbol_histories (created_at);
+
+CREATE TABLE IF NOT EXISTS data_updates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    table_name TEXT NOT NULL,
+    last_update DATETIME NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT uni_data_updates_table_name UNIQUE (table_name)
+);
+
+-- open_time của bảng volume vẫn là số mili giây, migration 0002 chuyển sang timestamp
+CREATE TABLE IF NOT EXISTS auto_volume_record (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    open_time REAL NOT NULL,
+    quote_asset_volume REAL NOT NULL,
+    open_price REAL NOT NULL,
+    close_price REAL NOT NULL,
+    high_price REAL NOT NULL,
+    low_price REAL NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_auto_volume_record_symbol ON auto_volume_record (symbol);
+CREATE INDEX IF NOT EXISTS idx_auto_volume_record_deleted_at ON auto_volume_record (deleted_at);
+
+CREATE TABLE IF NOT EXISTS notification_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_logs_symbol ON notification_logs (symbol);
+
+CREATE TABLE IF NOT EXISTS backfill_checkpoints (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    start_time DATETIME NOT NULL,
+    end_time DATETIME NOT NULL,
+    cursor DATETIME NOT NULL,
+    candles INTEGER NOT NULL DEFAULT 0,
+    completed NUMERIC NOT NULL DEFAULT false,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_backfill_checkpoints_job ON backfill_checkpoints (symbol, "interval", start_time, end_time);
+
+CREATE TABLE IF NOT EXISTS quarantined_klines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    raw_payload TEXT NOT NULL,
+    created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_quarantined_klines_symbol ON quarantined_klines (symbol);
@@ -0,0 +1,21 @@
+CREATE TABLE auto_volume_record_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    open_time REAL NOT NULL,
+    quote_asset_volume REAL NOT NULL,
+    open_price REAL NOT NULL,
+    close_price REAL NOT NULL,
+    high_price REAL NOT NULL,
+    low_price REAL NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+INSERT INTO auto_volume_record_old
+SELECT id, symbol, CAST(strftime('%s', open_time) AS INTEGER) * 1000,
+       quote_asset_volume, open_price, close_price, high_price, low_price, created_at, updated_at, deleted_at
+FROM auto_volume_record;
+DROP TABLE auto_volume_record;
+ALTER TABLE auto_volume_record_old RENAME TO auto_volume_record;
+CREATE INDEX idx_auto_volume_record_symbol ON auto_volume_record (symbol);
+CREATE INDEX idx_auto_volume_record_deleted_at ON auto_volume_record (deleted_at);
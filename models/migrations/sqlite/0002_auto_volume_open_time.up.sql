@@ -0,0 +1,26 @@
+-- SQLite không đổi được kiểu cột nên dựng lại bảng, open_time chuyển từ số mili giây sang
+-- chuỗi thời gian UTC đúng định dạng driver ghi (2006-01-02 15:04:05+00:00).
+-- Nến trùng (symbol, open_time) chỉ giữ lại bản ghi mới nhất.
+CREATE TABLE auto_volume_record_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    open_time DATETIME NOT NULL,
+    quote_asset_volume REAL NOT NULL,
+    open_price REAL NOT NULL,
+    close_price REAL NOT NULL,
+    high_price REAL NOT NULL,
+    low_price REAL NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+INSERT INTO auto_volume_record_new
+SELECT id, symbol, strftime('%Y-%m-%d %H:%M:%S+00:00', CAST(open_time AS INTEGER) / 1000, 'unixepoch'),
+       quote_asset_volume, open_price, close_price, high_price, low_price, created_at, updated_at, deleted_at
+FROM auto_volume_record
+WHERE id IN (SELECT MAX(id) FROM auto_volume_record GROUP BY symbol, CAST(open_time AS INTEGER) / 1000);
+DROP TABLE auto_volume_record;
+ALTER TABLE auto_volume_record_new RENAME TO auto_volume_record;
+CREATE INDEX idx_auto_volume_record_symbol ON auto_volume_record (symbol);
+CREATE INDEX idx_auto_volume_record_deleted_at ON auto_volume_record (deleted_at);
+CREATE UNIQUE INDEX idx_auto_volume_record_candle ON auto_volume_record (symbol, open_time);
@@ -0,0 +1,3 @@
+-- SQLite không hỗ trợ partition, price_histories giữ nguyên một bảng.
+-- Migration này chỉ để giữ cùng version với migrations/postgres.
+SELECT 1;
@@ -0,0 +1,10 @@
+-- Trạng thái chỉ báo tính dần theo nến đã đóng, mỗi (symbol, interval) một dòng
+CREATE TABLE IF NOT EXISTS indicator_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    "interval" TEXT NOT NULL,
+    open_time DATETIME NOT NULL,
+    state TEXT NOT NULL,
+    updated_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_indicator_snapshots_series ON indicator_snapshots (symbol, "interval");
@@ -0,0 +1,10 @@
+ALTER TABLE analysis_records DROP COLUMN volatility;
+ALTER TABLE analysis_records DROP COLUMN keltner_lower;
+ALTER TABLE analysis_records DROP COLUMN keltner_middle;
+ALTER TABLE analysis_records DROP COLUMN keltner_upper;
+ALTER TABLE analysis_records DROP COLUMN atr;
+ALTER TABLE analysis_records DROP COLUMN bb_bandwidth;
+ALTER TABLE analysis_records DROP COLUMN bb_percent_b;
+ALTER TABLE analysis_records DROP COLUMN bb_lower;
+ALTER TABLE analysis_records DROP COLUMN bb_middle;
+ALTER TABLE analysis_records DROP COLUMN bb_upper;
@@ -0,0 +1,11 @@
+-- Bollinger Bands, ATR, Keltner Channels và trạng thái biến động của nến cuối
+ALTER TABLE analysis_records ADD COLUMN bb_upper REAL;
+ALTER TABLE analysis_records ADD COLUMN bb_middle REAL;
+ALTER TABLE analysis_records ADD COLUMN bb_lower REAL;
+ALTER TABLE analysis_records ADD COLUMN bb_percent_b REAL;
+ALTER TABLE analysis_records ADD COLUMN bb_bandwidth REAL;
+ALTER TABLE analysis_records ADD COLUMN atr REAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_upper REAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_middle REAL;
+ALTER TABLE analysis_records ADD COLUMN keltner_lower REAL;
+ALTER TABLE analysis_records ADD COLUMN volatility TEXT;
@@ -29,22 +29,45 @@ type AnalysisRecord struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Bollinger Bands, ATR, Keltner Channels và trạng thái biến động (squeeze, expansion, normal)
+	BBUpper       float64 `json:"bb_upper"`
+	BBMiddle      float64 `json:"bb_middle"`
+	BBLower       float64 `json:"bb_lower"`
+	BBPercentB    float64 `json:"bb_percent_b"`
+	BBBandwidth   float64 `json:"bb_bandwidth"`
+	ATR           float64 `json:"atr"`
+	KeltnerUpper  float64 `json:"keltner_upper"`
+	KeltnerMiddle float64 `json:"keltner_middle"`
+	KeltnerLower  float64 `json:"keltner_lower"`
+	Volatility    string  `json:"volatility"`
 }
 
-// PriceHistory lưu trữ lịch sử giá
+// PriceHistory lưu trữ lịch sử giá (kho nến), mỗi nến là duy nhất theo (symbol, interval, open_time)
 type PriceHistory struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Symbol    string         `gorm:"not null;index" json:"symbol"`
-	Interval  string         `gorm:"not null" json:"interval"`
-	OpenTime  time.Time      `gorm:"not null" json:"open_time"`
-	Open      float64        `gorm:"not null" json:"open"`
-	High      float64        `gorm:"not null" json:"high"`
-	Low       float64        `gorm:"not null" json:"low"`
-	Close     float64        `gorm:"not null" json:"close"`
-	Volume    float64        `gorm:"not null" json:"volume"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                  uint           `gorm:"primaryKey" json:"id"`
+	Symbol              string         `gorm:"not null;uniqueIndex:idx_price_histories_candle,priority:1" json:"symbol"`
+	Interval            string         `gorm:"not null;uniqueIndex:idx_price_histories_candle,priority:2" json:"interval"`
+	OpenTime            time.Time      `gorm:"not null;uniqueIndex:idx_price_histories_candle,priority:3" json:"open_time"`
+	CloseTime           time.Time      `json:"close_time"`
+	Open                float64        `gorm:"not null" json:"open"`
+	High                float64        `gorm:"not null" json:"high"`
+	Low                 float64        `gorm:"not null" json:"low"`
+	Close               float64        `gorm:"not null" json:"close"`
+	Volume              float64        `gorm:"not null" json:"volume"`
+	QuoteVolume         float64        `gorm:"not null;default:0" json:"quote_volume"`
+	Trades              int            `gorm:"not null;default:0" json:"trades"`
+	TakerBuyBaseVolume  float64        `gorm:"not null;default:0" json:"taker_buy_base_volume"`
+	TakerBuyQuoteVolume float64        `gorm:"not null;default:0" json:"taker_buy_quote_volume"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// CandleSeries là một chuỗi nến (symbol, interval) trong kho nến
+type CandleSeries struct {
+	Symbol   string
+	Interval string
 }
 
 // TableName định nghĩa tên bảng cho AnalysisRecord
@@ -57,15 +80,16 @@ func (PriceHistory) TableName() string {
 	return "price_histories"
 }
 
+// AutoVolumeRecord lưu nến của volume screener, mỗi nến là duy nhất theo (symbol, open_time)
 type AutoVolumeRecord struct {
-	ID               uint    `gorm:"primaryKey"`
-	Symbol           string  `gorm:"index;not null"`
-	OpenTime         float64 `gorm:"not null"`
-	QuoteAssetVolume float64 `gorm:"not null"`
-	OpenPrice        float64 `gorm:"not null"`
-	ClosePrice       float64 `gorm:"not null"`
-	HighPrice        float64 `gorm:"not null"`
-	LowPrice         float64 `gorm:"not null"`
+	ID               uint      `gorm:"primaryKey"`
+	Symbol           string    `gorm:"index;not null;uniqueIndex:idx_auto_volume_record_candle,priority:1"`
+	OpenTime         time.Time `gorm:"not null;uniqueIndex:idx_auto_volume_record_candle,priority:2"`
+	QuoteAssetVolume float64   `gorm:"not null"`
+	OpenPrice        float64   `gorm:"not null"`
+	ClosePrice       float64   `gorm:"not null"`
+	HighPrice        float64   `gorm:"not null"`
+	LowPrice         float64   `gorm:"not null"`
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
@@ -75,6 +99,18 @@ func (AutoVolumeRecord) TableName() string {
 	return "auto_volume_record"
 }
 
+// ToKline chuyển record về KlineData (chỉ có giá và quote volume) để dùng lại các phép biến đổi nến
+func (r AutoVolumeRecord) ToKline() KlineData {
+	return KlineData{
+		OpenTime:         r.OpenTime.UnixMilli(),
+		Open:             formatFloat(r.OpenPrice),
+		High:             formatFloat(r.HighPrice),
+		Low:              formatFloat(r.LowPrice),
+		Close:            formatFloat(r.ClosePrice),
+		QuoteAssetVolume: formatFloat(r.QuoteAssetVolume),
+	}
+}
+
 func (r *AutoVolumeRecord) Candlestick() float64 {
 	// 1: green, 0: red
 	if r.ClosePrice > r.OpenPrice {
@@ -136,3 +172,56 @@ type NotificationLog struct {
 func (NotificationLog) TableName() string {
 	return "notification_logs"
 }
+
+// BackfillCheckpoint lưu tiến độ backfill nến cho một symbol/interval trong khoảng [StartTime, EndTime).
+// Backfill lùi dần từ EndTime về StartTime, Cursor là open_time của nến cũ nhất đã lưu.
+type BackfillCheckpoint struct {
+	ID        uint      `gorm:"primaryKey"`
+	Symbol    string    `gorm:"not null;uniqueIndex:idx_backfill_checkpoints_job,priority:1"`
+	Interval  string    `gorm:"not null;uniqueIndex:idx_backfill_checkpoints_job,priority:2"`
+	StartTime time.Time `gorm:"not null;uniqueIndex:idx_backfill_checkpoints_job,priority:3"`
+	EndTime   time.Time `gorm:"not null;uniqueIndex:idx_backfill_checkpoints_job,priority:4"`
+	Cursor    time.Time `gorm:"not null"`
+	Candles   int64     `gorm:"not null;default:0"`
+	Completed bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName định nghĩa tên bảng cho BackfillCheckpoint
+func (BackfillCheckpoint) TableName() string {
+	return "backfill_checkpoints"
+}
+
+// QuarantinedKline lưu nến bị loại khi kiểm tra dữ liệu từ sàn, kèm payload gốc để điều tra
+type QuarantinedKline struct {
+	ID         uint   `gorm:"primaryKey"`
+	Source     string `gorm:"not null"` // rest hoặc stream
+	Symbol     string `gorm:"not null;index"`
+	Interval   string `gorm:"not null"`
+	OpenTime   int64  `gorm:"not null"`
+	Reason     string `gorm:"not null"`
+	RawPayload string `gorm:"type:text;not null"`
+	CreatedAt  time.Time
+}
+
+// TableName định nghĩa tên bảng cho QuarantinedKline
+func (QuarantinedKline) TableName() string {
+	return "quarantined_klines"
+}
+
+// IndicatorSnapshot lưu trạng thái các chỉ báo tính dần theo nến đã đóng của một (symbol, interval),
+// State là JSON do services.IndicatorSet tạo ra, OpenTime là nến đã đóng cuối cùng được cập nhật
+type IndicatorSnapshot struct {
+	ID        uint      `gorm:"primaryKey"`
+	Symbol    string    `gorm:"not null;uniqueIndex:idx_indicator_snapshots_series,priority:1"`
+	Interval  string    `gorm:"not null;uniqueIndex:idx_indicator_snapshots_series,priority:2"`
+	OpenTime  time.Time `gorm:"not null"`
+	State     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time
+}
+
+// TableName định nghĩa tên bảng cho IndicatorSnapshot
+func (IndicatorSnapshot) TableName() string {
+	return "indicator_snapshots"
+}
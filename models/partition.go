@@ -0,0 +1,175 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// partitionNamePattern khớp tên partition tháng của price_histories, ví dụ price_histories_2024_01
+var partitionNamePattern = regexp.MustCompile(`^price_histories_(\d{4})_(\d{2})$`)
+
+// PartitionReport là kết quả một lần bảo trì partition
+type PartitionReport struct {
+	Created []string `json:"created,omitempty"`
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// PartitionManager tạo trước partition tháng của price_histories và xóa partition hết hạn.
+// Chỉ PostgreSQL có partition (migration 0003), với driver khác mọi thao tác là no-op.
+type PartitionManager struct {
+	db          *gorm.DB
+	monthsAhead int
+}
+
+// NewPartitionManager tạo manager giữ sẵn partition cho monthsAhead tháng tới, cần gọi sau InitDatabase
+func NewPartitionManager(monthsAhead int) *PartitionManager {
+	return &PartitionManager{db: DB, monthsAhead: monthsAhead}
+}
+
+// Enabled cho biết database có dùng partition không
+func (m *PartitionManager) Enabled() bool {
+	return m.db.Dialector.Name() == "postgres"
+}
+
+// Maintain tạo partition cho tháng hiện tại, monthsAhead tháng tới và các tháng có nến nằm trong
+// partition default (ví dụ nến backfill), rồi xóa các partition có toàn bộ nến cũ hơn retention (0 là giữ mãi)
+func (m *PartitionManager) Maintain(now time.Time, retention time.Duration) (PartitionReport, error) {
+	var report PartitionReport
+	if !m.Enabled() {
+		return report, nil
+	}
+
+	existing, err := m.listPartitions()
+	if err != nil {
+		return report, err
+	}
+
+	var cutoff time.Time
+	if retention > 0 {
+		cutoff = now.Add(-retention)
+	}
+
+	months, err := m.defaultPartitionMonths()
+	if err != nil {
+		return report, err
+	}
+	current := monthStart(now)
+	for i := 0; i <= m.monthsAhead; i++ {
+		months = append(months, current.AddDate(0, i, 0))
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	for _, month := range months {
+		name := partitionName(month)
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		// Tháng đã hết hạn thì để retention xóa nến, không cần tạo partition
+		if !cutoff.IsZero() && !month.AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+		if err := m.createPartition(name, month); err != nil {
+			return report, err
+		}
+		existing[name] = month
+		report.Created = append(report.Created, name)
+	}
+
+	if cutoff.IsZero() {
+		return report, nil
+	}
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if existing[name].AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+		if err := m.db.Exec(fmt.Sprintf(`DROP TABLE %q`, name)).Error; err != nil {
+			return report, fmt.Errorf("lỗi xóa partition %s: %v", name, err)
+		}
+		report.Dropped = append(report.Dropped, name)
+	}
+	return report, nil
+}
+
+// listPartitions lấy các partition tháng hiện có, key là tên partition, value là tháng bắt đầu
+func (m *PartitionManager) listPartitions() (map[string]time.Time, error) {
+	var names []string
+	err := m.db.Raw(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'price_histories'::regclass`).Scan(&names).Error
+	if err != nil {
+		return nil, fmt.Errorf("lỗi đọc danh sách partition: %v", err)
+	}
+
+	partitions := make(map[string]time.Time)
+	for _, name := range names {
+		match := partitionNamePattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		month, err := time.Parse("2006_01", match[1]+"_"+match[2])
+		if err != nil {
+			continue
+		}
+		partitions[name] = month
+	}
+	return partitions, nil
+}
+
+// defaultPartitionMonths lấy các tháng (UTC) có nến nằm trong partition default
+func (m *PartitionManager) defaultPartitionMonths() ([]time.Time, error) {
+	var months []time.Time
+	err := m.db.Raw(`SELECT DISTINCT date_trunc('month', open_time AT TIME ZONE 'UTC') FROM price_histories_default`).
+		Scan(&months).Error
+	if err != nil {
+		return nil, fmt.Errorf("lỗi đọc partition default: %v", err)
+	}
+	for i, month := range months {
+		months[i] = monthStart(month)
+	}
+	return months, nil
+}
+
+// createPartition tạo partition cho tháng month. Nến của tháng đó đang nằm trong partition default
+// được chuyển sang partition mới trong cùng transaction (PostgreSQL không cho tạo partition khi default còn nến thuộc khoảng đó).
+func (m *PartitionManager) createPartition(name string, month time.Time) error {
+	from := month.Format("2006-01-02 15:04:05-07")
+	to := month.AddDate(0, 1, 0).Format("2006-01-02 15:04:05-07")
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			fmt.Sprintf(`CREATE TABLE %q (LIKE price_histories INCLUDING DEFAULTS)`, name),
+			fmt.Sprintf(`INSERT INTO %q SELECT * FROM price_histories_default WHERE open_time >= '%s' AND open_time < '%s'`, name, from, to),
+			fmt.Sprintf(`DELETE FROM price_histories_default WHERE open_time >= '%s' AND open_time < '%s'`, from, to),
+			fmt.Sprintf(`ALTER TABLE price_histories ATTACH PARTITION %q FOR VALUES FROM ('%s') TO ('%s')`, name, from, to),
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("lỗi tạo partition %s: %v", name, err)
+	}
+	return nil
+}
+
+// partitionName trả về tên partition của tháng, ví dụ price_histories_2024_01
+func partitionName(month time.Time) string {
+	return "price_histories_" + month.Format("2006_01")
+}
+
+// monthStart trả về thời điểm đầu tháng (UTC) chứa t
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AnalysisRepository xử lý thao tác với bảng analysis_records
@@ -31,6 +32,18 @@ func (r *AnalysisRepository) GetBySymbolAndInterval(symbol, interval string, lim
 	return records, err
 }
 
+// DeleteOlderThan xóa hẳn các phân tích tạo trước cutoff, trả về số dòng đã xóa
+func (r *AnalysisRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("created_at < ?", cutoff).Delete(&AnalysisRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeDeleted xóa hẳn các phân tích đã bị xóa mềm trước đây, trả về số dòng đã xóa
+func (r *AnalysisRepository) PurgeDeleted() (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&AnalysisRecord{})
+	return result.RowsAffected, result.Error
+}
+
 // GetLatestAnalysis lấy phân tích mới nhất
 func (r *AnalysisRepository) GetLatestAnalysis(symbol, interval string) (*AnalysisRecord, error) {
 	var record AnalysisRecord
@@ -80,10 +93,51 @@ func (r *PriceHistoryRepository) GetLatestPrice(symbol, interval string) (*Price
 	return &history, nil
 }
 
-// DeleteOldData xóa dữ liệu cũ hơn một khoảng thời gian
-func (r *PriceHistoryRepository) DeleteOldData(symbol, interval string, olderThan time.Time) error {
-	return r.db.Where("symbol = ? AND interval = ? AND open_time < ?", symbol, interval, olderThan).
-		Delete(&PriceHistory{}).Error
+// DeleteOldData xóa hẳn (không xóa mềm) các nến có open_time cũ hơn olderThan, trả về số dòng đã xóa
+func (r *PriceHistoryRepository) DeleteOldData(symbol, interval string, olderThan time.Time) (int64, error) {
+	result := r.db.Unscoped().
+		Where("symbol = ? AND interval = ? AND open_time < ?", symbol, interval, olderThan).
+		Delete(&PriceHistory{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteRange xóa hẳn các nến có open_time trong [from, to), trả về số dòng đã xóa
+func (r *PriceHistoryRepository) DeleteRange(symbol, interval string, from, to time.Time) (int64, error) {
+	result := r.db.Unscoped().
+		Where("symbol = ? AND interval = ? AND open_time >= ? AND open_time < ?", symbol, interval, from, to).
+		Delete(&PriceHistory{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeDeleted xóa hẳn các nến đã bị xóa mềm trước đây, trả về số dòng đã xóa
+func (r *PriceHistoryRepository) PurgeDeleted() (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&PriceHistory{})
+	return result.RowsAffected, result.Error
+}
+
+// ListSeries lấy các cặp (symbol, interval) đang có nến trong kho
+func (r *PriceHistoryRepository) ListSeries() ([]CandleSeries, error) {
+	var series []CandleSeries
+	err := r.db.Model(&PriceHistory{}).
+		Distinct("symbol", "interval").
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: "symbol"}},
+			{Column: clause.Column{Name: "interval"}},
+		}}).
+		Scan(&series).Error
+	return series, err
+}
+
+// GetOldest lấy nến cũ nhất của symbol/interval
+func (r *PriceHistoryRepository) GetOldest(symbol, interval string) (*PriceHistory, error) {
+	var history PriceHistory
+	err := r.db.Where("symbol = ? AND interval = ?", symbol, interval).
+		Order("open_time ASC").
+		First(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
 }
 
 // GetCount lấy số lượng record cho symbol và interval
@@ -95,6 +149,40 @@ func (r *PriceHistoryRepository) GetCount(symbol, interval string) (int64, error
 	return count, err
 }
 
+// UpsertCandles lưu danh sách nến, nến đã tồn tại (symbol, interval, open_time) sẽ được cập nhật
+// và khôi phục nếu trước đó bị xoá mềm
+func (r *PriceHistoryRepository) UpsertCandles(candles []PriceHistory) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "symbol"}, {Name: "interval"}, {Name: "open_time"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"close_time", "open", "high", "low", "close", "volume", "quote_volume",
+			"trades", "taker_buy_base_volume", "taker_buy_quote_volume", "updated_at", "deleted_at",
+		}),
+	}).CreateInBatches(&candles, 500).Error
+}
+
+// GetRange lấy các nến có open_time trong [from, to), sắp xếp tăng dần theo thời gian
+func (r *PriceHistoryRepository) GetRange(symbol, interval string, from, to time.Time) ([]PriceHistory, error) {
+	var histories []PriceHistory
+	err := r.db.Where("symbol = ? AND interval = ? AND open_time >= ? AND open_time < ?", symbol, interval, from, to).
+		Order("open_time ASC").
+		Find(&histories).Error
+	return histories, err
+}
+
+// GetOpenTimes lấy open_time của các nến trong [from, to), tăng dần, dùng để kiểm tra nến bị thiếu
+func (r *PriceHistoryRepository) GetOpenTimes(symbol, interval string, from, to time.Time) ([]time.Time, error) {
+	var openTimes []time.Time
+	err := r.db.Model(&PriceHistory{}).
+		Where("symbol = ? AND interval = ? AND open_time >= ? AND open_time < ?", symbol, interval, from, to).
+		Order("open_time ASC").
+		Pluck("open_time", &openTimes).Error
+	return openTimes, err
+}
+
 type SymbolRepository struct {
 	db *gorm.DB
 }
@@ -123,9 +211,10 @@ func (r *SymbolRepository) UpdateLastUpdateTime() error {
 	return r.db.Save(&dataUpdate).Error
 }
 
-func (r *SymbolRepository) GetAllSymbols() ([]string, error) {
+// GetAllSymbols lấy danh sách symbol đang giao dịch thuộc các quote asset (rỗng là mọi quote asset)
+func (r *SymbolRepository) GetAllSymbols(quoteAssets []string) ([]string, error) {
 	var symbols []Symbol
-	err := r.db.Find(&symbols).Error
+	err := whereQuoteAssets(r.db, quoteAssets).Where("status = ?", SymbolStatusTrading).Find(&symbols).Error
 	if err != nil {
 		return nil, err
 	}
@@ -136,28 +225,138 @@ func (r *SymbolRepository) GetAllSymbols() ([]string, error) {
 	return result, nil
 }
 
+// GetTradingSymbols lấy thông tin đầy đủ của các symbol đang giao dịch thuộc các quote asset (rỗng là mọi quote asset)
+func (r *SymbolRepository) GetTradingSymbols(quoteAssets []string) ([]Symbol, error) {
+	var symbols []Symbol
+	err := whereQuoteAssets(r.db, quoteAssets).Where("status = ?", SymbolStatusTrading).Find(&symbols).Error
+	return symbols, err
+}
+
+// whereQuoteAssets lọc symbol theo quote asset, không lọc khi quoteAssets rỗng
+func whereQuoteAssets(db *gorm.DB, quoteAssets []string) *gorm.DB {
+	if len(quoteAssets) == 0 {
+		return db
+	}
+	return db.Where("quote_asset IN ?", quoteAssets)
+}
+
+// GetBySymbol lấy thông tin của một symbol
+func (r *SymbolRepository) GetBySymbol(symbol string) (*Symbol, error) {
+	var result Symbol
+	err := r.db.Where("symbol = ?", symbol).First(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (r *SymbolRepository) GetSymbolByBaseAsset(baseAsset string) ([]Symbol, error) {
 	var symbols []Symbol
 	err := r.db.Where("base_asset = ?", baseAsset).First(&symbols).Error
 	return symbols, err
 }
 
-func (r *SymbolRepository) SaveToDatabase(symbols []Symbol) error {
-	// Xoá dữ liệu cũ
-	if err := r.db.Unscoped().Where("1 = 1").Delete(&Symbol{}).Error; err != nil {
-		return err
-	}
-	// Lưu dữ liệu mới
-	if len(symbols) > 0 {
-		if err := r.db.Create(&symbols).Error; err != nil {
+// SyncSymbols đồng bộ bảng symbols với danh sách mới trong một transaction:
+// thêm symbol mới, cập nhật trạng thái thay đổi và đánh dấu REMOVED cho symbol biến mất.
+// Chỉ so sánh với các symbol đã lưu thuộc quoteAssets (danh sách mới đã được lọc theo quoteAssets),
+// symbol của quote asset bị tắt được giữ nguyên. Các thay đổi được ghi vào symbol_histories và trả về cho caller.
+func (r *SymbolRepository) SyncSymbols(symbols []Symbol, quoteAssets []string) ([]SymbolHistory, error) {
+	var events []SymbolHistory
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []Symbol
+		if err := whereQuoteAssets(tx, quoteAssets).Find(&existing).Error; err != nil {
 			return err
 		}
+
+		var upserts []Symbol
+		upserts, events = diffSymbols(existing, symbols, time.Now())
+		if len(upserts) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "symbol"}},
+				DoUpdates: clause.AssignmentColumns([]string{"status", "base_asset", "quote_asset", "tick_size", "step_size", "min_qty", "min_notional", "updated_at"}),
+			}).Create(&upserts).Error; err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return events, nil
 }
 
-const updateInterval = 15 * 24 * time.Hour // 15 ngày
-func (r *SymbolRepository) ShouldUpdate() bool {
+// diffSymbols so sánh danh sách symbol đang lưu với danh sách mới, trả về các symbol cần upsert
+// (mới, đổi trạng thái/thông tin, hoặc bị gỡ) và các sự kiện tương ứng
+func diffSymbols(existing, symbols []Symbol, now time.Time) ([]Symbol, []SymbolHistory) {
+	existingBySymbol := make(map[string]Symbol, len(existing))
+	for _, s := range existing {
+		existingBySymbol[s.Symbol] = s
+	}
+
+	seen := make(map[string]bool, len(symbols))
+	var upserts []Symbol
+	var events []SymbolHistory
+	for _, s := range symbols {
+		if seen[s.Symbol] {
+			continue
+		}
+		seen[s.Symbol] = true
+
+		old, ok := existingBySymbol[s.Symbol]
+		switch {
+		case !ok:
+			events = append(events, SymbolHistory{Symbol: s.Symbol, Event: SymbolEventAdded, NewStatus: s.Status, CreatedAt: now})
+		case old.Status != s.Status:
+			events = append(events, SymbolHistory{Symbol: s.Symbol, Event: SymbolEventStatusChanged, OldStatus: old.Status, NewStatus: s.Status, CreatedAt: now})
+		case !symbolDetailsChanged(old, s):
+			// Không có thay đổi
+			continue
+		}
+		s.ID = 0
+		upserts = append(upserts, s)
+	}
+
+	for _, old := range existing {
+		if seen[old.Symbol] || old.Status == SymbolStatusRemoved {
+			continue
+		}
+		events = append(events, SymbolHistory{Symbol: old.Symbol, Event: SymbolEventRemoved, OldStatus: old.Status, NewStatus: SymbolStatusRemoved, CreatedAt: now})
+		removed := old
+		removed.ID = 0
+		removed.Status = SymbolStatusRemoved
+		upserts = append(upserts, removed)
+	}
+	return upserts, events
+}
+
+// symbolDetailsChanged so sánh các thông tin ngoài trạng thái của symbol
+func symbolDetailsChanged(old, current Symbol) bool {
+	return old.BaseAsset != current.BaseAsset ||
+		old.QuoteAsset != current.QuoteAsset ||
+		old.TickSize != current.TickSize ||
+		old.StepSize != current.StepSize ||
+		old.MinQty != current.MinQty ||
+		old.MinNotional != current.MinNotional
+}
+
+// GetSymbolHistory lấy lịch sử thay đổi gần nhất của một symbol
+func (r *SymbolRepository) GetSymbolHistory(symbol string, limit int) ([]SymbolHistory, error) {
+	var histories []SymbolHistory
+	err := r.db.Where("symbol = ?", symbol).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&histories).Error
+	return histories, err
+}
+
+// ShouldUpdate kiểm tra lần cập nhật symbol gần nhất đã cũ hơn updateInterval hay chưa
+func (r *SymbolRepository) ShouldUpdate(updateInterval time.Duration) bool {
 	var dataUpdate DataUpdate
 	err := r.db.Model(&DataUpdate{}).Where("table_name = ?", "symbols").First(&dataUpdate).Error
 	if err != nil {
@@ -166,6 +365,13 @@ func (r *SymbolRepository) ShouldUpdate() bool {
 	return time.Since(dataUpdate.LastUpdate) > updateInterval
 }
 
+// HasSymbols kiểm tra bảng symbols đã có symbol nào của quoteAsset hay chưa
+func (r *SymbolRepository) HasSymbols(quoteAsset string) (bool, error) {
+	var count int64
+	err := r.db.Model(&Symbol{}).Where("quote_asset = ?", quoteAsset).Count(&count).Error
+	return count > 0, err
+}
+
 type AutoVolumeRecordRepository struct {
 	db *gorm.DB
 }
@@ -206,6 +412,45 @@ func (r *AutoVolumeRecordRepository) ReplaceAllForSymbol(symbol string, records
 	return tx.Commit().Error
 }
 
+// SaveClosedCandle thêm hoặc cập nhật một nến đã đóng theo (symbol, open_time)
+// và chỉ giữ lại keep nến mới nhất của symbol
+func (r *AutoVolumeRecordRepository) SaveClosedCandle(record *AutoVolumeRecord, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing AutoVolumeRecord
+		// Tìm cả bản ghi đã bị xoá mềm vì unique index (symbol, open_time) tính cả các dòng đó
+		result := tx.Unscoped().Where("symbol = ? AND open_time = ?", record.Symbol, record.OpenTime).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			record.ID = existing.ID
+			record.CreatedAt = existing.CreatedAt
+			if err := tx.Save(record).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+
+		// Xoá các nến cũ hơn keep nến mới nhất
+		var cutoff []time.Time
+		if err := tx.Model(&AutoVolumeRecord{}).
+			Where("symbol = ?", record.Symbol).
+			Order("open_time DESC").
+			Offset(keep).
+			Limit(1).
+			Pluck("open_time", &cutoff).Error; err != nil {
+			return err
+		}
+		if len(cutoff) > 0 {
+			return tx.Unscoped().
+				Where("symbol = ? AND open_time <= ?", record.Symbol, cutoff[0]).
+				Delete(&AutoVolumeRecord{}).Error
+		}
+		return nil
+	})
+}
+
 func (r *AutoVolumeRecordRepository) GetLastNBySymbol(symbol string, n int) ([]AutoVolumeRecord, error) {
 	var records []AutoVolumeRecord
 	err := r.db.Where("symbol = ?", symbol).Order("open_time DESC").Limit(n).Find(&records).Error
@@ -230,10 +475,105 @@ func (r *NotificationLogRepository) Create(log *NotificationLog) error {
 // CountBySymbolToday đếm số lần gửi tin nhắn cho một symbol trong ngày hôm nay
 func (r *NotificationLogRepository) CountBySymbolToday(symbol string) (int64, error) {
 	var count int64
-	loc := time.FixedZone("UTC+7", 7*60*60)
-	today := time.Now().In(loc).Truncate(24 * time.Hour)
 	err := r.db.Model(&NotificationLog{}).
-		Where("symbol = ? AND created_at >= ?", symbol, today).
+		Where("symbol = ? AND created_at >= ?", symbol, notificationDayStart(time.Now())).
 		Count(&count).Error
 	return count, err
 }
+
+// DeleteOlderThan xóa các log gửi trước cutoff, trả về số dòng đã xóa
+func (r *NotificationLogRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&NotificationLog{})
+	return result.RowsAffected, result.Error
+}
+
+// notificationDayStart trả về mốc bắt đầu ngày dùng để đếm số tin nhắn đã gửi trong ngày
+func notificationDayStart(now time.Time) time.Time {
+	loc := time.FixedZone("UTC+7", 7*60*60)
+	return now.In(loc).Truncate(24 * time.Hour)
+}
+
+// BackfillCheckpointRepository xử lý thao tác với bảng backfill_checkpoints
+type BackfillCheckpointRepository struct {
+	db *gorm.DB
+}
+
+// NewBackfillCheckpointRepository tạo instance mới
+func NewBackfillCheckpointRepository() *BackfillCheckpointRepository {
+	return &BackfillCheckpointRepository{db: DB}
+}
+
+// GetOrCreate lấy checkpoint của job backfill, tạo mới với Cursor = endTime nếu chưa có
+func (r *BackfillCheckpointRepository) GetOrCreate(symbol, interval string, startTime, endTime time.Time) (*BackfillCheckpoint, error) {
+	checkpoint := BackfillCheckpoint{
+		Symbol:    symbol,
+		Interval:  interval,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+	err := r.db.Where("symbol = ? AND interval = ? AND start_time = ? AND end_time = ?", symbol, interval, startTime, endTime).
+		Attrs(BackfillCheckpoint{Cursor: endTime}).
+		FirstOrCreate(&checkpoint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Save cập nhật tiến độ của checkpoint
+func (r *BackfillCheckpointRepository) Save(checkpoint *BackfillCheckpoint) error {
+	return r.db.Save(checkpoint).Error
+}
+
+// KlineQuarantineRepository xử lý thao tác với bảng quarantined_klines
+type KlineQuarantineRepository struct {
+	db *gorm.DB
+}
+
+// NewKlineQuarantineRepository tạo instance mới
+func NewKlineQuarantineRepository() *KlineQuarantineRepository {
+	return &KlineQuarantineRepository{db: DB}
+}
+
+// CreateBatch lưu danh sách nến bị loại
+func (r *KlineQuarantineRepository) CreateBatch(records []QuarantinedKline) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.Create(&records).Error
+}
+
+// GetRecent lấy các nến bị loại gần nhất
+func (r *KlineQuarantineRepository) GetRecent(limit int) ([]QuarantinedKline, error) {
+	var records []QuarantinedKline
+	err := r.db.Order("created_at DESC").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// IndicatorSnapshotRepository xử lý thao tác với bảng indicator_snapshots
+type IndicatorSnapshotRepository struct {
+	db *gorm.DB
+}
+
+// NewIndicatorSnapshotRepository tạo instance mới
+func NewIndicatorSnapshotRepository() *IndicatorSnapshotRepository {
+	return &IndicatorSnapshotRepository{db: DB}
+}
+
+// Get lấy snapshot của symbol/interval, trả về gorm.ErrRecordNotFound nếu chưa có
+func (r *IndicatorSnapshotRepository) Get(symbol, interval string) (*IndicatorSnapshot, error) {
+	var snapshot IndicatorSnapshot
+	err := r.db.Where("symbol = ? AND interval = ?", symbol, interval).First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Save ghi đè snapshot của symbol/interval
+func (r *IndicatorSnapshotRepository) Save(snapshot *IndicatorSnapshot) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "interval"}},
+		DoUpdates: clause.AssignmentColumns([]string{"open_time", "state", "updated_at"}),
+	}).Create(snapshot).Error
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"chatbtc/config"
+)
+
+// setupTestDB mở database SQLite trong bộ nhớ (DB_DRIVER=sqlite, DB_PATH=:memory:) và chạy toàn bộ migration
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{DBDriver: "sqlite", DBPath: ":memory:"}
+	if err := InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	if err := MigrateDatabase(); err != nil {
+		t.Fatalf("MigrateDatabase: %v", err)
+	}
+	t.Cleanup(func() {
+		CloseDatabase()
+		DB = nil
+		config.AppConfig = previous
+	})
+}
+
+func testCandle(symbol, interval string, openTime time.Time, close float64) PriceHistory {
+	return PriceHistory{
+		Symbol:    symbol,
+		Interval:  interval,
+		OpenTime:  openTime,
+		CloseTime: openTime.Add(time.Hour - time.Millisecond),
+		Open:      close - 1,
+		High:      close + 1,
+		Low:       close - 2,
+		Close:     close,
+		Volume:    10,
+	}
+}
+
+func TestPriceHistoryUpsertCandlesUpdatesExisting(t *testing.T) {
+	setupTestDB(t)
+	repo := NewPriceHistoryRepository()
+	openTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	if err := repo.UpsertCandles([]PriceHistory{testCandle("BTCUSDT", "1h", openTime, 100)}); err != nil {
+		t.Fatalf("UpsertCandles: %v", err)
+	}
+	first, err := repo.GetLatestPrice("BTCUSDT", "1h")
+	if err != nil {
+		t.Fatalf("GetLatestPrice: %v", err)
+	}
+
+	// Upsert lại cùng nến với giá mới và thêm một nến kế tiếp
+	updated := testCandle("BTCUSDT", "1h", openTime, 105)
+	next := testCandle("BTCUSDT", "1h", openTime.Add(time.Hour), 110)
+	if err := repo.UpsertCandles([]PriceHistory{updated, next}); err != nil {
+		t.Fatalf("UpsertCandles again: %v", err)
+	}
+
+	if count, _ := repo.GetCount("BTCUSDT", "1h"); count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+	candles, err := repo.GetRange("BTCUSDT", "1h", openTime, openTime.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(candles) != 2 || candles[0].Close != 105 || candles[1].Close != 110 {
+		t.Fatalf("candles = %+v, want closes 105, 110", candles)
+	}
+	if candles[0].ID != first.ID {
+		t.Errorf("re-upserted candle ID = %d, want %d", candles[0].ID, first.ID)
+	}
+}
+
+func TestPriceHistoryRangeWithNonUTCTimes(t *testing.T) {
+	setupTestDB(t)
+	repo := NewPriceHistoryRepository()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var candles []PriceHistory
+	for i := 0; i < 6; i++ {
+		candles = append(candles, testCandle("ETHUSDT", "1h", start.Add(time.Duration(i)*time.Hour), float64(100+i)))
+	}
+	if err := repo.UpsertCandles(candles); err != nil {
+		t.Fatalf("UpsertCandles: %v", err)
+	}
+
+	// Cùng thời điểm 02:00-05:00 UTC nhưng truyền vào theo UTC+7 và UTC-5
+	from := start.Add(2 * time.Hour).In(time.FixedZone("UTC+7", 7*60*60))
+	to := start.Add(5 * time.Hour).In(time.FixedZone("UTC-5", -5*60*60))
+
+	histories, err := repo.GetRange("ETHUSDT", "1h", from, to)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if len(histories) != 3 || !histories[0].OpenTime.Equal(start.Add(2*time.Hour)) || !histories[2].OpenTime.Equal(start.Add(4*time.Hour)) {
+		t.Fatalf("GetRange returned %d candles %+v, want 02:00-04:00 UTC", len(histories), histories)
+	}
+
+	openTimes, err := repo.GetOpenTimes("ETHUSDT", "1h", from, to)
+	if err != nil {
+		t.Fatalf("GetOpenTimes: %v", err)
+	}
+	if len(openTimes) != 3 {
+		t.Fatalf("GetOpenTimes returned %v, want 3 open times", openTimes)
+	}
+	for i, openTime := range openTimes {
+		if want := start.Add(time.Duration(i+2) * time.Hour); !openTime.Equal(want) {
+			t.Errorf("openTimes[%d] = %v, want %v", i, openTime, want)
+		}
+	}
+
+	deleted, err := repo.DeleteOldData("ETHUSDT", "1h", from)
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteOldData = %d, %v; want 2 rows", deleted, err)
+	}
+}
+
+func TestAutoVolumeSaveClosedCandle(t *testing.T) {
+	setupTestDB(t)
+	repo := NewAutoVolumeRecordRepository()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	record := func(i int, volume float64) *AutoVolumeRecord {
+		return &AutoVolumeRecord{Symbol: "BTCUSDT", OpenTime: start.Add(time.Duration(i) * time.Hour), QuoteAssetVolume: volume}
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := repo.SaveClosedCandle(record(i, float64(i)), 3); err != nil {
+			t.Fatalf("SaveClosedCandle %d: %v", i, err)
+		}
+	}
+	// Lưu lại nến cuối với volume mới: cập nhật chứ không thêm dòng
+	again := record(4, 40)
+	if err := repo.SaveClosedCandle(again, 3); err != nil {
+		t.Fatalf("SaveClosedCandle again: %v", err)
+	}
+
+	records, err := repo.GetLastNBySymbol("BTCUSDT", 10)
+	if err != nil {
+		t.Fatalf("GetLastNBySymbol: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("kept %d records, want 3", len(records))
+	}
+	if !records[0].OpenTime.Equal(start.Add(4*time.Hour)) || records[0].QuoteAssetVolume != 40 {
+		t.Errorf("newest record = %v/%v, want 04:00 with volume 40", records[0].OpenTime, records[0].QuoteAssetVolume)
+	}
+	if !records[2].OpenTime.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("oldest kept record at %v, want 02:00", records[2].OpenTime)
+	}
+
+	var total int64
+	DB.Unscoped().Model(&AutoVolumeRecord{}).Count(&total)
+	if total != 3 {
+		t.Errorf("table has %d rows including soft-deleted, want 3", total)
+	}
+}
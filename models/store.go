@@ -0,0 +1,130 @@
+package models
+
+import "time"
+
+// AnalysisStore lưu lịch sử phân tích (AnalysisRepository hoặc MemoryAnalysisRepository)
+type AnalysisStore interface {
+	Create(record *AnalysisRecord) error
+	GetBySymbolAndInterval(symbol, interval string, limit int) ([]AnalysisRecord, error)
+	GetLatestAnalysis(symbol, interval string) (*AnalysisRecord, error)
+	DeleteOlderThan(cutoff time.Time) (int64, error)
+	PurgeDeleted() (int64, error)
+}
+
+// PriceHistoryStore là kho nến (PriceHistoryRepository hoặc MemoryPriceHistoryRepository)
+type PriceHistoryStore interface {
+	Create(history *PriceHistory) error
+	GetBySymbolAndInterval(symbol, interval string, limit int) ([]PriceHistory, error)
+	GetLatestPrice(symbol, interval string) (*PriceHistory, error)
+	DeleteOldData(symbol, interval string, olderThan time.Time) (int64, error)
+	DeleteRange(symbol, interval string, from, to time.Time) (int64, error)
+	PurgeDeleted() (int64, error)
+	ListSeries() ([]CandleSeries, error)
+	GetOldest(symbol, interval string) (*PriceHistory, error)
+	GetCount(symbol, interval string) (int64, error)
+	UpsertCandles(candles []PriceHistory) error
+	GetRange(symbol, interval string, from, to time.Time) ([]PriceHistory, error)
+	GetOpenTimes(symbol, interval string, from, to time.Time) ([]time.Time, error)
+}
+
+// SymbolStore lưu danh sách symbol và lịch sử niêm yết (SymbolRepository hoặc MemorySymbolRepository)
+type SymbolStore interface {
+	Create(symbol *Symbol) error
+	UpdateLastUpdateTime() error
+	GetAllSymbols(quoteAssets []string) ([]string, error)
+	GetTradingSymbols(quoteAssets []string) ([]Symbol, error)
+	GetBySymbol(symbol string) (*Symbol, error)
+	GetSymbolByBaseAsset(baseAsset string) ([]Symbol, error)
+	SyncSymbols(symbols []Symbol, quoteAssets []string) ([]SymbolHistory, error)
+	GetSymbolHistory(symbol string, limit int) ([]SymbolHistory, error)
+	ShouldUpdate(updateInterval time.Duration) bool
+	HasSymbols(quoteAsset string) (bool, error)
+}
+
+// AutoVolumeStore lưu nến của volume screener (AutoVolumeRecordRepository hoặc MemoryAutoVolumeRecordRepository)
+type AutoVolumeStore interface {
+	Create(record *AutoVolumeRecord) error
+	ReplaceAllForSymbol(symbol string, records []AutoVolumeRecord) error
+	SaveClosedCandle(record *AutoVolumeRecord, keep int) error
+	GetLastNBySymbol(symbol string, n int) ([]AutoVolumeRecord, error)
+}
+
+// NotificationLogStore lưu log gửi cảnh báo (NotificationLogRepository hoặc MemoryNotificationLogRepository)
+type NotificationLogStore interface {
+	Create(log *NotificationLog) error
+	CountBySymbolToday(symbol string) (int64, error)
+	DeleteOlderThan(cutoff time.Time) (int64, error)
+}
+
+// IndicatorSnapshotStore lưu trạng thái chỉ báo (IndicatorSnapshotRepository hoặc MemoryIndicatorSnapshotRepository)
+type IndicatorSnapshotStore interface {
+	Get(symbol, interval string) (*IndicatorSnapshot, error)
+	Save(snapshot *IndicatorSnapshot) error
+}
+
+// BackfillCheckpointStore lưu tiến độ backfill (BackfillCheckpointRepository hoặc MemoryBackfillCheckpointRepository)
+type BackfillCheckpointStore interface {
+	GetOrCreate(symbol, interval string, startTime, endTime time.Time) (*BackfillCheckpoint, error)
+	Save(checkpoint *BackfillCheckpoint) error
+}
+
+// KlineQuarantineStore lưu các nến bị loại (KlineQuarantineRepository hoặc MemoryKlineQuarantineRepository)
+type KlineQuarantineStore interface {
+	CreateBatch(records []QuarantinedKline) error
+	GetRecent(limit int) ([]QuarantinedKline, error)
+}
+
+// Repositories gom các repository mà service dùng để truyền vào constructor
+type Repositories struct {
+	Analysis          AnalysisStore
+	PriceHistory      PriceHistoryStore
+	Symbol            SymbolStore
+	AutoVolume        AutoVolumeStore
+	NotificationLog   NotificationLogStore
+	IndicatorSnapshot IndicatorSnapshotStore
+
+	BackfillCheckpoint BackfillCheckpointStore
+	KlineQuarantine    KlineQuarantineStore
+}
+
+// NewRepositories tạo các repository dùng database (models.DB), cần gọi sau InitDatabase
+func NewRepositories() Repositories {
+	return Repositories{
+		Analysis:          NewAnalysisRepository(),
+		PriceHistory:      NewPriceHistoryRepository(),
+		Symbol:            NewSymbolRepository(),
+		AutoVolume:        NewAutoVolumeRecordRepository(),
+		NotificationLog:   NewNotificationLogRepository(),
+		IndicatorSnapshot: NewIndicatorSnapshotRepository(),
+
+		BackfillCheckpoint: NewBackfillCheckpointRepository(),
+		KlineQuarantine:    NewKlineQuarantineRepository(),
+	}
+}
+
+// NewMemoryRepositories tạo các repository lưu trong bộ nhớ, dùng cho test hoặc chạy thử không cần database
+func NewMemoryRepositories() Repositories {
+	return Repositories{
+		Analysis:          NewMemoryAnalysisRepository(),
+		PriceHistory:      NewMemoryPriceHistoryRepository(),
+		Symbol:            NewMemorySymbolRepository(),
+		AutoVolume:        NewMemoryAutoVolumeRecordRepository(),
+		NotificationLog:   NewMemoryNotificationLogRepository(),
+		IndicatorSnapshot: NewMemoryIndicatorSnapshotRepository(),
+
+		BackfillCheckpoint: NewMemoryBackfillCheckpointRepository(),
+		KlineQuarantine:    NewMemoryKlineQuarantineRepository(),
+	}
+}
+
+var (
+	_ AnalysisStore          = (*AnalysisRepository)(nil)
+	_ PriceHistoryStore      = (*PriceHistoryRepository)(nil)
+	_ SymbolStore            = (*SymbolRepository)(nil)
+	_ AutoVolumeStore        = (*AutoVolumeRecordRepository)(nil)
+	_ NotificationLogStore   = (*NotificationLogRepository)(nil)
+	_ IndicatorSnapshotStore = (*IndicatorSnapshotRepository)(nil)
+
+	_ BackfillCheckpointStore = (*BackfillCheckpointRepository)(nil)
+	_ KlineQuarantineStore    = (*KlineQuarantineRepository)(nil)
+)
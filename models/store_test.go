@@ -0,0 +1,307 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// forEachStore chạy cùng một test trên repository GORM (SQLite :memory:) và repository trong bộ nhớ
+// để đảm bảo hai bản cài đặt có cùng hành vi
+func forEachStore(t *testing.T, test func(t *testing.T, repos Repositories)) {
+	t.Run("gorm", func(t *testing.T) {
+		setupTestDB(t)
+		test(t, NewRepositories())
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+}
+
+func TestStorePriceHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.PriceHistory
+		if _, err := repo.GetLatestPrice("BTCUSDT", "1h"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLatestPrice on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if _, err := repo.GetOldest("BTCUSDT", "1h"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetOldest on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+
+		start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		for _, i := range []int{2, 0, 3, 1} {
+			candle := testCandle("BTCUSDT", "1h", start.Add(time.Duration(i)*time.Hour), float64(100+i))
+			if err := repo.Create(&candle); err != nil {
+				t.Fatalf("Create %d: %v", i, err)
+			}
+		}
+		duplicate := testCandle("BTCUSDT", "1h", start, 999)
+		if err := repo.Create(&duplicate); err == nil {
+			t.Errorf("Create duplicate (symbol, interval, open_time): want unique key error")
+		}
+		other := testCandle("ETHUSDT", "4h", start, 50)
+		if err := repo.Create(&other); err != nil {
+			t.Fatalf("Create ETHUSDT: %v", err)
+		}
+
+		// Mới nhất trước, có limit
+		latest, err := repo.GetBySymbolAndInterval("BTCUSDT", "1h", 2)
+		if err != nil {
+			t.Fatalf("GetBySymbolAndInterval: %v", err)
+		}
+		if len(latest) != 2 || latest[0].Close != 103 || latest[1].Close != 102 {
+			t.Errorf("GetBySymbolAndInterval = %v, want closes 103, 102", closes(latest))
+		}
+		if oldest, err := repo.GetOldest("BTCUSDT", "1h"); err != nil || oldest.Close != 100 {
+			t.Errorf("GetOldest = %v, %v; want close 100", oldest, err)
+		}
+
+		// Tăng dần trong [from, to)
+		ranged, _ := repo.GetRange("BTCUSDT", "1h", start.Add(time.Hour), start.Add(3*time.Hour))
+		if got := closes(ranged); len(got) != 2 || got[0] != 101 || got[1] != 102 {
+			t.Errorf("GetRange = %v, want 101, 102", got)
+		}
+
+		series, _ := repo.ListSeries()
+		if len(series) != 2 || series[0] != (CandleSeries{"BTCUSDT", "1h"}) || series[1] != (CandleSeries{"ETHUSDT", "4h"}) {
+			t.Errorf("ListSeries = %v", series)
+		}
+
+		deleted, err := repo.DeleteRange("BTCUSDT", "1h", start.Add(time.Hour), start.Add(3*time.Hour))
+		if err != nil || deleted != 2 {
+			t.Errorf("DeleteRange = %d, %v; want 2", deleted, err)
+		}
+		if count, _ := repo.GetCount("BTCUSDT", "1h"); count != 2 {
+			t.Errorf("GetCount after DeleteRange = %d, want 2", count)
+		}
+	})
+}
+
+func closes(histories []PriceHistory) []float64 {
+	var result []float64
+	for _, history := range histories {
+		result = append(result, history.Close)
+	}
+	return result
+}
+
+func TestStoreAnalysis(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.Analysis
+		if _, err := repo.GetLatestAnalysis("BTCUSDT", "1h"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLatestAnalysis on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		for i, offset := range []time.Duration{-3 * time.Hour, -time.Hour, -2 * time.Hour} {
+			record := AnalysisRecord{Symbol: "BTCUSDT", Interval: "1h", ClosePrice: float64(i), CreatedAt: now.Add(offset)}
+			if err := repo.Create(&record); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		records, _ := repo.GetBySymbolAndInterval("BTCUSDT", "1h", 10)
+		if len(records) != 3 || records[0].ClosePrice != 1 || records[1].ClosePrice != 2 || records[2].ClosePrice != 0 {
+			t.Errorf("GetBySymbolAndInterval not newest first: %+v", records)
+		}
+		if latest, err := repo.GetLatestAnalysis("BTCUSDT", "1h"); err != nil || latest.ClosePrice != 1 {
+			t.Errorf("GetLatestAnalysis = %+v, %v; want close 1", latest, err)
+		}
+
+		deleted, err := repo.DeleteOlderThan(now.Add(-90 * time.Minute))
+		if err != nil || deleted != 2 {
+			t.Errorf("DeleteOlderThan = %d, %v; want 2", deleted, err)
+		}
+	})
+}
+
+func TestStoreSymbol(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.Symbol
+		if _, err := repo.GetBySymbol("BTCUSDT"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetBySymbol on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if _, err := repo.GetSymbolByBaseAsset("BTC"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetSymbolByBaseAsset on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+
+		events, err := repo.SyncSymbols([]Symbol{
+			{Symbol: "BTCUSDT", Status: SymbolStatusTrading, BaseAsset: "BTC", QuoteAsset: "USDT"},
+			{Symbol: "ETHUSDT", Status: SymbolStatusTrading, BaseAsset: "ETH", QuoteAsset: "USDT"},
+			{Symbol: "ETHBTC", Status: SymbolStatusTrading, BaseAsset: "ETH", QuoteAsset: "BTC"},
+		}, []string{"USDT", "BTC"})
+		if err != nil || len(events) != 3 {
+			t.Fatalf("SyncSymbols = %v, %v; want 3 added events", events, err)
+		}
+		duplicate := Symbol{Symbol: "BTCUSDT", Status: SymbolStatusTrading, BaseAsset: "BTC", QuoteAsset: "USDT"}
+		if err := repo.Create(&duplicate); err == nil {
+			t.Errorf("Create duplicate symbol: want unique key error")
+		}
+
+		// ETHUSDT biến mất khỏi danh sách mới thì bị đánh dấu REMOVED, ETHBTC thuộc quote asset
+		// đã tắt nên không bị so sánh
+		events, err = repo.SyncSymbols([]Symbol{{Symbol: "BTCUSDT", Status: SymbolStatusTrading, BaseAsset: "BTC", QuoteAsset: "USDT"}}, []string{"USDT"})
+		if err != nil || len(events) != 1 || events[0].Symbol != "ETHUSDT" || events[0].Event != SymbolEventRemoved {
+			t.Fatalf("SyncSymbols removal = %+v, %v", events, err)
+		}
+		symbols, _ := repo.GetAllSymbols([]string{"USDT"})
+		if len(symbols) != 1 || symbols[0] != "BTCUSDT" {
+			t.Errorf("GetAllSymbols(USDT) = %v, want [BTCUSDT]", symbols)
+		}
+		if ethBTC, err := repo.GetBySymbol("ETHBTC"); err != nil || ethBTC.Status != SymbolStatusTrading {
+			t.Errorf("ETHBTC after its quote asset was disabled = %+v, %v; want unchanged", ethBTC, err)
+		}
+		if trading, _ := repo.GetTradingSymbols(nil); len(trading) != 2 {
+			t.Errorf("GetTradingSymbols(nil) = %d symbols, want 2", len(trading))
+		}
+		history, _ := repo.GetSymbolHistory("ETHUSDT", 10)
+		if len(history) != 2 || history[0].Event != SymbolEventRemoved {
+			t.Errorf("GetSymbolHistory = %+v, want removal first", history)
+		}
+		if has, _ := repo.HasSymbols("USDT"); !has {
+			t.Errorf("HasSymbols(USDT) = false, want true")
+		}
+		if has, _ := repo.HasSymbols("FDUSD"); has {
+			t.Errorf("HasSymbols(FDUSD) = true, want false")
+		}
+	})
+}
+
+func TestStoreAutoVolume(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.AutoVolume
+		start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		for _, i := range []int{1, 0, 2} {
+			record := AutoVolumeRecord{Symbol: "BTCUSDT", OpenTime: start.Add(time.Duration(i) * time.Hour), QuoteAssetVolume: float64(i)}
+			if err := repo.Create(&record); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		duplicate := AutoVolumeRecord{Symbol: "BTCUSDT", OpenTime: start}
+		if err := repo.Create(&duplicate); err == nil {
+			t.Errorf("Create duplicate (symbol, open_time): want unique key error")
+		}
+
+		records, _ := repo.GetLastNBySymbol("BTCUSDT", 2)
+		if len(records) != 2 || records[0].QuoteAssetVolume != 2 || records[1].QuoteAssetVolume != 1 {
+			t.Errorf("GetLastNBySymbol = %+v, want volumes 2, 1", records)
+		}
+
+		if err := repo.SaveClosedCandle(&AutoVolumeRecord{Symbol: "BTCUSDT", OpenTime: start.Add(3 * time.Hour), QuoteAssetVolume: 3}, 2); err != nil {
+			t.Fatalf("SaveClosedCandle: %v", err)
+		}
+		records, _ = repo.GetLastNBySymbol("BTCUSDT", 10)
+		if len(records) != 2 || records[0].QuoteAssetVolume != 3 || records[1].QuoteAssetVolume != 2 {
+			t.Errorf("after SaveClosedCandle keep 2 = %+v, want volumes 3, 2", records)
+		}
+
+		if err := repo.ReplaceAllForSymbol("BTCUSDT", []AutoVolumeRecord{{Symbol: "BTCUSDT", OpenTime: start, QuoteAssetVolume: 9}}); err != nil {
+			t.Fatalf("ReplaceAllForSymbol: %v", err)
+		}
+		records, _ = repo.GetLastNBySymbol("BTCUSDT", 10)
+		if len(records) != 1 || records[0].QuoteAssetVolume != 9 {
+			t.Errorf("after ReplaceAllForSymbol = %+v", records)
+		}
+
+		// Dữ liệu mới bị trùng thì dữ liệu cũ được giữ nguyên
+		duplicates := []AutoVolumeRecord{
+			{Symbol: "BTCUSDT", OpenTime: start.Add(time.Hour), QuoteAssetVolume: 1},
+			{Symbol: "BTCUSDT", OpenTime: start.Add(time.Hour), QuoteAssetVolume: 2},
+		}
+		if err := repo.ReplaceAllForSymbol("BTCUSDT", duplicates); err == nil {
+			t.Errorf("ReplaceAllForSymbol with duplicate candles: want error")
+		}
+		records, _ = repo.GetLastNBySymbol("BTCUSDT", 10)
+		if len(records) != 1 || records[0].QuoteAssetVolume != 9 {
+			t.Errorf("after failed ReplaceAllForSymbol = %+v, want the previous record kept", records)
+		}
+	})
+}
+
+func TestStoreNotificationLog(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.NotificationLog
+		now := time.Now()
+		for _, createdAt := range []time.Time{now, now.Add(-48 * time.Hour)} {
+			if err := repo.Create(&NotificationLog{Symbol: "BTCUSDT", CreatedAt: createdAt}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if count, _ := repo.CountBySymbolToday("BTCUSDT"); count != 1 {
+			t.Errorf("CountBySymbolToday = %d, want 1", count)
+		}
+		if deleted, _ := repo.DeleteOlderThan(now.Add(-24 * time.Hour)); deleted != 1 {
+			t.Errorf("DeleteOlderThan = %d, want 1", deleted)
+		}
+	})
+}
+
+func TestStoreIndicatorSnapshot(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.IndicatorSnapshot
+		if _, err := repo.Get("BTCUSDT", "1h"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Get on empty store: err = %v, want gorm.ErrRecordNotFound", err)
+		}
+		first := IndicatorSnapshot{Symbol: "BTCUSDT", Interval: "1h", OpenTime: time.Now().UTC(), State: "{}"}
+		if err := repo.Save(&first); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		second := IndicatorSnapshot{Symbol: "BTCUSDT", Interval: "1h", OpenTime: time.Now().UTC(), State: `{"step":1}`}
+		if err := repo.Save(&second); err != nil {
+			t.Fatalf("Save again: %v", err)
+		}
+		snapshot, err := repo.Get("BTCUSDT", "1h")
+		if err != nil || snapshot.State != `{"step":1}` || snapshot.ID != first.ID {
+			t.Errorf("Get = %+v, %v; want overwritten state with ID %d", snapshot, err, first.ID)
+		}
+	})
+}
+
+func TestStoreBackfillCheckpoint(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.BackfillCheckpoint
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(30 * 24 * time.Hour)
+		checkpoint, err := repo.GetOrCreate("BTCUSDT", "1h", from, to)
+		if err != nil {
+			t.Fatalf("GetOrCreate: %v", err)
+		}
+		if !checkpoint.Cursor.Equal(to) || checkpoint.Completed {
+			t.Errorf("new checkpoint = %+v, want cursor at end time", checkpoint)
+		}
+
+		checkpoint.Cursor = from.Add(24 * time.Hour)
+		checkpoint.Candles = 24
+		if err := repo.Save(checkpoint); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		again, err := repo.GetOrCreate("BTCUSDT", "1h", from, to)
+		if err != nil || again.ID != checkpoint.ID || again.Candles != 24 || !again.Cursor.Equal(from.Add(24*time.Hour)) {
+			t.Errorf("GetOrCreate after Save = %+v, %v", again, err)
+		}
+		other, _ := repo.GetOrCreate("BTCUSDT", "4h", from, to)
+		if other.ID == checkpoint.ID {
+			t.Errorf("different interval shares checkpoint ID %d", other.ID)
+		}
+	})
+}
+
+func TestStoreKlineQuarantine(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repos Repositories) {
+		repo := repos.KlineQuarantine
+		now := time.Now().UTC().Truncate(time.Second)
+		records := []QuarantinedKline{
+			{Source: "rest", Symbol: "BTCUSDT", Interval: "1h", OpenTime: 1, Reason: "a", RawPayload: "[]", CreatedAt: now.Add(-time.Minute)},
+			{Source: "stream", Symbol: "BTCUSDT", Interval: "1h", OpenTime: 2, Reason: "b", RawPayload: "{}", CreatedAt: now},
+		}
+		if err := repo.CreateBatch(records); err != nil {
+			t.Fatalf("CreateBatch: %v", err)
+		}
+		recent, _ := repo.GetRecent(1)
+		if len(recent) != 1 || recent[0].OpenTime != 2 {
+			t.Errorf("GetRecent = %+v, want newest record", recent)
+		}
+	})
+}
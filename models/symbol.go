@@ -8,12 +8,57 @@ import (
 
 // Symbol represents a trading pair from Binance
 type Symbol struct {
-	ID        uint   `gorm:"primaryKey"`
-	Symbol    string `gorm:"unique;not null"`
-	Status    string `gorm:"not null"`
-	BaseAsset string `gorm:"not null"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         uint   `gorm:"primaryKey"`
+	Symbol     string `gorm:"unique;not null"`
+	Status     string `gorm:"not null"`
+	BaseAsset  string `gorm:"not null"`
+	QuoteAsset string `gorm:"not null;default:'USDT';index"`
+	// Bộ lọc giao dịch của sàn, lưu dạng chuỗi thập phân để giữ nguyên độ chính xác
+	TickSize    string `gorm:"not null;default:''"`
+	StepSize    string `gorm:"not null;default:''"`
+	MinQty      string `gorm:"not null;default:''"`
+	MinNotional string `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// DisplayName trả về tên dạng BASE/QUOTE, riêng cặp USDT chỉ hiển thị BASE
+func (s Symbol) DisplayName() string {
+	if s.BaseAsset == "" || s.QuoteAsset == "" {
+		return s.Symbol
+	}
+	if s.QuoteAsset == "USDT" {
+		return s.BaseAsset
+	}
+	return s.BaseAsset + "/" + s.QuoteAsset
+}
+
+// SymbolStatusTrading là trạng thái symbol đang giao dịch trên Binance
+const SymbolStatusTrading = "TRADING"
+
+// SymbolStatusRemoved là trạng thái gán cho symbol không còn xuất hiện trong exchangeInfo
+const SymbolStatusRemoved = "REMOVED"
+
+// Các loại sự kiện trong lịch sử symbol
+const (
+	SymbolEventAdded         = "added"
+	SymbolEventStatusChanged = "status_changed"
+	SymbolEventRemoved       = "removed"
+)
+
+// SymbolHistory records listing, delisting and status changes of a symbol
+type SymbolHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	Symbol    string    `gorm:"not null;index"`
+	Event     string    `gorm:"not null"`
+	OldStatus string    `gorm:"not null;default:''"`
+	NewStatus string    `gorm:"not null;default:''"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName định nghĩa tên bảng cho SymbolHistory
+func (SymbolHistory) TableName() string {
+	return "symbol_histories"
 }
 
 // DataUpdate tracks when data was last updated
@@ -32,10 +77,46 @@ type BinanceExchangeInfo struct {
 
 // BinanceSymbol represents a symbol from Binance API
 type BinanceSymbol struct {
-	Symbol     string `json:"symbol"`
-	Status     string `json:"status"`
-	BaseAsset  string `json:"baseAsset"`
-	QuoteAsset string `json:"quoteAsset"`
+	Symbol     string          `json:"symbol"`
+	Status     string          `json:"status"`
+	BaseAsset  string          `json:"baseAsset"`
+	QuoteAsset string          `json:"quoteAsset"`
+	Filters    []BinanceFilter `json:"filters"`
+}
+
+// BinanceFilter represents one entry of the filters array in exchangeInfo
+type BinanceFilter struct {
+	FilterType  string `json:"filterType"`
+	MinPrice    string `json:"minPrice,omitempty"`
+	MaxPrice    string `json:"maxPrice,omitempty"`
+	TickSize    string `json:"tickSize,omitempty"`
+	MinQty      string `json:"minQty,omitempty"`
+	MaxQty      string `json:"maxQty,omitempty"`
+	StepSize    string `json:"stepSize,omitempty"`
+	MinNotional string `json:"minNotional,omitempty"`
+	MaxNotional string `json:"maxNotional,omitempty"`
+}
+
+// ToSymbol chuyển symbol từ exchangeInfo sang model, lấy tick size, lot size và min notional từ filters
+func (b BinanceSymbol) ToSymbol() Symbol {
+	symbol := Symbol{
+		Symbol:     b.Symbol,
+		Status:     b.Status,
+		BaseAsset:  b.BaseAsset,
+		QuoteAsset: b.QuoteAsset,
+	}
+	for _, filter := range b.Filters {
+		switch filter.FilterType {
+		case "PRICE_FILTER":
+			symbol.TickSize = filter.TickSize
+		case "LOT_SIZE":
+			symbol.StepSize = filter.StepSize
+			symbol.MinQty = filter.MinQty
+		case "NOTIONAL", "MIN_NOTIONAL":
+			symbol.MinNotional = filter.MinNotional
+		}
+	}
+	return symbol
 }
 
 // BeforeCreate will set timestamps
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math"
+
+	"chatbtc/models"
+)
+
+// ADXSeries tính ADX, +DI và −DI theo Wilder cho từng nến. TR, +DM, −DM được làm mượt bằng
+// S = S - S/period + giá trị mới (giá trị đầu là tổng period nến đầu), ADX đầu tiên là trung bình
+// period giá trị DX đầu, sau đó adx = (adx*(period-1) + DX) / period.
+// DI có từ nến thứ period, ADX từ nến thứ 2*period-1 (tính từ 0), phần tử chưa đủ dữ liệu là NaN.
+func (s *TechnicalAnalysisService) ADXSeries(highs, lows, closes []float64, period int) (adx, plusDI, minusDI []float64) {
+	adx, plusDI, minusDI = nanSeries(len(closes)), nanSeries(len(closes)), nanSeries(len(closes))
+	if period <= 0 || len(closes) < period+1 {
+		return adx, plusDI, minusDI
+	}
+
+	var smoothedTR, smoothedPlusDM, smoothedMinusDM, adxValue float64
+	for i := 1; i < len(closes); i++ {
+		upMove, downMove := highs[i]-highs[i-1], lows[i-1]-lows[i]
+		var plusDM, minusDM float64
+		if upMove > downMove && upMove > 0 {
+			plusDM = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM = downMove
+		}
+		trueRange := math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+
+		if i <= period {
+			smoothedTR += trueRange
+			smoothedPlusDM += plusDM
+			smoothedMinusDM += minusDM
+			if i < period {
+				continue
+			}
+		} else {
+			smoothedTR = smoothedTR - smoothedTR/float64(period) + trueRange
+			smoothedPlusDM = smoothedPlusDM - smoothedPlusDM/float64(period) + plusDM
+			smoothedMinusDM = smoothedMinusDM - smoothedMinusDM/float64(period) + minusDM
+		}
+
+		if smoothedTR > 0 {
+			plusDI[i] = 100 * smoothedPlusDM / smoothedTR
+			minusDI[i] = 100 * smoothedMinusDM / smoothedTR
+		} else {
+			plusDI[i], minusDI[i] = 0, 0
+		}
+		var dx float64
+		if sum := plusDI[i] + minusDI[i]; sum > 0 {
+			dx = 100 * math.Abs(plusDI[i]-minusDI[i]) / sum
+		}
+
+		// DX đầu tiên ở nến period, ADX đầu tiên là trung bình DX của nến period..2*period-1
+		switch {
+		case i < 2*period-1:
+			adxValue += dx / float64(period)
+		case i == 2*period-1:
+			adxValue += dx / float64(period)
+			adx[i] = adxValue
+		default:
+			adxValue = (adxValue*float64(period-1) + dx) / float64(period)
+			adx[i] = adxValue
+		}
+	}
+	return adx, plusDI, minusDI
+}
+
+// trendStrength xếp loại độ mạnh xu hướng theo ADX: dưới ADX_WEAK là yếu, từ ADX_STRONG trở lên là mạnh
+func trendStrength(adx float64) string {
+	switch {
+	case adx >= models.ADX_STRONG:
+		return "strong"
+	case adx >= models.ADX_WEAK:
+		return "moderate"
+	default:
+		return "weak"
+	}
+}
+
+// analyzeTrendStrength tính ADX, +DI, −DI của nến cuối, ok = false nếu chưa đủ 2*ADX_PERIOD nến
+func (s *TechnicalAnalysisService) analyzeTrendStrength(klines []models.KlineData) (adx, plusDI, minusDI float64, ok bool) {
+	highs, lows, closes := klineSeries(klines)
+	adxSeries, plusSeries, minusSeries := s.ADXSeries(highs, lows, closes, models.ADX_PERIOD)
+	if len(adxSeries) == 0 || math.IsNaN(adxSeries[len(adxSeries)-1]) {
+		return 0, 0, 0, false
+	}
+	last := len(adxSeries) - 1
+	return adxSeries[last], plusSeries[last], minusSeries[last], true
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestADXSeries(t *testing.T) {
+	// 14 nến đầu trong bảng tính mẫu của Wilder, giá trị mong đợi tính tay theo công thức Wilder với chu kỳ 4
+	highs := []float64{30.20, 30.28, 30.45, 29.35, 29.35, 29.29, 28.83, 28.73, 28.67, 28.85, 28.64, 27.68, 27.21, 26.87}
+	lows := []float64{29.41, 29.32, 29.96, 28.74, 28.56, 28.41, 28.08, 27.43, 27.66, 27.83, 27.40, 27.09, 26.18, 26.13}
+	closes := []float64{29.87, 30.24, 30.10, 28.90, 28.92, 28.48, 28.56, 27.56, 28.47, 28.28, 27.49, 27.23, 26.35, 26.33}
+	nan := math.NaN()
+	want := []struct{ plusDI, minusDI, adx float64 }{
+		{nan, nan, nan},
+		{nan, nan, nan},
+		{nan, nan, nan},
+		{nan, nan, nan},
+		{4.722222, 41.388889, nan},
+		{3.561453, 35.405028, nan},
+		{2.783843, 37.281659, nan},
+		{1.850210, 41.547082, 84.703800},
+		{1.338973, 30.067095, 86.396139},
+		{5.463619, 22.462515, 80.014835},
+		{3.875011, 26.014135, 78.528822},
+		{3.271538, 30.145495, 79.001600},
+		{2.388787, 45.396459, 81.751697},
+		{1.905615, 37.580925, 83.900780},
+	}
+
+	adx, plusDI, minusDI := NewTechnicalAnalysisService().ADXSeries(highs, lows, closes, 4)
+	check := func(name string, i int, got, want float64) {
+		if math.IsNaN(want) {
+			if !math.IsNaN(got) {
+				t.Errorf("%s[%d] = %v, want NaN", name, i, got)
+			}
+			return
+		}
+		if math.Abs(got-want) > 1e-5 {
+			t.Errorf("%s[%d] = %.6f, want %.6f", name, i, got, want)
+		}
+	}
+	for i, w := range want {
+		check("+DI", i, plusDI[i], w.plusDI)
+		check("-DI", i, minusDI[i], w.minusDI)
+		check("ADX", i, adx[i], w.adx)
+	}
+}
+
+func TestADXSeriesNotEnoughData(t *testing.T) {
+	adx, plusDI, minusDI := NewTechnicalAnalysisService().ADXSeries([]float64{2, 3}, []float64{1, 2}, []float64{1.5, 2.5}, 4)
+	for i := range adx {
+		if !math.IsNaN(adx[i]) || !math.IsNaN(plusDI[i]) || !math.IsNaN(minusDI[i]) {
+			t.Errorf("index %d = %v/%v/%v, want NaN", i, adx[i], plusDI[i], minusDI[i])
+		}
+	}
+}
@@ -8,40 +8,52 @@ import (
 
 // AnalysisService xử lý các thao tác liên quan đến phân tích
 type AnalysisService struct {
-	analysisRepo *models.AnalysisRepository
-	priceRepo    *models.PriceHistoryRepository
+	analysisRepo models.AnalysisStore
+	priceRepo    models.PriceHistoryStore
 }
 
-// NewAnalysisService tạo instance mới
-func NewAnalysisService() *AnalysisService {
+// NewAnalysisService tạo instance mới với các repository được truyền vào
+func NewAnalysisService(repos models.Repositories) *AnalysisService {
 	return &AnalysisService{
-		analysisRepo: models.NewAnalysisRepository(),
-		priceRepo:    models.NewPriceHistoryRepository(),
+		analysisRepo: repos.Analysis,
+		priceRepo:    repos.PriceHistory,
 	}
 }
 
-// SaveAnalysis lưu kết quả phân tích vào database
-func (s *AnalysisService) SaveAnalysis(symbol, interval string, closePrice, volume, rsi, ema9, ema21, ema50, macd, macdSignal, volumeSMA float64, trend, power, signal, recommendation, volumeSignal string) error {
+// SaveAnalysis lưu kết quả phân tích (data) của nến có giá đóng cửa closePrice và volume vào database
+func (s *AnalysisService) SaveAnalysis(closePrice, volume float64, data *models.AnalysisData) error {
 	loc := time.FixedZone("UTC+7", 7*60*60)
+	symbol, interval := data.Symbol, data.Interval
 	record := &models.AnalysisRecord{
 		Symbol:         symbol,
 		Interval:       interval,
 		ClosePrice:     closePrice,
 		Volume:         volume,
-		RSI:            rsi,
-		EMA9:           ema9,
-		EMA21:          ema21,
-		EMA50:          ema50,
-		MACD:           macd,
-		MACDSignal:     macdSignal,
-		VolumeSMA:      volumeSMA,
-		Trend:          trend,
-		Power:          power,
-		Signal:         signal,
-		Recommendation: recommendation,
-		VolumeSignal:   volumeSignal,
+		RSI:            data.RSI,
+		EMA9:           data.EMA9,
+		EMA21:          data.EMA21,
+		EMA50:          data.EMA50,
+		MACD:           data.MACD,
+		MACDSignal:     data.MACDSignal,
+		VolumeSMA:      data.VolumeSMA,
+		Trend:          data.Trend,
+		Power:          data.Power,
+		Signal:         data.Signal,
+		Recommendation: data.Recommendation,
+		VolumeSignal:   data.VolumeSignal,
 		CreatedAt:      time.Now().In(loc),
 		UpdatedAt:      time.Now().In(loc),
+
+		BBUpper:       data.VolatilityAnalysis.BBUpper,
+		BBMiddle:      data.VolatilityAnalysis.BBMiddle,
+		BBLower:       data.VolatilityAnalysis.BBLower,
+		BBPercentB:    data.VolatilityAnalysis.PercentB,
+		BBBandwidth:   data.VolatilityAnalysis.Bandwidth,
+		ATR:           data.VolatilityAnalysis.ATR,
+		KeltnerUpper:  data.VolatilityAnalysis.KeltnerUpper,
+		KeltnerMiddle: data.VolatilityAnalysis.KeltnerMiddle,
+		KeltnerLower:  data.VolatilityAnalysis.KeltnerLower,
+		Volatility:    data.VolatilityAnalysis.State,
 	}
 
 	err := s.analysisRepo.Create(record)
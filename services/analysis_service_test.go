@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+
+	"chatbtc/models"
+)
+
+func TestAnalysisServiceSaveAndHistory(t *testing.T) {
+	service := NewAnalysisService(models.NewMemoryRepositories())
+	for i, closePrice := range []float64{100, 101, 102} {
+		data := &models.AnalysisData{Symbol: "BTCUSDT", Interval: "1h", Recommendation: "BUY"}
+		if err := service.SaveAnalysis(closePrice, float64(10*(i+1)), data); err != nil {
+			t.Fatalf("SaveAnalysis: %v", err)
+		}
+	}
+	data := &models.AnalysisData{Symbol: "BTCUSDT", Interval: "4h"}
+	if err := service.SaveAnalysis(200, 1, data); err != nil {
+		t.Fatalf("SaveAnalysis: %v", err)
+	}
+
+	history, err := service.GetAnalysisHistory("BTCUSDT", "1h", 2)
+	if err != nil {
+		t.Fatalf("GetAnalysisHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(history))
+	}
+	if history[0].ClosePrice != 102 || history[1].ClosePrice != 101 {
+		t.Errorf("history close prices = %v, %v; want newest first 102, 101", history[0].ClosePrice, history[1].ClosePrice)
+	}
+}
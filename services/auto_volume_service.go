@@ -1,13 +1,11 @@
 package services
 
 import (
+	"chatbtc/config"
 	"chatbtc/models"
 	"chatbtc/utils"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -15,205 +13,458 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+const (
+	volumeInterval         = "1h" // Khung nến dùng cho volume screener
+	volumeRecordsPerSymbol = 22   // Số nến đã đóng lưu cho mỗi symbol
+	alertOscillatorCandles = 100  // Số nến đã đóng dùng tính Stochastic/StochRSI khi lọc cảnh báo
+)
+
 type AutoVolumeService struct {
-	volumeRepo          *models.AutoVolumeRecordRepository
-	symbolRepo          *models.SymbolRepository
-	notificationLogRepo *models.NotificationLogRepository
+	volumeRepo          models.AutoVolumeStore
+	priceRepo           models.PriceHistoryStore
+	symbolRepo          models.SymbolStore
+	notificationLogRepo models.NotificationLogStore
 	telegramBotService  *TelegramBotService
+	exchange            ExchangeClient
+	quoteConverter      *QuoteConverter
+	candles             *CandleService
+	engine              *IndicatorEngine
+	workers             int
+	// analysisInterval là khung nến dùng khi phân tích volume, khác 1h thì dựng lại từ kho nến
+	analysisInterval string
+	// patternTransform là kiểu nến dùng khi nhận diện mô hình (nến thường hoặc Heikin-Ashi)
+	patternTransform CandleTransform
+	// alertConditions là các điều kiện Stochastic/StochRSI, cảnh báo chỉ gửi khi khớp ít nhất một điều kiện
+	alertConditions []string
 }
 
-// Truyền TelegramBotService vào khi khởi tạo
-func NewAutoVolumeService(telegramBotService *TelegramBotService) *AutoVolumeService {
+// Truyền TelegramBotService, ExchangeClient và các repository vào khi khởi tạo
+func NewAutoVolumeService(telegramBotService *TelegramBotService, exchange ExchangeClient, repos models.Repositories, cache *CandleCache) *AutoVolumeService {
+	analysisInterval := config.AppConfig.VolumeInterval
+	if err := ValidateInterval(analysisInterval); err != nil {
+		log.Printf("⚠️ VOLUME_INTERVAL không hợp lệ (%v), dùng %s", err, volumeInterval)
+		analysisInterval = volumeInterval
+	}
+	// Renko không giữ số nến cố định nên mô hình nến chỉ hỗ trợ Heikin-Ashi
+	patternTransform, err := ParseCandleTransform(config.AppConfig.VolumePatternCandles)
+	if err != nil || (patternTransform.Kind != "" && patternTransform.Kind != "ha") {
+		log.Printf("⚠️ VOLUME_PATTERN_CANDLES không hợp lệ (%q), dùng nến thường", config.AppConfig.VolumePatternCandles)
+		patternTransform = CandleTransform{}
+	}
+	alertConditions, err := ParseOscillatorConditions(config.AppConfig.VolumeAlertConditions)
+	if err != nil {
+		log.Printf("⚠️ VOLUME_ALERT_CONDITIONS không hợp lệ (%v), không lọc cảnh báo theo Stochastic", err)
+	}
 	return &AutoVolumeService{
-		volumeRepo:          models.NewAutoVolumeRecordRepository(),
-		symbolRepo:          models.NewSymbolRepository(),
-		notificationLogRepo: models.NewNotificationLogRepository(),
+		volumeRepo:          repos.AutoVolume,
+		priceRepo:           repos.PriceHistory,
+		symbolRepo:          repos.Symbol,
+		notificationLogRepo: repos.NotificationLog,
 		telegramBotService:  telegramBotService,
+		exchange:            exchange,
+		quoteConverter:      NewQuoteConverter(exchange),
+		candles:             NewCandleService(exchange, repos.PriceHistory, cache),
+		workers:             config.AppConfig.VolumeWorkers,
+		analysisInterval:    analysisInterval,
+		patternTransform:    patternTransform,
+		alertConditions:     alertConditions,
 	}
 }
 
-func (s *AutoVolumeService) FetchAndSaveAllSymbolsVolume() error {
-	symbols, err := s.symbolRepo.GetAllSymbols()
+// SetIndicatorEngine đưa các nến đã đóng nhận được (REST, WebSocket) vào engine chỉ báo
+func (s *AutoVolumeService) SetIndicatorEngine(engine *IndicatorEngine) {
+	s.engine = engine
+	s.candles.SetIndicatorEngine(engine)
+}
+
+func (s *AutoVolumeService) FetchAndSaveAllSymbolsVolume() (RunSummary, error) {
+	symbols, err := s.symbolRepo.GetAllSymbols(config.AppConfig.QuoteAssets)
 	if err != nil {
-		return err
+		return RunSummary{}, err
 	}
-	for _, symbol := range symbols {
-		// Lấy dữ liệu kline
-		url := fmt.Sprintf("https://api.binance.com/api/v3/klines?symbol=%s&interval=1h&limit=23", symbol)
-		resp, err := http.Get(url)
-		if err != nil {
-			fmt.Printf("Lỗi lấy dữ liệu %s: %v\n", symbol, err)
-			continue
-		}
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
+	summary := s.FetchAndSaveSymbolsVolume(symbols)
+	if reporter, ok := s.exchange.(RateLimitReporter); ok {
+		log.Printf("Rate limit Binance sau khi lấy volume: %s", reporter.RateLimitBudget())
+	}
+	return summary, nil
+}
 
-		var klines [][]interface{}
-		if err := json.Unmarshal(body, &klines); err != nil || len(klines) == 0 {
-			fmt.Printf("Lỗi parse dữ liệu %s: %v\n", symbol, err)
-			continue
-		}
-		// Loại bỏ cây nến cuối cùng (chưa đóng) nếu có nhiều hơn 1 nến
-		if len(klines) > 1 {
-			klines = klines[:len(klines)-1]
-		}
-		// Lấy 22 nến đã đóng gần nhất
-		recentKlines := klines
-		if len(klines) > 22 {
-			recentKlines = klines[len(klines)-22:]
-		}
+// FetchAndSaveSymbolsVolume lấy 22 nến đã đóng gần nhất qua REST cho danh sách symbol
+// bằng worker pool giới hạn số goroutine
+func (s *AutoVolumeService) FetchAndSaveSymbolsVolume(symbols []string) RunSummary {
+	return runSymbolPool("Lấy volume", symbols, s.workers, s.fetchAndSaveSymbolVolume)
+}
 
-		loc := time.FixedZone("UTC+7", 7*60*60)
-
-		// Tạo slice để lưu tất cả records cho symbol này
-		var records []models.AutoVolumeRecord
-
-		for _, k := range recentKlines {
-			openTime := k[0].(float64)
-			quoteAssetVolumeStr := k[7].(string)
-			quoteAssetVolume, _ := strconv.ParseFloat(quoteAssetVolumeStr, 64)
-			openPriceStr := k[1].(string)
-			openPrice, _ := strconv.ParseFloat(openPriceStr, 64)
-			closePriceStr := k[4].(string)
-			closePrice, _ := strconv.ParseFloat(closePriceStr, 64)
-			highPriceStr := k[2].(string)
-			highPrice, _ := strconv.ParseFloat(highPriceStr, 64)
-			lowPriceStr := k[3].(string)
-			lowPrice, _ := strconv.ParseFloat(lowPriceStr, 64)
-
-			record := models.AutoVolumeRecord{
-				Symbol:           symbol,
-				OpenTime:         openTime,
-				QuoteAssetVolume: quoteAssetVolume,
-				OpenPrice:        openPrice,
-				ClosePrice:       closePrice,
-				HighPrice:        highPrice,
-				LowPrice:         lowPrice,
-				CreatedAt:        time.Now().In(loc),
-				UpdatedAt:        time.Now().In(loc),
-			}
-			records = append(records, record)
-		}
+// fetchAndSaveSymbolVolume lấy và lưu nến cho một symbol
+func (s *AutoVolumeService) fetchAndSaveSymbolVolume(symbol string) error {
+	// Lấy dữ liệu kline
+	klines, err := s.exchange.GetKlines(symbol, volumeInterval, volumeRecordsPerSymbol+1)
+	if err != nil {
+		return fmt.Errorf("lỗi lấy dữ liệu: %v", err)
+	}
+	if len(klines) == 0 {
+		return skipSymbol("không có dữ liệu kline")
+	}
+	s.candles.CacheCandles(symbol, volumeInterval, klines)
+	// Loại bỏ cây nến cuối cùng (chưa đóng) nếu có nhiều hơn 1 nến
+	if len(klines) > 1 {
+		klines = klines[:len(klines)-1]
+	}
+	// Lấy 22 nến đã đóng gần nhất
+	recentKlines := klines
+	if len(klines) > volumeRecordsPerSymbol {
+		recentKlines = klines[len(klines)-volumeRecordsPerSymbol:]
+	}
 
-		// Thay thế tất cả dữ liệu cũ bằng dữ liệu mới
-		if err := s.volumeRepo.ReplaceAllForSymbol(symbol, records); err != nil {
-			fmt.Printf("Lỗi lưu DB %s: %v\n", symbol, err)
-		} else {
-			fmt.Printf("Đã cập nhật %d records volume cho %s\n", len(records), symbol)
+	// Tạo slice để lưu tất cả records cho symbol này
+	var records []models.AutoVolumeRecord
+	for _, k := range recentKlines {
+		record, err := newAutoVolumeRecord(symbol, k)
+		if err != nil {
+			return err
 		}
-		time.Sleep(100 * time.Millisecond)
+		records = append(records, record)
+	}
+
+	// Thay thế tất cả dữ liệu cũ bằng dữ liệu mới
+	if err := s.volumeRepo.ReplaceAllForSymbol(symbol, records); err != nil {
+		return fmt.Errorf("lỗi lưu DB: %v", err)
+	}
+	// Lưu thêm vào kho nến để /analyze dùng lại
+	if err := storeClosedKlines(s.priceRepo, symbol, volumeInterval, recentKlines); err != nil {
+		log.Printf("⚠️ Lỗi lưu kho nến %s: %v", symbol, err)
+	}
+	if err := s.engine.Update(symbol, volumeInterval, klines); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
 	return nil
 }
 
-func (s *AutoVolumeService) AnalyzeAndNotifyVolumes(channelID string) error {
-	// Lấy tất cả symbols thay vì tất cả records
-	symbols, err := s.symbolRepo.GetAllSymbols()
+// SaveClosedKline lưu một nến đã đóng (ví dụ nhận từ WebSocket) vào AutoVolumeRecord và kho nến
+func (s *AutoVolumeService) SaveClosedKline(symbol string, kline models.KlineData) error {
+	record, err := newAutoVolumeRecord(symbol, kline)
 	if err != nil {
 		return err
 	}
-	log.Println("Analyzing volumes for ", len(symbols), "symbols")
-	taService := NewTechnicalAnalysisService()
+	if err := s.volumeRepo.SaveClosedCandle(&record, volumeRecordsPerSymbol); err != nil {
+		return err
+	}
+	if err := storeClosedKlines(s.priceRepo, symbol, volumeInterval, []models.KlineData{kline}); err != nil {
+		return err
+	}
+	return s.engine.Update(symbol, volumeInterval, []models.KlineData{kline})
+}
 
-	// Map để theo dõi symbols đã xử lý để tránh trùng lặp
-	processedSymbols := make(map[string]bool)
+// newAutoVolumeRecord chuyển một nến sang AutoVolumeRecord, trả về lỗi nếu số liệu không parse được
+func newAutoVolumeRecord(symbol string, k models.KlineData) (models.AutoVolumeRecord, error) {
 	loc := time.FixedZone("UTC+7", 7*60*60)
-
-	for _, symbol := range symbols {
-		// Kiểm tra nếu symbol đã được xử lý
-		if processedSymbols[symbol] {
-			continue
-		}
-		records22, _ := s.volumeRepo.GetLastNBySymbol(symbol, 23)
-		// Kiểm tra nếu không có dữ liệu
-		if len(records22) == 0 {
-			continue
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"quote volume", k.QuoteAssetVolume}, {"open", k.Open}, {"close", k.Close}, {"high", k.High}, {"low", k.Low},
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field.value, 64)
+		if err != nil {
+			return models.AutoVolumeRecord{}, fmt.Errorf("nến %d có %s không hợp lệ: %q", k.OpenTime, field.name, field.value)
 		}
+		values[i] = value
+	}
 
-		var volumes []float64
-		for _, r := range records22 {
-			volumes = append(volumes, r.QuoteAssetVolume)
-		}
-		//chỉ tới cây nến 21
-		var totalCandlestickLength float64 = 0
-		var totalCandlestickBody float64 = 0
-		for _, r := range records22[1:] {
-			totalCandlestickLength += r.CandlestickLength()
-			totalCandlestickBody += r.CandlestickBody()
-		}
-		averageCandlestickBody := totalCandlestickBody / float64(len(records22)-1)
-
-		volumeAnalysis := taService.analyzeVolumeFromFloat64(volumes)
-		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			// Lấy bản ghi MỚI NHẤT (records22[0])
-			latestRecord := records22[0]
-			// lấy bản ghi cây nến thứ 21
-			record21 := records22[1]
-			// lấy bản ghi cây nến thứ 20
-			record20 := records22[2]
-
-			// Lấy time hiện tại
-			currentTime := time.Now().In(loc)
-			formattedTime := currentTime.Format("2006-01-02 15:04:05")
-
-			// Phân tích mô hình
-			breakoutResult := detectBreakout(records22, averageCandlestickBody)
-			confirmation3 := breakoutResult.Confirmation
-			pattern3 := breakoutResult.Pattern
-			engulfingResult := detectEngulfing(record20, record21)
-			confirmation1 := engulfingResult.Confirmation
-			pattern1 := engulfingResult.Pattern
-			piercingResult := detectPiercingPattern(record20, record21, averageCandlestickBody)
-			confirmation2 := piercingResult.Confirmation
-			pattern2 := piercingResult.Pattern
-			hammerResult := detectHammer(records22)
-			confirmation4 := hammerResult.Confirmation
-			pattern4 := hammerResult.Pattern
-
-			patternString := utils.FormatElements(pattern1, pattern2, pattern3, pattern4)
-			confirmationString := utils.FormatElements(confirmation1, confirmation2, confirmation3, confirmation4)
-			count, _ := s.notificationLogRepo.CountBySymbolToday(symbol)
-			message := fmt.Sprintf("💰*[ALERT]* Symbol: *%s*\n"+
-				"📅 Time: %s\n"+
-				"🚀 Volume: *%s* (SMA21: %s)\n"+
-				"💵 Price: *%s*\n"+
-				"🎯 Strength: *%s*\n"+
-				"🔥 Signal: *%s*\n"+
-				"🔖 Daily Occurrences: %d\n"+
-				"✨ Pattern: %s\n"+
-				"📊 Confirmation: %s",
-				strings.TrimSuffix(latestRecord.Symbol, "USDT"),
-				formattedTime,
-				utils.FormatVolume(decimal.NewFromFloat(latestRecord.QuoteAssetVolume)),
-				utils.FormatVolume(volumeAnalysis.VolumeSMA21),
-				utils.FormatPrice(decimal.NewFromFloat(latestRecord.ClosePrice)),
-				volumeAnalysis.VolumeStrength,
-				volumeAnalysis.VolumeSignal,
-				count+1,
-				patternString,
-				confirmationString,
-			)
-			s.telegramBotService.SendTelegramToChannel(channelID, message)
+	return models.AutoVolumeRecord{
+		Symbol:           symbol,
+		OpenTime:         time.UnixMilli(k.OpenTime).UTC(),
+		QuoteAssetVolume: values[0],
+		OpenPrice:        values[1],
+		ClosePrice:       values[2],
+		HighPrice:        values[3],
+		LowPrice:         values[4],
+		CreatedAt:        time.Now().In(loc),
+		UpdatedAt:        time.Now().In(loc),
+	}, nil
+}
+
+// volumeAlert là cảnh báo volume chờ gửi lên channel
+type volumeAlert struct {
+	symbol  string
+	message string
+}
+
+func (s *AutoVolumeService) AnalyzeAndNotifyVolumes(channelID string) (RunSummary, error) {
+	// Lấy tất cả symbols thay vì tất cả records
+	tradingSymbols, err := s.symbolRepo.GetTradingSymbols(config.AppConfig.QuoteAssets)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	symbols := make([]string, 0, len(tradingSymbols))
+	symbolInfo := make(map[string]models.Symbol, len(tradingSymbols))
+	for _, info := range tradingSymbols {
+		symbols = append(symbols, info.Symbol)
+		symbolInfo[info.Symbol] = info
+	}
+	log.Println("Analyzing volumes for ", len(symbols), "symbols")
+
+	// Gửi cảnh báo tuần tự, cách nhau 1 giây để không vượt giới hạn gửi tin của Telegram.
+	// Mỗi symbol có tối đa một cảnh báo nên buffer đủ cho tất cả, worker không phải chờ việc gửi.
+	alerts := make(chan volumeAlert, len(symbols))
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for alert := range alerts {
+			s.telegramBotService.SendTelegramToChannel(channelID, alert.message)
 
 			// Lưu log sau khi gửi
 			notificationLog := &models.NotificationLog{
-				Symbol:    symbol,
+				Symbol:    alert.symbol,
 				CreatedAt: time.Now(),
 			}
 			if err := s.notificationLogRepo.Create(notificationLog); err != nil {
-				log.Printf("Lỗi lưu log thông báo cho %s: %v", symbol, err)
+				log.Printf("Lỗi lưu log thông báo cho %s: %v", alert.symbol, err)
 			}
+			time.Sleep(1 * time.Second)
 		}
+	}()
+
+	taService := NewTechnicalAnalysisService()
+	summary := runSymbolPool("Phân tích volume", symbols, s.workers, func(symbol string) error {
+		return s.analyzeSymbolVolume(taService, symbolInfo[symbol], alerts)
+	})
+	close(alerts)
+	<-sent
 
-		// Đánh dấu symbol đã được xử lý
-		processedSymbols[symbol] = true
-		time.Sleep(1 * time.Second)
+	return summary, nil
+}
+
+// analyzeSymbolVolume phân tích volume của một symbol và đẩy tối đa một cảnh báo vào alerts nếu đủ mạnh
+func (s *AutoVolumeService) analyzeSymbolVolume(taService *TechnicalAnalysisService, info models.Symbol, alerts chan<- volumeAlert) error {
+	symbol := info.Symbol
+	loc := time.FixedZone("UTC+7", 7*60*60)
+	records22, err := s.loadVolumeWindow(symbol)
+	if err != nil {
+		return err
+	}
+
+	var volumes []float64
+	for _, r := range records22 {
+		volumes = append(volumes, r.QuoteAssetVolume)
+	}
+	volumeAnalysis := taService.analyzeVolumeFromFloat64(volumes)
+	if volumeAnalysis.VolumeStrength != "EXTREME" && volumeAnalysis.VolumeStrength != "STRONG" {
+		return nil
 	}
-	time.Sleep(1 * time.Second)
 
+	// Lấy bản ghi MỚI NHẤT (records22[0])
+	latestRecord := records22[0]
+
+	// Quy đổi volume sang USD để so sánh giữa các cặp khác quote asset
+	volumeUSD, err := s.quoteConverter.ToUSD(info.QuoteAsset, latestRecord.QuoteAssetVolume)
+	if err != nil {
+		return fmt.Errorf("lỗi quy đổi volume sang USD: %v", err)
+	}
+	if volumeUSD < config.AppConfig.VolumeMinUSD {
+		return nil
+	}
+	oscillatorMatches, err := s.matchAlertConditions(taService, symbol)
+	if err != nil {
+		return err
+	}
+	if len(s.alertConditions) > 0 && len(oscillatorMatches) == 0 {
+		return nil
+	}
+	// Mô hình nến chạy trên nến gốc hoặc nến Heikin-Ashi (VOLUME_PATTERN_CANDLES=ha), volume luôn tính trên nến gốc
+	patternRecords, err := s.patternWindow(records22)
+	if err != nil {
+		return err
+	}
+	//chỉ tới cây nến 21
+	var totalCandlestickLength float64 = 0
+	var totalCandlestickBody float64 = 0
+	for _, r := range patternRecords[1:] {
+		totalCandlestickLength += r.CandlestickLength()
+		totalCandlestickBody += r.CandlestickBody()
+	}
+	averageCandlestickBody := totalCandlestickBody / float64(len(patternRecords)-1)
+
+	// lấy bản ghi cây nến thứ 21
+	record21 := patternRecords[1]
+	// lấy bản ghi cây nến thứ 20
+	record20 := patternRecords[2]
+
+	// Lấy time hiện tại
+	currentTime := time.Now().In(loc)
+	formattedTime := currentTime.Format("2006-01-02 15:04:05")
+
+	// Phân tích mô hình
+	breakoutResult := detectBreakout(patternRecords, averageCandlestickBody)
+	confirmation3 := breakoutResult.Confirmation
+	pattern3 := breakoutResult.Pattern
+	engulfingResult := detectEngulfing(record20, record21)
+	confirmation1 := engulfingResult.Confirmation
+	pattern1 := engulfingResult.Pattern
+	piercingResult := detectPiercingPattern(record20, record21, averageCandlestickBody)
+	confirmation2 := piercingResult.Confirmation
+	pattern2 := piercingResult.Pattern
+	hammerResult := detectHammer(patternRecords)
+	confirmation4 := hammerResult.Confirmation
+	pattern4 := hammerResult.Pattern
+
+	patternString := utils.FormatElements(pattern1, pattern2, pattern3, pattern4)
+	confirmationString := utils.FormatElements(confirmation1, confirmation2, confirmation3, confirmation4)
+	count, _ := s.notificationLogRepo.CountBySymbolToday(symbol)
+	message := fmt.Sprintf("💰*[ALERT]* Symbol: *%s*\n"+
+		"📅 Time: %s\n"+
+		"🚀 Volume: *%s* (SMA21: %s)\n"+
+		"💵 Price: *%s*\n"+
+		"🎯 Strength: *%s*\n"+
+		"🔥 Signal: *%s*\n"+
+		"🔖 Daily Occurrences: %d\n"+
+		"✨ Pattern: %s\n"+
+		"📊 Confirmation: %s",
+		info.DisplayName(),
+		formattedTime,
+		formatQuoteVolume(decimal.NewFromFloat(latestRecord.QuoteAssetVolume), info.QuoteAsset, volumeUSD),
+		formatQuoteAmount(utils.FormatVolume(volumeAnalysis.VolumeSMA21), info.QuoteAsset),
+		formatQuoteAmount(utils.FormatPriceTick(decimal.NewFromFloat(latestRecord.ClosePrice), info.TickSize), info.QuoteAsset),
+		volumeAnalysis.VolumeStrength,
+		volumeAnalysis.VolumeSignal,
+		count+1,
+		patternString,
+		confirmationString,
+	)
+	if len(oscillatorMatches) > 0 {
+		descriptions := make([]string, len(oscillatorMatches))
+		for i, condition := range oscillatorMatches {
+			descriptions[i] = describeOscillatorCondition(condition)
+		}
+		message += fmt.Sprintf("\n🔁 Oscillator: %s", strings.Join(descriptions, ", "))
+	}
+	alerts <- volumeAlert{symbol: symbol, message: message}
 	return nil
 }
 
+// matchAlertConditions tính Stochastic/StochRSI trên các nến đã đóng của khung phân tích và trả về
+// các điều kiện VOLUME_ALERT_CONDITIONS đang khớp, nil nếu không cấu hình điều kiện
+func (s *AutoVolumeService) matchAlertConditions(taService *TechnicalAnalysisService, symbol string) ([]string, error) {
+	if len(s.alertConditions) == 0 {
+		return nil, nil
+	}
+	klines, err := s.candles.GetCandles(symbol, s.analysisInterval, alertOscillatorCandles+1)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi lấy nến tính Stochastic: %v", err)
+	}
+	// Bỏ nến chưa đóng, cảnh báo chỉ xét nến đã đóng như phần volume
+	if n := len(klines); n > 0 && klines[n-1].CloseTime >= time.Now().UnixMilli() {
+		klines = klines[:n-1]
+	}
+	return matchOscillatorConditions(taService.analyzeOscillators(klines), s.alertConditions), nil
+}
+
+// loadVolumeWindow lấy cửa sổ nến đã đóng (mới nhất trước) để phân tích volume.
+// Khung 1h đọc từ AutoVolumeRecord, khung khác dựng lại từ kho nến.
+func (s *AutoVolumeService) loadVolumeWindow(symbol string) ([]models.AutoVolumeRecord, error) {
+	if s.analysisInterval != volumeInterval {
+		return s.loadResampledVolumeWindow(symbol)
+	}
+
+	records, err := s.volumeRepo.GetLastNBySymbol(symbol, volumeRecordsPerSymbol+1)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi đọc DB: %v", err)
+	}
+	// Kiểm tra nếu không có dữ liệu
+	if len(records) == 0 {
+		return nil, skipSymbol("không có dữ liệu volume")
+	}
+	// Nến bị thiếu sẽ làm lệch cửa sổ SMA21: lấy lại từ sàn, nếu vẫn thiếu thì không cảnh báo
+	if gaps := volumeRecordGaps(records, time.Hour); len(gaps) > 0 {
+		log.Printf("Phát hiện thiếu nến %s: %v, đang lấy lại", symbol, gaps)
+		if err := s.fetchAndSaveSymbolVolume(symbol); err != nil {
+			return nil, fmt.Errorf("lỗi lấy lại nến bị thiếu: %v", err)
+		}
+		if records, err = s.volumeRepo.GetLastNBySymbol(symbol, volumeRecordsPerSymbol+1); err != nil {
+			return nil, fmt.Errorf("lỗi đọc DB: %v", err)
+		}
+		if gaps := volumeRecordGaps(records, time.Hour); len(gaps) > 0 {
+			return nil, skipSymbol("cửa sổ nến vẫn còn thiếu: %v", gaps)
+		}
+	}
+	return records, nil
+}
+
+// loadResampledVolumeWindow dựng 22 nến đã đóng theo analysisInterval từ kho nến
+func (s *AutoVolumeService) loadResampledVolumeWindow(symbol string) ([]models.AutoVolumeRecord, error) {
+	klines, err := s.candles.GetCandles(symbol, s.analysisInterval, volumeRecordsPerSymbol+1)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi dựng nến %s: %v", s.analysisInterval, err)
+	}
+	// Bỏ nến cuối nếu chưa đóng
+	if len(klines) > 0 && klines[len(klines)-1].CloseTime >= time.Now().UnixMilli() {
+		klines = klines[:len(klines)-1]
+	}
+	if len(klines) == 0 {
+		return nil, skipSymbol("không có dữ liệu volume")
+	}
+
+	step, _ := utils.IntervalDuration(s.analysisInterval)
+	if gaps := FindCandleGaps(klineOpenTimes(klines), step); len(gaps) > 0 {
+		return nil, skipSymbol("cửa sổ nến %s còn thiếu: %v", s.analysisInterval, gaps)
+	}
+
+	// AutoVolumeRecord sắp xếp mới nhất trước
+	records := make([]models.AutoVolumeRecord, 0, len(klines))
+	for i := len(klines) - 1; i >= 0; i-- {
+		record, err := newAutoVolumeRecord(symbol, klines[i])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// patternWindow trả về cửa sổ nến (mới nhất trước) dùng cho nhận diện mô hình nến
+func (s *AutoVolumeService) patternWindow(records []models.AutoVolumeRecord) ([]models.AutoVolumeRecord, error) {
+	if s.patternTransform.IsNone() {
+		return records, nil
+	}
+
+	klines := make([]models.KlineData, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		klines = append(klines, records[i].ToKline())
+	}
+	transformed, err := s.patternTransform.Apply(klines)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi dựng nến %s: %v", s.patternTransform.Label(), err)
+	}
+
+	result := make([]models.AutoVolumeRecord, 0, len(transformed))
+	for i := len(transformed) - 1; i >= 0; i-- {
+		record, err := newAutoVolumeRecord(records[0].Symbol, transformed[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// formatQuoteAmount thêm đơn vị quote asset cho cặp không phải USD
+func formatQuoteAmount(amount string, quoteAsset string) string {
+	if IsUSDQuote(quoteAsset) {
+		return amount
+	}
+	return amount + " " + quoteAsset
+}
+
+// formatQuoteVolume hiển thị volume theo quote asset, kèm giá trị quy đổi USD với cặp không phải USD
+func formatQuoteVolume(volume decimal.Decimal, quoteAsset string, volumeUSD float64) string {
+	if IsUSDQuote(quoteAsset) {
+		return utils.FormatVolume(volume)
+	}
+	return fmt.Sprintf("%s %s (~$%s)", utils.FormatVolume(volume), quoteAsset, utils.FormatVolume(decimal.NewFromFloat(volumeUSD)))
+}
+
 // Hàm phân tích volume cho 1 giá trị float64 (tương thích với analyzeVolume)
 func (s *TechnicalAnalysisService) analyzeVolumeFromFloat64(volumes []float64) models.VolumeAnalysis {
 	// ĐẢO NGƯỢC SLICE Ở ĐÂY nếu cần
@@ -464,11 +715,12 @@ func (s *Scheduler2) Stop() {
 
 func (s *Scheduler2) Run() {
 	log.Println("Running update")
-	if err := s.autoVolumeService.FetchAndSaveAllSymbolsVolume(); err != nil {
+	summary, err := s.autoVolumeService.FetchAndSaveAllSymbolsVolume()
+	if err != nil {
 		log.Printf("Lỗi khi cập nhật dữ liệu: %v", err)
+		return
 	}
-	log.Println("Update completed")
-
+	log.Printf("Update completed - %s", summary)
 }
 
 type Scheduler3 struct {
@@ -509,10 +761,12 @@ func (s *Scheduler3) Start() {
 }
 
 func (s *Scheduler3) Run() {
-	if err := s.autoVolumeService.AnalyzeAndNotifyVolumes(s.channelID); err != nil {
+	summary, err := s.autoVolumeService.AnalyzeAndNotifyVolumes(s.channelID)
+	if err != nil {
 		log.Printf("Lỗi khi phân tích và gửi cảnh báo: %v", err)
+		return
 	}
-	log.Println("Analyze and notify completed")
+	log.Printf("Analyze and notify completed - %s", summary)
 }
 func (s *Scheduler3) Stop() {
 	s.stopChan <- true
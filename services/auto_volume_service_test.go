@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chatbtc/config"
+	"chatbtc/models"
+)
+
+func newTestAutoVolumeService(t *testing.T, exchange ExchangeClient) (*AutoVolumeService, models.Repositories) {
+	t.Helper()
+	setTestConfig(t, &config.Config{VolumeInterval: volumeInterval, VolumeWorkers: 2})
+	repos := models.NewMemoryRepositories()
+	return NewAutoVolumeService(nil, exchange, repos, nil), repos
+}
+
+func TestAutoVolumeFetchAndSaveKeepsClosedCandles(t *testing.T) {
+	exchange := newFakeExchange()
+	exchange.set("BTCUSDT", volumeInterval, testKlines(latestHourStart(30), time.Hour, 30, risingPrice, func(int) float64 { return 1000 }))
+	service, repos := newTestAutoVolumeService(t, exchange)
+
+	summary := service.FetchAndSaveSymbolsVolume([]string{"BTCUSDT"})
+	if summary.Fetched != 1 || summary.Failed != 0 {
+		t.Fatalf("summary = %s", summary)
+	}
+
+	records, _ := repos.AutoVolume.GetLastNBySymbol("BTCUSDT", 100)
+	if len(records) != volumeRecordsPerSymbol {
+		t.Fatalf("stored %d records, want %d", len(records), volumeRecordsPerSymbol)
+	}
+	// Nến chưa đóng không được lưu
+	if current := time.Now().Truncate(time.Hour); !records[0].OpenTime.Equal(current.Add(-time.Hour)) {
+		t.Errorf("newest record at %v, want last closed candle %v", records[0].OpenTime, current.Add(-time.Hour))
+	}
+	if count, _ := repos.PriceHistory.GetCount("BTCUSDT", volumeInterval); count != volumeRecordsPerSymbol {
+		t.Errorf("price store has %d candles, want %d", count, volumeRecordsPerSymbol)
+	}
+}
+
+func TestAutoVolumeSaveClosedKlineTrimsWindow(t *testing.T) {
+	service, repos := newTestAutoVolumeService(t, newFakeExchange())
+	klines := testKlines(latestHourStart(30), time.Hour, 30, risingPrice, func(i int) float64 { return float64(i) })
+	for _, k := range klines[:len(klines)-1] {
+		if err := service.SaveClosedKline("ETHUSDT", k); err != nil {
+			t.Fatalf("SaveClosedKline: %v", err)
+		}
+	}
+
+	records, _ := repos.AutoVolume.GetLastNBySymbol("ETHUSDT", 100)
+	if len(records) != volumeRecordsPerSymbol || records[0].QuoteAssetVolume != 28 {
+		t.Fatalf("records = %d (newest volume %v), want %d ending with volume 28", len(records), records[0].QuoteAssetVolume, volumeRecordsPerSymbol)
+	}
+}
+
+func TestAutoVolumeAnalyzeAlertsOnVolumeSpike(t *testing.T) {
+	exchange := newFakeExchange()
+	service, _ := newTestAutoVolumeService(t, exchange)
+	// Nến đã đóng cuối cùng có volume gấp 5 lần các nến trước
+	klines := testKlines(latestHourStart(23), time.Hour, 23, risingPrice, func(i int) float64 {
+		if i == 21 {
+			return 5000
+		}
+		return 1000
+	})
+	for _, k := range klines[:22] {
+		if err := service.SaveClosedKline("BTCUSDT", k); err != nil {
+			t.Fatalf("SaveClosedKline: %v", err)
+		}
+	}
+
+	alerts := make(chan volumeAlert, 1)
+	info := models.Symbol{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"}
+	if err := service.analyzeSymbolVolume(NewTechnicalAnalysisService(), info, alerts); err != nil {
+		t.Fatalf("analyzeSymbolVolume: %v", err)
+	}
+	select {
+	case alert := <-alerts:
+		if alert.symbol != "BTCUSDT" || !strings.Contains(alert.message, "EXTREME") {
+			t.Errorf("alert = %+v, want EXTREME alert for BTCUSDT", alert)
+		}
+	default:
+		t.Fatal("no alert for a 5x volume spike")
+	}
+	if exchange.calls != 0 {
+		t.Errorf("exchange called %d times for a complete window", exchange.calls)
+	}
+}
+
+func TestAutoVolumeAnalyzeRefetchesMissingCandles(t *testing.T) {
+	exchange := newFakeExchange()
+	service, repos := newTestAutoVolumeService(t, exchange)
+	klines := testKlines(latestHourStart(23), time.Hour, 23, risingPrice, func(i int) float64 {
+		if i == 21 {
+			return 5000
+		}
+		return 1000
+	})
+	// Kho thiếu một nến ở giữa cửa sổ, sàn có đủ nến
+	for i, k := range klines[:22] {
+		if i == 10 {
+			continue
+		}
+		if err := service.SaveClosedKline("BTCUSDT", k); err != nil {
+			t.Fatalf("SaveClosedKline: %v", err)
+		}
+	}
+	exchange.set("BTCUSDT", volumeInterval, klines)
+
+	alerts := make(chan volumeAlert, 1)
+	info := models.Symbol{Symbol: "BTCUSDT", QuoteAsset: "USDT"}
+	if err := service.analyzeSymbolVolume(NewTechnicalAnalysisService(), info, alerts); err != nil {
+		t.Fatalf("analyzeSymbolVolume: %v", err)
+	}
+	if exchange.calls == 0 {
+		t.Error("missing candle was not refetched from the exchange")
+	}
+	if len(alerts) != 1 {
+		t.Error("no alert after the window was repaired")
+	}
+	if records, _ := repos.AutoVolume.GetLastNBySymbol("BTCUSDT", 100); len(volumeRecordGaps(records, time.Hour)) > 0 {
+		t.Errorf("window still has gaps after refetch")
+	}
+
+	// Sàn cũng thiếu nến thì bỏ qua symbol, không cảnh báo
+	gapped := append(append([]models.KlineData(nil), klines[:5]...), klines[6:]...)
+	exchange.set("ETHUSDT", volumeInterval, gapped)
+	for _, k := range gapped[:len(gapped)-1] {
+		if err := service.SaveClosedKline("ETHUSDT", k); err != nil {
+			t.Fatalf("SaveClosedKline: %v", err)
+		}
+	}
+	err := service.analyzeSymbolVolume(NewTechnicalAnalysisService(), models.Symbol{Symbol: "ETHUSDT", QuoteAsset: "USDT"}, alerts)
+	if !errors.Is(err, errSymbolSkipped) {
+		t.Errorf("analyzeSymbolVolume with unrecoverable gap: err = %v, want skip", err)
+	}
+}
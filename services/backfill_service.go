@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+)
+
+const backfillPageSize = 1000 // Số nến tối đa Binance trả về cho một request klines
+
+// BackfillService lấy nến lịch sử từ sàn và ghi vào kho nến, có checkpoint để chạy tiếp khi bị ngắt
+type BackfillService struct {
+	exchange       ExchangeClient
+	priceRepo      models.PriceHistoryStore
+	checkpointRepo models.BackfillCheckpointStore
+	gapRepair      *GapRepairService
+	workers        int
+}
+
+// NewBackfillService tạo instance mới của service, dùng kho nến và checkpoint trong repos, workers là số symbol backfill song song
+func NewBackfillService(exchange ExchangeClient, repos models.Repositories, workers int) *BackfillService {
+	return &BackfillService{
+		exchange:       exchange,
+		priceRepo:      repos.PriceHistory,
+		checkpointRepo: repos.BackfillCheckpoint,
+		gapRepair:      NewGapRepairService(exchange, repos.PriceHistory),
+		workers:        workers,
+	}
+}
+
+// Run backfill nến cho các symbol và interval trong khoảng [from, to)
+func (s *BackfillService) Run(symbols, intervals []string, from, to time.Time) (RunSummary, error) {
+	if !from.Before(to) {
+		return RunSummary{}, fmt.Errorf("khoảng thời gian không hợp lệ: %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	for _, interval := range intervals {
+		if _, err := utils.IntervalDuration(interval); err != nil {
+			return RunSummary{}, err
+		}
+	}
+
+	log.Printf("📥 Backfill %d symbol, interval %v, từ %s tới %s",
+		len(symbols), intervals, from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+	summary := runSymbolPool("Backfill", symbols, s.workers, func(symbol string) error {
+		for _, interval := range intervals {
+			if err := s.backfillSymbol(symbol, interval, from, to); err != nil {
+				return fmt.Errorf("%s: %v", interval, err)
+			}
+			// Lấp các nến bị thiếu trong khoảng đã backfill (request lỗi, dữ liệu cũ bị xoá...)
+			if _, err := s.gapRepair.RepairRange(symbol, interval, from, to); err != nil {
+				return fmt.Errorf("%s: %v", interval, err)
+			}
+		}
+		return nil
+	})
+	return summary, nil
+}
+
+// backfillSymbol lùi dần từ cursor của checkpoint về from, mỗi trang tối đa 1000 nến
+func (s *BackfillService) backfillSymbol(symbol, interval string, from, to time.Time) error {
+	checkpoint, err := s.checkpointRepo.GetOrCreate(symbol, interval, from, to)
+	if err != nil {
+		return fmt.Errorf("lỗi đọc checkpoint: %v", err)
+	}
+	if checkpoint.Completed {
+		log.Printf("Backfill %s %s đã hoàn thành trước đó (%d nến), bỏ qua", symbol, interval, checkpoint.Candles)
+		return nil
+	}
+	if checkpoint.Cursor.Before(to) {
+		log.Printf("Backfill %s %s tiếp tục từ %s", symbol, interval, checkpoint.Cursor.Format("2006-01-02 15:04"))
+	}
+
+	total := to.Sub(from)
+	for checkpoint.Cursor.After(from) {
+		page, err := s.exchange.GetKlinesRange(symbol, interval, time.Time{}, checkpoint.Cursor.Add(-time.Millisecond), backfillPageSize)
+		if err != nil {
+			return err
+		}
+
+		// Bỏ các nến cũ hơn from
+		klines := page[:0:0]
+		for _, k := range page {
+			if k.OpenTime >= from.UnixMilli() {
+				klines = append(klines, k)
+			}
+		}
+		if err := storeClosedKlines(s.priceRepo, symbol, interval, klines); err != nil {
+			return fmt.Errorf("lỗi lưu kho nến: %v", err)
+		}
+
+		if len(page) == 0 {
+			// Sàn không còn dữ liệu cũ hơn (symbol mới niêm yết)
+			checkpoint.Cursor = from
+		} else {
+			checkpoint.Cursor = time.UnixMilli(page[0].OpenTime).UTC()
+		}
+		checkpoint.Candles += int64(len(klines))
+		if len(page) < backfillPageSize || !checkpoint.Cursor.After(from) {
+			checkpoint.Completed = true
+		}
+		if err := s.checkpointRepo.Save(checkpoint); err != nil {
+			return fmt.Errorf("lỗi lưu checkpoint: %v", err)
+		}
+
+		done := to.Sub(checkpoint.Cursor)
+		if checkpoint.Completed {
+			done = total
+		}
+		log.Printf("Backfill %s %s: %.1f%% (%d nến, tới %s)",
+			symbol, interval, float64(done)/float64(total)*100, checkpoint.Candles, checkpoint.Cursor.Format("2006-01-02 15:04"))
+		if checkpoint.Completed {
+			break
+		}
+	}
+
+	log.Printf("✅ Backfill %s %s hoàn thành: %d nến", symbol, interval, checkpoint.Candles)
+	return nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+)
+
+// CandleCacheStats là thống kê hit/miss của cache nến
+type CandleCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Series  int     `json:"series"`
+	Candles int     `json:"candles"`
+}
+
+// candleCacheKey là một chuỗi nến (symbol, interval) trong cache
+type candleCacheKey struct {
+	symbol   string
+	interval string
+}
+
+// candleRing là ring buffer các nến liên tiếp của một chuỗi, tăng dần theo open_time
+type candleRing struct {
+	candles   []models.KlineData
+	start     int
+	size      int
+	step      int64 // Độ dài một nến (ms)
+	updatedAt time.Time
+}
+
+func (r *candleRing) at(i int) *models.KlineData {
+	return &r.candles[(r.start+i)%len(r.candles)]
+}
+
+func (r *candleRing) last() *models.KlineData {
+	return r.at(r.size - 1)
+}
+
+// push thêm nến vào cuối, ghi đè nến cũ nhất khi đầy
+func (r *candleRing) push(k models.KlineData) {
+	if r.size < len(r.candles) {
+		r.size++
+	} else {
+		r.start = (r.start + 1) % len(r.candles)
+	}
+	*r.last() = k
+}
+
+func (r *candleRing) reset() {
+	r.start, r.size = 0, 0
+}
+
+// tail trả về bản sao n nến mới nhất
+func (r *candleRing) tail(n int) []models.KlineData {
+	klines := make([]models.KlineData, n)
+	for i := range klines {
+		klines[i] = *r.at(r.size - n + i)
+	}
+	return klines
+}
+
+// put ghi một nến: cập nhật nến đã có cùng open_time, nối tiếp nến mới, làm lại từ đầu khi bị đứt quãng
+func (r *candleRing) put(k models.KlineData) {
+	if r.size == 0 {
+		r.push(k)
+		return
+	}
+	first, last := r.at(0).OpenTime, r.last().OpenTime
+	switch {
+	case k.OpenTime == last+r.step:
+		r.push(k)
+	case k.OpenTime > last:
+		r.reset()
+		r.push(k)
+	case k.OpenTime >= first && (k.OpenTime-first)%r.step == 0:
+		*r.at(int((k.OpenTime - first) / r.step)) = k
+	}
+	// Nến cũ hơn nến đầu tiên bị bỏ qua, ring buffer chỉ giữ phần mới nhất
+}
+
+// CandleCache giữ các nến gần nhất của mỗi (symbol, interval) trong bộ nhớ, dùng chung cho cả process.
+// Cache được nạp từ các nguồn lấy nến (REST, WebSocket) và trả nến cho phân tích mà không cần gọi sàn
+// hay database. Cache nil luôn miss và không lưu gì.
+type CandleCache struct {
+	mu       sync.Mutex
+	capacity int
+	maxAge   time.Duration
+	series   map[candleCacheKey]*candleRing
+	hits     int64
+	misses   int64
+}
+
+// NewCandleCache tạo cache giữ tối đa capacity nến mỗi chuỗi. Dữ liệu cũ hơn maxAge kể từ lần nạp
+// gần nhất không được dùng vì nến hiện tại (chưa đóng) đã thay đổi. capacity <= 0 trả về nil (tắt cache).
+func NewCandleCache(capacity int, maxAge time.Duration) *CandleCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &CandleCache{
+		capacity: capacity,
+		maxAge:   maxAge,
+		series:   make(map[candleCacheKey]*candleRing),
+	}
+}
+
+// Put nạp các nến (tăng dần theo thời gian, có thể gồm nến chưa đóng) vào cache
+func (c *CandleCache) Put(symbol, interval string, klines []models.KlineData) {
+	if c == nil || len(klines) == 0 {
+		return
+	}
+	step, err := utils.IntervalDuration(interval)
+	if err != nil {
+		// Interval không có độ dài cố định (1M) không được cache
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := candleCacheKey{symbol: symbol, interval: interval}
+	ring, ok := c.series[key]
+	if !ok {
+		ring = &candleRing{candles: make([]models.KlineData, c.capacity), step: step.Milliseconds()}
+		c.series[key] = ring
+	}
+
+	// Dữ liệu mới bắt đầu trước nến đầu tiên trong cache: dựng lại từ dữ liệu mới rồi nối các nến mới hơn đã có
+	if ring.size > 0 && klines[0].OpenTime < ring.at(0).OpenTime {
+		cached := ring.tail(ring.size)
+		ring.reset()
+		for _, k := range klines {
+			ring.put(k)
+		}
+		for _, k := range cached {
+			if k.OpenTime > ring.last().OpenTime {
+				ring.put(k)
+			}
+		}
+	} else {
+		for _, k := range klines {
+			ring.put(k)
+		}
+	}
+	ring.updatedAt = time.Now()
+}
+
+// Get trả về limit nến gần nhất, tăng dần. Chỉ hit khi cache có đủ nến, thời điểm hiện tại nằm trong
+// [OpenTime, CloseTime] của nến cuối và cache được nạp trong vòng maxAge. So với thời gian của chính nến
+// thay vì căn theo Unix epoch vì nến 1w của Binance bắt đầu từ thứ Hai, còn epoch rơi vào thứ Năm.
+func (c *CandleCache) Get(symbol, interval string, limit int) ([]models.KlineData, bool) {
+	if c == nil || limit <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	ring, ok := c.series[candleCacheKey{symbol: symbol, interval: interval}]
+	if !ok || ring.size < limit || now.Sub(ring.updatedAt) > c.maxAge ||
+		now.UnixMilli() < ring.last().OpenTime || now.UnixMilli() > ring.last().CloseTime {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return ring.tail(limit), true
+}
+
+// Stats trả về thống kê hit/miss và số nến đang giữ
+func (c *CandleCache) Stats() CandleCacheStats {
+	if c == nil {
+		return CandleCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CandleCacheStats{Hits: c.hits, Misses: c.misses, Series: len(c.series)}
+	for _, ring := range c.series {
+		stats.Candles += ring.size
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
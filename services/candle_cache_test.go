@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCandleCacheHitsMondayAlignedWeeklyCandles(t *testing.T) {
+	cache := NewCandleCache(10, time.Minute)
+	// 3 nến 1w mở vào thứ Hai như Binance, nến cuối là tuần hiện tại
+	now := time.Now().UTC()
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday = monday.AddDate(0, 0, -(int(monday.Weekday())+6)%7)
+	week := 7 * 24 * time.Hour
+	klines := testKlines(monday.Add(-2*week), week, 3, risingPrice, nil)
+	cache.Put("BTCUSDT", "1w", klines)
+
+	got, ok := cache.Get("BTCUSDT", "1w", 3)
+	if !ok || len(got) != 3 || got[2].OpenTime != klines[2].OpenTime {
+		t.Fatalf("Get 1w: ok=%v, %d candles; want hit with the current week", ok, len(got))
+	}
+
+	// Tuần hiện tại chưa có trong cache thì phải lấy lại
+	stale := NewCandleCache(10, time.Minute)
+	stale.Put("BTCUSDT", "1w", klines[:2])
+	if _, ok := stale.Get("BTCUSDT", "1w", 2); ok {
+		t.Error("Get hit although the cache does not hold the current week")
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+)
+
+// CandleService đọc nến từ cache nến trong bộ nhớ, rồi tới kho nến (price_histories) và chỉ gọi sàn để lấy phần còn thiếu
+type CandleService struct {
+	exchange  ExchangeClient
+	priceRepo models.PriceHistoryStore
+	cache     *CandleCache
+	engine    *IndicatorEngine
+}
+
+// NewCandleService tạo instance mới của service, priceRepo là kho nến, cache có thể nil
+func NewCandleService(exchange ExchangeClient, priceRepo models.PriceHistoryStore, cache *CandleCache) *CandleService {
+	return &CandleService{
+		exchange:  exchange,
+		priceRepo: priceRepo,
+		cache:     cache,
+	}
+}
+
+// SetIndicatorEngine đưa các nến đã đóng lấy được vào engine chỉ báo
+func (s *CandleService) SetIndicatorEngine(engine *IndicatorEngine) {
+	s.engine = engine
+}
+
+// CacheCandles nạp các nến vừa lấy được (tăng dần, có thể gồm nến chưa đóng) vào cache nến
+func (s *CandleService) CacheCandles(symbol, interval string, klines []models.KlineData) {
+	s.cache.Put(symbol, interval, klines)
+}
+
+// GetCandles trả về limit nến gần nhất (nến cuối có thể chưa đóng), tăng dần theo thời gian.
+// Các nến đã đóng lấy được từ sàn sẽ được lưu lại cho lần sau. Interval không lấy trực tiếp
+// từ sàn (2h, 3h, 45m...) được dựng lại từ nến 1m/1h. Kết quả được nạp vào cache nến và engine chỉ báo.
+func (s *CandleService) GetCandles(symbol, interval string, limit int) ([]models.KlineData, error) {
+	klines, ok := s.cache.Get(symbol, interval, limit)
+	if !ok {
+		var err error
+		if klines, err = s.fetchCandles(symbol, interval, limit); err != nil {
+			return nil, err
+		}
+		s.cache.Put(symbol, interval, klines)
+	}
+	if err := s.engine.Update(symbol, interval, klines); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	return klines, nil
+}
+
+// fetchCandles lấy limit nến gần nhất từ kho nến và sàn
+func (s *CandleService) fetchCandles(symbol, interval string, limit int) ([]models.KlineData, error) {
+	if !directIntervals[interval] && interval != "1M" {
+		return s.getResampledCandles(symbol, interval, limit)
+	}
+
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		// Interval không có độ dài cố định (1M): lấy thẳng từ sàn
+		return s.exchange.GetKlines(symbol, interval, limit)
+	}
+
+	stored, err := s.priceRepo.GetBySymbolAndInterval(symbol, interval, limit)
+	if err != nil {
+		log.Printf("⚠️ Lỗi đọc kho nến %s %s: %v", symbol, interval, err)
+		stored = nil
+	}
+	// GetBySymbolAndInterval trả về giảm dần, đảo lại cho tăng dần
+	for i, j := 0, len(stored)-1; i < j; i, j = i+1, j-1 {
+		stored[i], stored[j] = stored[j], stored[i]
+	}
+
+	// Chỉ lấy từ nến mới nhất đã lưu tới nến hiện tại, nếu kho chưa đủ thì lấy toàn bộ
+	fetchLimit := limit
+	storedKlines := models.PriceHistoriesToKlines(stored)
+	if gaps := FindCandleGaps(klineOpenTimes(storedKlines), duration); len(gaps) > 0 {
+		// Kho nến bị thiếu: lấy lại toàn bộ để upsert lấp chỗ trống
+		log.Printf("Kho nến %s %s thiếu nến %v, lấy lại %d nến từ sàn", symbol, interval, gaps, limit)
+	} else if len(stored) > 0 {
+		missing := int(time.Since(stored[len(stored)-1].OpenTime)/duration) + 1
+		if missing < limit && len(stored)-1+missing >= limit {
+			fetchLimit = missing
+		}
+	}
+
+	fetched, err := s.exchange.GetKlines(symbol, interval, fetchLimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.StoreClosedKlines(symbol, interval, fetched); err != nil {
+		log.Printf("⚠️ Lỗi lưu kho nến %s %s: %v", symbol, interval, err)
+	}
+	if fetchLimit == limit || len(fetched) == 0 {
+		return fetched, nil
+	}
+
+	// Ghép nến đã lưu (trước nến đầu tiên vừa lấy) với nến vừa lấy
+	firstFetched := fetched[0].OpenTime
+	klines := make([]models.KlineData, 0, len(stored)+len(fetched))
+	for _, k := range storedKlines {
+		if k.OpenTime < firstFetched {
+			klines = append(klines, k)
+		}
+	}
+	klines = append(klines, fetched...)
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	log.Printf("Kho nến %s %s: dùng %d nến đã lưu, lấy thêm %d nến từ sàn", symbol, interval, len(klines)-len(fetched), len(fetched))
+	return klines, nil
+}
+
+// getResampledCandles dựng limit nến interval từ nến cơ sở 1m/1h
+func (s *CandleService) getResampledCandles(symbol, interval string, limit int) ([]models.KlineData, error) {
+	baseInterval, base, err := resampleBase(interval)
+	if err != nil {
+		return nil, err
+	}
+	target, _ := utils.IntervalDuration(interval)
+
+	now := time.Now()
+	from := utils.AlignTime(now, target).Add(-time.Duration(limit-1) * target)
+	baseKlines, err := s.GetRange(symbol, baseInterval, from, now)
+	if err != nil {
+		return nil, err
+	}
+	klines, dropped, err := ResampleKlines(baseKlines, base, target, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(dropped) > 0 {
+		log.Printf("⚠️ Bỏ %d nến %s %s do thiếu nến %s: %v", len(dropped), symbol, interval, baseInterval, dropped)
+	}
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	log.Printf("Dựng %d nến %s %s từ %d nến %s", len(klines), symbol, interval, len(baseKlines), baseInterval)
+	return klines, nil
+}
+
+// GetRange trả về các nến có open_time trong [from, to), tăng dần. Dùng kho nến tới chỗ thiếu
+// đầu tiên, phần còn lại lấy từ sàn theo từng trang và lưu lại các nến đã đóng.
+func (s *CandleService) GetRange(symbol, interval string, from, to time.Time) ([]models.KlineData, error) {
+	step, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.priceRepo.GetRange(symbol, interval, from, to)
+	if err != nil {
+		log.Printf("⚠️ Lỗi đọc kho nến %s %s: %v", symbol, interval, err)
+		stored = nil
+	}
+
+	// Giữ các nến đã lưu liên tiếp từ from, dừng ở chỗ thiếu đầu tiên
+	cursor := utils.AlignTime(from, step)
+	if cursor.Before(from) {
+		cursor = cursor.Add(step)
+	}
+	klines := make([]models.KlineData, 0, int(to.Sub(from)/step)+1)
+	for _, history := range stored {
+		if !history.OpenTime.Equal(cursor) {
+			break
+		}
+		klines = append(klines, history.ToKline())
+		cursor = cursor.Add(step)
+	}
+
+	// Lấy phần còn lại từ sàn
+	for cursor.Before(to) {
+		page, err := s.exchange.GetKlinesRange(symbol, interval, cursor, to.Add(-time.Millisecond), backfillPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.StoreClosedKlines(symbol, interval, page); err != nil {
+			log.Printf("⚠️ Lỗi lưu kho nến %s %s: %v", symbol, interval, err)
+		}
+		klines = append(klines, page...)
+		if len(page) < backfillPageSize {
+			break
+		}
+		cursor = time.UnixMilli(page[len(page)-1].OpenTime).Add(step)
+	}
+	return klines, nil
+}
+
+// StoreClosedKlines lưu các nến đã đóng vào kho nến, nến chưa đóng bị bỏ qua
+func (s *CandleService) StoreClosedKlines(symbol, interval string, klines []models.KlineData) error {
+	return storeClosedKlines(s.priceRepo, symbol, interval, klines)
+}
+
+// storeClosedKlines chuyển và upsert các nến đã đóng vào price_histories
+func storeClosedKlines(priceRepo models.PriceHistoryStore, symbol, interval string, klines []models.KlineData) error {
+	now := time.Now().UnixMilli()
+	candles := make([]models.PriceHistory, 0, len(klines))
+	for _, k := range klines {
+		if k.CloseTime >= now {
+			continue
+		}
+		candle, err := models.NewPriceHistoryFromKline(symbol, interval, k)
+		if err != nil {
+			return fmt.Errorf("lỗi chuyển nến: %v", err)
+		}
+		candles = append(candles, candle)
+	}
+	return priceRepo.UpsertCandles(candles)
+}
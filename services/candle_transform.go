@@ -0,0 +1,264 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"chatbtc/models"
+)
+
+const (
+	renkoATRPeriod   = 14  // Số kỳ ATR dùng làm box size mặc định của Renko
+	renkoSourceLimit = 500 // Renko gộp nhiều nến thành ít brick nên cần nhiều nến gốc hơn
+
+	// Giới hạn để box size quá nhỏ (ví dụ renko:0.00000001) không sinh ra hàng tỷ brick làm hết bộ nhớ
+	renkoMinBoxRatio = 0.0001 // Box size tối thiểu so với giá đóng cửa của nến đầu tiên (0.01%)
+	renkoMaxBricks   = 10000  // Số brick tối đa, vượt quá thì trả về lỗi
+)
+
+// CandleTransform là phép biến đổi nến trước khi phân tích (Heikin-Ashi, Renko)
+type CandleTransform struct {
+	Kind    string  // "ha" hoặc "renko", rỗng là nến thường
+	BoxSize float64 // Box size cố định của Renko, 0 thì dùng ATR(14)
+}
+
+// ParseCandleTransform đọc tham số biến đổi nến: ha, renko (box theo ATR14) hoặc renko:<box>
+func ParseCandleTransform(arg string) (CandleTransform, error) {
+	arg = strings.ToLower(strings.TrimSpace(arg))
+	switch {
+	case arg == "":
+		return CandleTransform{}, nil
+	case arg == "ha" || arg == "heikin-ashi":
+		return CandleTransform{Kind: "ha"}, nil
+	case arg == "renko":
+		return CandleTransform{Kind: "renko"}, nil
+	case strings.HasPrefix(arg, "renko:"):
+		boxSize, err := strconv.ParseFloat(strings.TrimPrefix(arg, "renko:"), 64)
+		if err != nil || boxSize <= 0 || math.IsInf(boxSize, 0) {
+			return CandleTransform{}, fmt.Errorf("box size Renko không hợp lệ: %q", arg)
+		}
+		return CandleTransform{Kind: "renko", BoxSize: boxSize}, nil
+	default:
+		return CandleTransform{}, fmt.Errorf("kiểu nến không hỗ trợ: %q (dùng ha, renko hoặc renko:<box>)", arg)
+	}
+}
+
+// IsNone cho biết có phải nến thường không
+func (t CandleTransform) IsNone() bool {
+	return t.Kind == ""
+}
+
+// Label trả về tên hiển thị của kiểu nến
+func (t CandleTransform) Label() string {
+	switch t.Kind {
+	case "ha":
+		return "Heikin-Ashi"
+	case "renko":
+		if t.BoxSize > 0 {
+			return fmt.Sprintf("Renko box %s", strconv.FormatFloat(t.BoxSize, 'f', -1, 64))
+		}
+		return fmt.Sprintf("Renko ATR%d", renkoATRPeriod)
+	default:
+		return ""
+	}
+}
+
+// SourceLimit trả về số nến gốc cần lấy để còn đủ limit nến sau biến đổi
+func (t CandleTransform) SourceLimit(limit int) int {
+	if t.Kind == "renko" && limit < renkoSourceLimit {
+		return renkoSourceLimit
+	}
+	return limit
+}
+
+// Apply biến đổi danh sách nến (tăng dần theo thời gian)
+func (t CandleTransform) Apply(klines []models.KlineData) ([]models.KlineData, error) {
+	switch t.Kind {
+	case "":
+		return klines, nil
+	case "ha":
+		return HeikinAshi(klines)
+	case "renko":
+		boxSize := t.BoxSize
+		if boxSize == 0 {
+			atr, err := averageTrueRange(klines, renkoATRPeriod)
+			if err != nil {
+				return nil, err
+			}
+			boxSize = atr
+		}
+		return Renko(klines, boxSize)
+	default:
+		return nil, fmt.Errorf("kiểu nến không hỗ trợ: %q", t.Kind)
+	}
+}
+
+// klineOHLC parse open, high, low, close của một nến
+func klineOHLC(k models.KlineData) (float64, float64, float64, float64, error) {
+	values := make([]float64, 4)
+	for i, raw := range []string{k.Open, k.High, k.Low, k.Close} {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("nến %d có giá không hợp lệ: %q", k.OpenTime, raw)
+		}
+		values[i] = value
+	}
+	return values[0], values[1], values[2], values[3], nil
+}
+
+// HeikinAshi chuyển nến thường sang nến Heikin-Ashi, giữ nguyên thời gian và volume:
+// close = (O+H+L+C)/4, open = (open HA trước + close HA trước)/2, high/low bao cả open/close HA
+func HeikinAshi(klines []models.KlineData) ([]models.KlineData, error) {
+	result := make([]models.KlineData, 0, len(klines))
+	var prevOpen, prevClose float64
+	for i, k := range klines {
+		open, high, low, close, err := klineOHLC(k)
+		if err != nil {
+			return nil, err
+		}
+
+		haClose := (open + high + low + close) / 4
+		haOpen := (open + close) / 2
+		if i > 0 {
+			haOpen = (prevOpen + prevClose) / 2
+		}
+		haHigh := math.Max(high, math.Max(haOpen, haClose))
+		haLow := math.Min(low, math.Min(haOpen, haClose))
+		prevOpen, prevClose = haOpen, haClose
+
+		ha := k
+		ha.Open = formatTransformPrice(haOpen)
+		ha.High = formatTransformPrice(haHigh)
+		ha.Low = formatTransformPrice(haLow)
+		ha.Close = formatTransformPrice(haClose)
+		result = append(result, ha)
+	}
+	return result, nil
+}
+
+// Renko dựng brick Renko từ giá đóng cửa: giá đi thêm boxSize theo chiều cũ thì thêm brick,
+// đảo chiều cần đi đủ 2 box. Volume của các nến chưa tạo brick được cộng dồn vào brick kế tiếp.
+// Nhiều brick sinh ra từ cùng một nến chia đều khoảng thời gian của nến đó nên open_time luôn tăng dần,
+// nhưng không nằm trên lưới interval: brick Renko không được lưu vào kho nến hay engine chỉ báo.
+// Box size nhỏ hơn renkoMinBoxRatio của giá hoặc số brick vượt renkoMaxBricks trả về lỗi.
+func Renko(klines []models.KlineData, boxSize float64) ([]models.KlineData, error) {
+	if boxSize <= 0 || math.IsNaN(boxSize) {
+		return nil, fmt.Errorf("box size Renko phải lớn hơn 0")
+	}
+	if len(klines) == 0 {
+		return nil, nil
+	}
+
+	_, _, _, base, err := klineOHLC(klines[0])
+	if err != nil {
+		return nil, err
+	}
+	if minBox := math.Abs(base) * renkoMinBoxRatio; boxSize < minBox {
+		return nil, fmt.Errorf("box size Renko %s quá nhỏ so với giá %s, tối thiểu %s",
+			formatTransformPrice(boxSize), formatTransformPrice(base), formatTransformPrice(minBox))
+	}
+	var bricks []models.KlineData
+	direction := 0 // 1: tăng, -1: giảm, 0: chưa có brick
+	var volume, quoteVolume float64
+	for _, k := range klines[1:] {
+		_, _, _, close, err := klineOHLC(k)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := strconv.ParseFloat(k.Volume, 64)
+		q, _ := strconv.ParseFloat(k.QuoteAssetVolume, 64)
+		volume += v
+		quoteVolume += q
+
+		first := len(bricks)
+		for {
+			brickOpen, brickClose, brickDirection, ok := nextRenkoBrick(base, close, boxSize, direction)
+			if !ok {
+				break
+			}
+			if len(bricks) >= renkoMaxBricks {
+				return nil, fmt.Errorf("box size Renko %s quá nhỏ: vượt quá %d brick", formatTransformPrice(boxSize), renkoMaxBricks)
+			}
+			direction = brickDirection
+
+			brick := models.KlineData{
+				Open:                     formatTransformPrice(brickOpen),
+				High:                     formatTransformPrice(math.Max(brickOpen, brickClose)),
+				Low:                      formatTransformPrice(math.Min(brickOpen, brickClose)),
+				Close:                    formatTransformPrice(brickClose),
+				Volume:                   formatTransformPrice(volume),
+				QuoteAssetVolume:         formatTransformPrice(quoteVolume),
+				TakerBuyBaseAssetVolume:  "0",
+				TakerBuyQuoteAssetVolume: "0",
+				Ignore:                   "0",
+			}
+			bricks = append(bricks, brick)
+			volume, quoteVolume = 0, 0
+			base = brickClose
+		}
+		splitBrickTimes(bricks[first:], k.OpenTime, k.CloseTime)
+	}
+	return bricks, nil
+}
+
+// splitBrickTimes chia [openTime, closeTime] của nến gốc cho các brick sinh ra từ nến đó,
+// brick cuối giữ close_time của nến gốc
+func splitBrickTimes(bricks []models.KlineData, openTime, closeTime int64) {
+	if len(bricks) == 0 {
+		return
+	}
+	step := (closeTime + 1 - openTime) / int64(len(bricks))
+	if step < 1 {
+		step = 1
+	}
+	for i := range bricks {
+		bricks[i].OpenTime = openTime + int64(i)*step
+		bricks[i].CloseTime = bricks[i].OpenTime + step - 1
+	}
+	bricks[len(bricks)-1].CloseTime = closeTime
+}
+
+// nextRenkoBrick trả về brick tiếp theo nếu giá close đã đi đủ xa so với base (close của brick trước)
+func nextRenkoBrick(base, close, boxSize float64, direction int) (float64, float64, int, bool) {
+	switch {
+	case direction >= 0 && close >= base+boxSize:
+		return base, base + boxSize, 1, true
+	case direction <= 0 && close <= base-boxSize:
+		return base, base - boxSize, -1, true
+	case direction == 1 && close <= base-2*boxSize:
+		// Đảo chiều giảm: brick mới bắt đầu từ đáy brick tăng trước
+		return base - boxSize, base - 2*boxSize, -1, true
+	case direction == -1 && close >= base+2*boxSize:
+		return base + boxSize, base + 2*boxSize, 1, true
+	default:
+		return 0, 0, direction, false
+	}
+}
+
+// averageTrueRange trả về ATR (Wilder) của nến cuối theo ATRSeries, lỗi nếu có nến giá không hợp lệ
+func averageTrueRange(klines []models.KlineData, period int) (float64, error) {
+	if len(klines) < period+1 {
+		return 0, fmt.Errorf("không đủ %d nến để tính ATR%d", period+1, period)
+	}
+
+	highs, lows, closes := make([]float64, len(klines)), make([]float64, len(klines)), make([]float64, len(klines))
+	for i, k := range klines {
+		_, high, low, close, err := klineOHLC(k)
+		if err != nil {
+			return 0, err
+		}
+		highs[i], lows[i], closes[i] = high, low, close
+	}
+	atr := lastValue(atrSeries(highs, lows, closes, period))
+	if atr <= 0 {
+		return 0, fmt.Errorf("ATR%d bằng 0, không dựng được Renko", period)
+	}
+	return atr, nil
+}
+
+// formatTransformPrice format số thực sang chuỗi cho KlineData
+func formatTransformPrice(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
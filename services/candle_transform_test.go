@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"chatbtc/models"
+)
+
+func TestRenkoBricksHaveDistinctTimes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := []models.KlineData{
+		testKline(start, time.Hour, 100, 100),
+		// Một nến đi 5 box tạo 5 brick
+		testKline(start.Add(time.Hour), time.Hour, 100, 105),
+		testKline(start.Add(2*time.Hour), time.Hour, 105, 106),
+	}
+	bricks, err := Renko(klines, 1)
+	if err != nil {
+		t.Fatalf("Renko: %v", err)
+	}
+	if len(bricks) != 6 {
+		t.Fatalf("got %d bricks, want 6", len(bricks))
+	}
+	for i := 1; i < len(bricks); i++ {
+		if bricks[i].OpenTime <= bricks[i-1].OpenTime || bricks[i].OpenTime <= bricks[i-1].CloseTime {
+			t.Errorf("brick %d open_time %d not after brick %d (%d-%d)", i, bricks[i].OpenTime, i-1, bricks[i-1].OpenTime, bricks[i-1].CloseTime)
+		}
+	}
+	if bricks[0].OpenTime != klines[1].OpenTime || bricks[4].CloseTime != klines[1].CloseTime {
+		t.Errorf("bricks of one candle span %d-%d, want %d-%d", bricks[0].OpenTime, bricks[4].CloseTime, klines[1].OpenTime, klines[1].CloseTime)
+	}
+	if bricks[5].OpenTime != klines[2].OpenTime {
+		t.Errorf("last brick open_time = %d, want %d", bricks[5].OpenTime, klines[2].OpenTime)
+	}
+}
+
+func TestAnalyzeTransformedCandlesUsesRealPrice(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := testKlines(start, time.Hour, 60, func(i int) (float64, float64) { return 100 + float64(i), 100.5 + float64(i) }, nil)
+	ha, err := HeikinAshi(klines)
+	if err != nil {
+		t.Fatalf("HeikinAshi: %v", err)
+	}
+
+	ta := NewTechnicalAnalysisService()
+	data, err := ta.GetAnalysisData("BTCUSDT", ha, klines, "1h/ha")
+	if err != nil {
+		t.Fatalf("GetAnalysisData: %v", err)
+	}
+	if data.CurrentPrice != 159.5 {
+		t.Errorf("CurrentPrice = %v, want real close 159.5", data.CurrentPrice)
+	}
+
+	report, err := ta.AnalyzeCrypto("BTCUSDT", ha, klines, "1h/ha", models.Symbol{})
+	if err != nil {
+		t.Fatalf("AnalyzeCrypto: %v", err)
+	}
+	if !strings.Contains(report, "**Giá hiện tại:** $159.5000") {
+		t.Errorf("report does not show the real close:\n%s", report)
+	}
+}
+
+func TestRenkoRejectsTinyBoxSize(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := []models.KlineData{
+		testKline(start, time.Hour, 100, 100),
+		testKline(start.Add(time.Hour), time.Hour, 100, 200),
+	}
+
+	// /analyze 4h BTCUSDT renko:0.00000001 không được dựng hàng tỷ brick
+	transform, err := ParseCandleTransform("renko:0.00000001")
+	if err != nil {
+		t.Fatalf("ParseCandleTransform: %v", err)
+	}
+	if _, err := transform.Apply(klines); err == nil || !strings.Contains(err.Error(), "quá nhỏ") {
+		t.Errorf("Apply with box 1e-8: err = %v, want box size too small", err)
+	}
+
+	// Box size đủ lớn so với giá nhưng giá chạy quá xa vẫn bị chặn ở renkoMaxBricks
+	klines = append(klines, testKline(start.Add(2*time.Hour), time.Hour, 200, 300))
+	if _, err := Renko(klines, 0.01); err == nil || !strings.Contains(err.Error(), "brick") {
+		t.Errorf("Renko over %d bricks: err = %v, want brick limit error", renkoMaxBricks, err)
+	}
+
+	if bricks, err := Renko(klines[:2], 1); err != nil || len(bricks) != 100 {
+		t.Errorf("Renko box 1: %d bricks, err = %v; want 100", len(bricks), err)
+	}
+}
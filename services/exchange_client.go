@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbtc/models"
+)
+
+// ExchangeClient trừu tượng hoá các endpoint của sàn mà bot sử dụng
+type ExchangeClient interface {
+	// GetKlines lấy limit nến gần nhất của symbol theo interval
+	GetKlines(symbol, interval string, limit int) ([]models.KlineData, error)
+	// GetKlinesRange lấy tối đa limit nến trong khoảng [startTime, endTime], thời điểm rỗng sẽ bị bỏ qua.
+	// Chỉ truyền endTime thì sàn trả về limit nến gần nhất trước endTime (dùng để lùi dần về quá khứ).
+	GetKlinesRange(symbol, interval string, startTime, endTime time.Time, limit int) ([]models.KlineData, error)
+	// GetTicker24h lấy thống kê giá 24h của symbol
+	GetTicker24h(symbol string) (*models.BinanceTicker24h, error)
+	// GetExchangeInfo lấy danh sách symbol và trạng thái giao dịch
+	GetExchangeInfo() (*models.BinanceExchangeInfo, error)
+}
+
+// BinanceClient gọi REST API của Binance (hoặc một server giả lập tương thích)
+type BinanceClient struct {
+	baseURL    string
+	httpClient *http.Client
+	quarantine KlineQuarantine
+}
+
+// NewBinanceClient tạo client với base URL (ví dụ: https://api.binance.com) và HTTP client được truyền vào
+func NewBinanceClient(baseURL string, httpClient *http.Client) *BinanceClient {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	return &BinanceClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// SetQuarantine đặt nơi lưu các nến không hợp lệ, nil thì chỉ ghi log
+func (c *BinanceClient) SetQuarantine(quarantine KlineQuarantine) {
+	c.quarantine = quarantine
+}
+
+// GetKlines lấy dữ liệu kline từ Binance
+func (c *BinanceClient) GetKlines(symbol, interval string, limit int) ([]models.KlineData, error) {
+	query := url.Values{}
+	query.Set("symbol", strings.ToUpper(symbol))
+	query.Set("interval", interval)
+	query.Set("limit", strconv.Itoa(limit))
+	return c.getKlines(query)
+}
+
+// GetKlinesRange lấy dữ liệu kline trong một khoảng thời gian từ Binance
+func (c *BinanceClient) GetKlinesRange(symbol, interval string, startTime, endTime time.Time, limit int) ([]models.KlineData, error) {
+	query := url.Values{}
+	query.Set("symbol", strings.ToUpper(symbol))
+	query.Set("interval", interval)
+	query.Set("limit", strconv.Itoa(limit))
+	if !startTime.IsZero() {
+		query.Set("startTime", strconv.FormatInt(startTime.UnixMilli(), 10))
+	}
+	if !endTime.IsZero() {
+		query.Set("endTime", strconv.FormatInt(endTime.UnixMilli(), 10))
+	}
+	return c.getKlines(query)
+}
+
+// getKlines gọi endpoint klines và parse kết quả
+func (c *BinanceClient) getKlines(query url.Values) ([]models.KlineData, error) {
+	body, err := c.get("/api/v3/klines", query)
+	if err != nil {
+		return nil, err
+	}
+
+	klines, rejected, err := decodeKlines(body)
+	if err != nil {
+		return nil, err
+	}
+	quarantineKlines(c.quarantine, "rest", query.Get("symbol"), query.Get("interval"), rejected)
+	return klines, nil
+}
+
+// GetTicker24h lấy thông tin giá và volume 24h từ Binance ticker API
+func (c *BinanceClient) GetTicker24h(symbol string) (*models.BinanceTicker24h, error) {
+	query := url.Values{}
+	query.Set("symbol", strings.ToUpper(symbol))
+
+	body, err := c.get("/api/v3/ticker/24hr", query)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticker models.BinanceTicker24h
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return nil, fmt.Errorf("lỗi khi parse JSON ticker: %v", err)
+	}
+	return &ticker, nil
+}
+
+// GetExchangeInfo lấy danh sách symbol từ Binance API
+func (c *BinanceClient) GetExchangeInfo() (*models.BinanceExchangeInfo, error) {
+	body, err := c.get("/api/v3/exchangeInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchangeInfo models.BinanceExchangeInfo
+	if err := json.Unmarshal(body, &exchangeInfo); err != nil {
+		return nil, fmt.Errorf("lỗi khi parse JSON exchangeInfo: %v", err)
+	}
+	return &exchangeInfo, nil
+}
+
+// RateLimitBudget trả về ngân sách weight nếu HTTP client dùng RateLimitedTransport
+func (c *BinanceClient) RateLimitBudget() RateLimitBudget {
+	if transport, ok := c.httpClient.Transport.(*RateLimitedTransport); ok {
+		return transport.RateLimitBudget()
+	}
+	return RateLimitBudget{}
+}
+
+// get gọi endpoint GET và trả về body, chuyển lỗi của Binance thành error
+func (c *BinanceClient) get(path string, query url.Values) ([]byte, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi gọi API Binance: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc response: %v", err)
+	}
+
+	// Kiểm tra xem response có phải là error object không
+	var errorResponse struct {
+		Code *int   `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Code != nil {
+		return nil, fmt.Errorf("lỗi API Binance: %s", errorResponse.Msg)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
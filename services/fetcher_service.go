@@ -1,31 +1,37 @@
 package services
 
 import (
+	"chatbtc/config"
 	"chatbtc/models"
-	"encoding/json"
-	"fmt"
 	"log"
-	"net/http"
 	"time"
 )
 
-const binanceAPIURL = "https://api.binance.com/api/v3/exchangeInfo"
+// ChannelNotifier gửi tin nhắn lên channel Telegram
+type ChannelNotifier interface {
+	SendTelegramToChannel(channelID, message string)
+	GetChannelID() string
+}
 
 // FetcherService lấy dữ liệu từ Binance API
-type FetcherService struct{}
+type FetcherService struct {
+	exchange   ExchangeClient
+	notifier   ChannelNotifier
+	symbolRepo models.SymbolStore
+}
 
-// NewFetcherService tạo instance mới của service
-func NewFetcherService() *FetcherService {
-	return &FetcherService{}
+// NewFetcherService tạo instance mới của service, notifier dùng để thông báo niêm yết/huỷ niêm yết
+func NewFetcherService(exchange ExchangeClient, notifier ChannelNotifier, symbolRepo models.SymbolStore) *FetcherService {
+	return &FetcherService{
+		exchange:   exchange,
+		notifier:   notifier,
+		symbolRepo: symbolRepo,
+	}
 }
 
 // FetchAndUpdateSymbols lấy danh sách symbol từ Binance API và cập nhật vào database
 func (s *FetcherService) FetchAndUpdateSymbols() error {
-	// kiểm tra cập nhật
-	if !models.NewSymbolRepository().ShouldUpdate() {
-		log.Println("Dữ liệu đã được cập nhật, bỏ qua việc lấy dữ liệu mới")
-		return nil
-	}
+	symbolRepo := s.symbolRepo
 
 	// lấy dữ liệu mới
 	symbols, err := s.fetchFromAPI()
@@ -34,12 +40,29 @@ func (s *FetcherService) FetchAndUpdateSymbols() error {
 		return err
 	}
 
-	// lưu dữ liệu vào database
-	if err := models.NewSymbolRepository().SaveToDatabase(symbols); err != nil {
+	// Quote asset chưa có symbol nào (lần đồng bộ đầu tiên hoặc vừa thêm vào QUOTE_ASSETS)
+	// không gửi thông báo cho toàn bộ symbol của quote asset đó
+	quoteAssets := config.AppConfig.QuoteAssets
+	knownQuoteAssets := make(map[string]bool, len(quoteAssets))
+	for _, quoteAsset := range quoteAssets {
+		hasSymbols, err := symbolRepo.HasSymbols(quoteAsset)
+		if err != nil {
+			return err
+		}
+		knownQuoteAssets[quoteAsset] = hasSymbols
+	}
+
+	// đồng bộ dữ liệu vào database (upsert, giữ lịch sử niêm yết/huỷ niêm yết),
+	// chỉ so sánh với các symbol thuộc quote asset đang bật
+	events, err := symbolRepo.SyncSymbols(symbols, quoteAssets)
+	if err != nil {
 		log.Printf("Lỗi khi lưu dữ liệu vào database: %v", err)
+		return err
 	}
+	logSymbolEvents(events)
+	s.announceListingEvents(knownQuoteAssetEvents(events, symbols, knownQuoteAssets), symbols)
 	// cập nhật thời gian cập nhật
-	if err := models.NewSymbolRepository().UpdateLastUpdateTime(); err != nil {
+	if err := symbolRepo.UpdateLastUpdateTime(); err != nil {
 		log.Printf("Lỗi khi cập nhật thời gian cập nhật: %v", err)
 	}
 	return nil
@@ -47,56 +70,81 @@ func (s *FetcherService) FetchAndUpdateSymbols() error {
 
 // fetchFromAPI lấy danh sách symbol từ Binance API
 func (s *FetcherService) fetchFromAPI() ([]models.Symbol, error) {
-	resp, err := http.Get(binanceAPIURL)
+	exchangeInfo, err := s.exchange.GetExchangeInfo()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
-	}
 
-	var exchangeInfo models.BinanceExchangeInfo
-	if err := json.NewDecoder(resp.Body).Decode(&exchangeInfo); err != nil {
-		return nil, err
+	// Lọc symbols theo các quote asset được cấu hình (giữ cả symbol không ở trạng thái
+	// TRADING để phát hiện chuyển trạng thái như TRADING -> BREAK)
+	quoteAssets := make(map[string]bool)
+	for _, quoteAsset := range config.AppConfig.QuoteAssets {
+		quoteAssets[quoteAsset] = true
 	}
-
-	// Lọc symbols với quoteAsset là USDT
 	var symbols []models.Symbol
 	for _, binanceSymbol := range exchangeInfo.Symbols {
-		if binanceSymbol.QuoteAsset == "USDT" && binanceSymbol.Status == "TRADING" {
-			symbol := models.Symbol{
-				Symbol:    binanceSymbol.Symbol,
-				Status:    binanceSymbol.Status,
-				BaseAsset: binanceSymbol.BaseAsset,
-			}
-			symbols = append(symbols, symbol)
+		if quoteAssets[binanceSymbol.QuoteAsset] {
+			symbols = append(symbols, binanceSymbol.ToSymbol())
 		}
 	}
 
 	return symbols, nil
 }
 
+// knownQuoteAssetEvents bỏ các sự kiện của symbol thuộc quote asset chưa có symbol nào trước lần đồng bộ này.
+// Symbol bị gỡ hoặc đổi trạng thái luôn thuộc quote asset đã có symbol nên được giữ lại.
+func knownQuoteAssetEvents(events []models.SymbolHistory, symbols []models.Symbol, knownQuoteAssets map[string]bool) []models.SymbolHistory {
+	quoteAssetBySymbol := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		quoteAssetBySymbol[symbol.Symbol] = symbol.QuoteAsset
+	}
+	var result []models.SymbolHistory
+	for _, event := range events {
+		if quoteAsset, ok := quoteAssetBySymbol[event.Symbol]; ok && !knownQuoteAssets[quoteAsset] {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// logSymbolEvents ghi log tổng hợp các thay đổi sau khi đồng bộ symbol
+func logSymbolEvents(events []models.SymbolHistory) {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.Event]++
+		log.Printf("Symbol %s: %s (%s -> %s)", event.Symbol, event.Event, event.OldStatus, event.NewStatus)
+	}
+	log.Printf("Đồng bộ symbol: %d mới, %d đổi trạng thái, %d bị gỡ",
+		counts[models.SymbolEventAdded], counts[models.SymbolEventStatusChanged], counts[models.SymbolEventRemoved])
+}
+
 type Scheduler struct {
 	fetchService *FetcherService
+	interval     time.Duration
 	stopChan     chan bool
 }
 
-func NewScheduler(fetchService *FetcherService) *Scheduler {
+// NewScheduler tạo scheduler làm mới danh sách symbol theo chu kỳ interval
+func NewScheduler(fetchService *FetcherService, interval time.Duration) *Scheduler {
 	return &Scheduler{
 		fetchService: fetchService,
+		interval:     interval,
 		stopChan:     make(chan bool),
 	}
 }
 
 func (s *Scheduler) Start() {
-	log.Println("Scheduler started")
-	// Chạy cập nhật đầu tiên
-	go s.runUpdate()
+	log.Printf("Scheduler started (làm mới symbol mỗi %v)", s.interval)
+	// Chạy cập nhật đầu tiên nếu dữ liệu đã cũ
+	if s.fetchService.symbolRepo.ShouldUpdate(s.interval) {
+		go s.runUpdate()
+	} else {
+		log.Println("Dữ liệu đã được cập nhật, bỏ qua việc lấy dữ liệu mới")
+	}
 
-	// Chạy cập nhật định kỳ mỗi 15 ngày
-	ticker := time.NewTicker(15 * 24 * time.Hour)
+	// Chạy cập nhật định kỳ
+	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
 	for {
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"chatbtc/config"
+	"chatbtc/models"
+)
+
+// fakeNotifier ghi lại các tin nhắn gửi lên channel
+type fakeNotifier struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (n *fakeNotifier) SendTelegramToChannel(channelID, message string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, message)
+}
+
+func (n *fakeNotifier) GetChannelID() string {
+	return "test"
+}
+
+func binanceSymbols(symbols ...string) *models.BinanceExchangeInfo {
+	info := &models.BinanceExchangeInfo{}
+	for _, symbol := range symbols {
+		base, quote, _ := strings.Cut(symbol, "/")
+		info.Symbols = append(info.Symbols, models.BinanceSymbol{
+			Symbol: base + quote, Status: models.SymbolStatusTrading, BaseAsset: base, QuoteAsset: quote,
+		})
+	}
+	return info
+}
+
+func TestFetcherDoesNotAnnounceQuoteAssetChanges(t *testing.T) {
+	setTestConfig(t, &config.Config{QuoteAssets: []string{"USDT", "BTC"}})
+	exchange := newFakeExchange()
+	notifier := &fakeNotifier{}
+	repos := models.NewMemoryRepositories()
+	fetcher := NewFetcherService(exchange, notifier, repos.Symbol)
+
+	// Lần đồng bộ đầu tiên không thông báo
+	exchange.info = binanceSymbols("BTC/USDT", "ETH/USDT", "ETH/BTC")
+	if err := fetcher.FetchAndUpdateSymbols(); err != nil {
+		t.Fatalf("FetchAndUpdateSymbols: %v", err)
+	}
+
+	// Tắt BTC, bật FDUSD: không có DELISTING cho ETHBTC và không có NEW LISTING cho các cặp FDUSD
+	config.AppConfig.QuoteAssets = []string{"USDT", "FDUSD"}
+	exchange.info = binanceSymbols("BTC/USDT", "ETH/USDT", "ETH/BTC", "BTC/FDUSD", "ETH/FDUSD")
+	if err := fetcher.FetchAndUpdateSymbols(); err != nil {
+		t.Fatalf("FetchAndUpdateSymbols: %v", err)
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("announced %d messages after changing QUOTE_ASSETS: %v", len(notifier.messages), notifier.messages)
+	}
+	if ethBTC, _ := repos.Symbol.GetBySymbol("ETHBTC"); ethBTC.Status != models.SymbolStatusTrading {
+		t.Errorf("ETHBTC status = %s, want unchanged %s", ethBTC.Status, models.SymbolStatusTrading)
+	}
+	if symbols, _ := repos.Symbol.GetAllSymbols(config.AppConfig.QuoteAssets); len(symbols) != 4 {
+		t.Errorf("tracked symbols = %v, want the USDT and FDUSD pairs", symbols)
+	}
+
+	// Niêm yết mới thật sự của quote asset đã theo dõi vẫn được thông báo
+	exchange.info = binanceSymbols("BTC/USDT", "ETH/USDT", "SOL/USDT", "BTC/FDUSD", "ETH/FDUSD")
+	if err := fetcher.FetchAndUpdateSymbols(); err != nil {
+		t.Fatalf("FetchAndUpdateSymbols: %v", err)
+	}
+	if len(notifier.messages) != 1 || !strings.Contains(notifier.messages[0], "SOL/USDT") {
+		t.Errorf("messages = %v, want one NEW LISTING for SOL/USDT", notifier.messages)
+	}
+}
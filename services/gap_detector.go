@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+)
+
+// CandleGap là một đoạn nến bị thiếu, From/To là open_time của nến thiếu đầu tiên và cuối cùng
+type CandleGap struct {
+	From    time.Time
+	To      time.Time
+	Missing int
+}
+
+// String format gap để ghi log
+func (g CandleGap) String() string {
+	return fmt.Sprintf("%s → %s (%d nến)", g.From.UTC().Format("2006-01-02 15:04"), g.To.UTC().Format("2006-01-02 15:04"), g.Missing)
+}
+
+// FindCandleGaps kiểm tra các open_time (tăng dần) có liên tiếp theo step không và trả về các đoạn bị thiếu
+func FindCandleGaps(openTimes []time.Time, step time.Duration) []CandleGap {
+	var gaps []CandleGap
+	for i := 1; i < len(openTimes); i++ {
+		diff := openTimes[i].Sub(openTimes[i-1])
+		if diff <= step {
+			continue
+		}
+		gaps = append(gaps, CandleGap{
+			From:    openTimes[i-1].Add(step),
+			To:      openTimes[i].Add(-step),
+			Missing: int(diff/step) - 1,
+		})
+	}
+	return gaps
+}
+
+// klineOpenTimes lấy open_time của danh sách nến theo thứ tự tăng dần
+func klineOpenTimes(klines []models.KlineData) []time.Time {
+	openTimes := make([]time.Time, 0, len(klines))
+	for _, k := range klines {
+		openTimes = append(openTimes, time.UnixMilli(k.OpenTime))
+	}
+	return openTimes
+}
+
+// volumeRecordGaps kiểm tra cửa sổ AutoVolumeRecord (sắp xếp giảm dần theo open_time) có liên tiếp không
+func volumeRecordGaps(records []models.AutoVolumeRecord, step time.Duration) []CandleGap {
+	openTimes := make([]time.Time, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		openTimes = append(openTimes, records[i].OpenTime)
+	}
+	return FindCandleGaps(openTimes, step)
+}
+
+// GapRepairService phát hiện nến bị thiếu trong kho nến và lấy lại từ sàn
+type GapRepairService struct {
+	exchange  ExchangeClient
+	priceRepo models.PriceHistoryStore
+}
+
+// NewGapRepairService tạo instance mới của service, priceRepo là kho nến cần kiểm tra
+func NewGapRepairService(exchange ExchangeClient, priceRepo models.PriceHistoryStore) *GapRepairService {
+	return &GapRepairService{
+		exchange:  exchange,
+		priceRepo: priceRepo,
+	}
+}
+
+// RepairRange kiểm tra các nến đã lưu trong [from, to), lấy lại các đoạn bị thiếu
+// và trả về các đoạn vẫn còn thiếu sau khi sửa (ví dụ sàn bảo trì không có nến)
+func (s *GapRepairService) RepairRange(symbol, interval string, from, to time.Time) ([]CandleGap, error) {
+	step, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	openTimes, err := s.priceRepo.GetOpenTimes(symbol, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi đọc kho nến: %v", err)
+	}
+	gaps := FindCandleGaps(openTimes, step)
+	if len(gaps) == 0 {
+		return nil, nil
+	}
+
+	for _, gap := range gaps {
+		log.Printf("Phát hiện thiếu nến %s %s: %s, đang lấy lại", symbol, interval, gap)
+		if err := s.refetchGap(symbol, interval, gap, step); err != nil {
+			return nil, fmt.Errorf("lỗi lấy lại nến %s: %v", gap, err)
+		}
+	}
+
+	// Kiểm tra lại sau khi sửa
+	openTimes, err = s.priceRepo.GetOpenTimes(symbol, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi đọc kho nến: %v", err)
+	}
+	remaining := FindCandleGaps(openTimes, step)
+	for _, gap := range remaining {
+		log.Printf("⚠️ Sàn không có nến %s %s: %s", symbol, interval, gap)
+	}
+	return remaining, nil
+}
+
+// refetchGap lấy các nến trong gap theo từng trang 1000 nến và lưu vào kho nến
+func (s *GapRepairService) refetchGap(symbol, interval string, gap CandleGap, step time.Duration) error {
+	cursor := gap.From
+	for !cursor.After(gap.To) {
+		page, err := s.exchange.GetKlinesRange(symbol, interval, cursor, gap.To, backfillPageSize)
+		if err != nil {
+			return err
+		}
+		if err := storeClosedKlines(s.priceRepo, symbol, interval, page); err != nil {
+			return err
+		}
+		if len(page) < backfillPageSize {
+			return nil
+		}
+		cursor = time.UnixMilli(page[len(page)-1].OpenTime).Add(step)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"chatbtc/config"
+	"chatbtc/models"
+)
+
+// testKline tạo nến đã đóng bắt đầu tại openTime, độ dài step, giá mở/đóng là open/close
+func testKline(openTime time.Time, step time.Duration, open, close float64) models.KlineData {
+	high, low := open, close
+	if close > open {
+		high, low = close, open
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return models.KlineData{
+		OpenTime:                 openTime.UnixMilli(),
+		Open:                     format(open),
+		High:                     format(high + 1),
+		Low:                      format(low - 1),
+		Close:                    format(close),
+		Volume:                   "10",
+		CloseTime:                openTime.Add(step).UnixMilli() - 1,
+		QuoteAssetVolume:         format(10 * close),
+		NumberOfTrades:           5,
+		TakerBuyBaseAssetVolume:  "4",
+		TakerBuyQuoteAssetVolume: format(4 * close),
+	}
+}
+
+// storeTestKlines lưu các nến vào kho nến
+func storeTestKlines(t *testing.T, repo models.PriceHistoryStore, symbol, interval string, klines []models.KlineData) {
+	t.Helper()
+	candles := make([]models.PriceHistory, 0, len(klines))
+	for _, k := range klines {
+		candle, err := models.NewPriceHistoryFromKline(symbol, interval, k)
+		if err != nil {
+			t.Fatalf("NewPriceHistoryFromKline: %v", err)
+		}
+		candles = append(candles, candle)
+	}
+	if err := repo.UpsertCandles(candles); err != nil {
+		t.Fatalf("UpsertCandles: %v", err)
+	}
+}
+
+// fakeExchange là ExchangeClient trả về nến có sẵn theo (symbol, interval)
+type fakeExchange struct {
+	mu     sync.Mutex
+	klines map[string][]models.KlineData
+	calls  int
+	info   *models.BinanceExchangeInfo
+}
+
+func newFakeExchange() *fakeExchange {
+	return &fakeExchange{klines: make(map[string][]models.KlineData)}
+}
+
+func (e *fakeExchange) set(symbol, interval string, klines []models.KlineData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.klines[symbol+"/"+interval] = klines
+}
+
+func (e *fakeExchange) GetKlines(symbol, interval string, limit int) ([]models.KlineData, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	klines, ok := e.klines[symbol+"/"+interval]
+	if !ok {
+		return nil, fmt.Errorf("không có nến %s %s", symbol, interval)
+	}
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return append([]models.KlineData(nil), klines...), nil
+}
+
+func (e *fakeExchange) GetKlinesRange(symbol, interval string, startTime, endTime time.Time, limit int) ([]models.KlineData, error) {
+	all, err := e.GetKlines(symbol, interval, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	var klines []models.KlineData
+	for _, k := range all {
+		if (!startTime.IsZero() && k.OpenTime < startTime.UnixMilli()) || (!endTime.IsZero() && k.OpenTime > endTime.UnixMilli()) {
+			continue
+		}
+		klines = append(klines, k)
+	}
+	if len(klines) > limit {
+		if startTime.IsZero() {
+			klines = klines[len(klines)-limit:]
+		} else {
+			klines = klines[:limit]
+		}
+	}
+	return klines, nil
+}
+
+func (e *fakeExchange) GetTicker24h(symbol string) (*models.BinanceTicker24h, error) {
+	return nil, fmt.Errorf("không hỗ trợ")
+}
+
+func (e *fakeExchange) GetExchangeInfo() (*models.BinanceExchangeInfo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.info == nil {
+		return nil, fmt.Errorf("không hỗ trợ")
+	}
+	return e.info, nil
+}
+
+// testKlines tạo n nến liên tiếp dài step, nến đầu mở tại start. price trả về giá mở và đóng của nến i,
+// volume khác nil thì thay quote volume mặc định của testKline bằng volume(i).
+func testKlines(start time.Time, step time.Duration, n int, price func(i int) (open, close float64), volume func(i int) float64) []models.KlineData {
+	klines := make([]models.KlineData, 0, n)
+	for i := 0; i < n; i++ {
+		open, close := price(i)
+		k := testKline(start.Add(time.Duration(i)*step), step, open, close)
+		if volume != nil {
+			k.QuoteAssetVolume = strconv.FormatFloat(volume(i), 'f', -1, 64)
+		}
+		klines = append(klines, k)
+	}
+	return klines
+}
+
+// risingPrice tăng giá 1 mỗi nến: nến i mở ở 100+i, đóng ở 101+i
+func risingPrice(i int) (float64, float64) {
+	return 100 + float64(i), 101 + float64(i)
+}
+
+// latestHourStart là open time của nến đầu trong n nến 1h liên tiếp kết thúc bằng nến đang chạy (chưa đóng)
+func latestHourStart(n int) time.Time {
+	return time.Now().Truncate(time.Hour).Add(-time.Duration(n-1) * time.Hour)
+}
+
+// setTestConfig đặt config.AppConfig cho test và khôi phục khi test kết thúc
+func setTestConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = cfg
+	t.Cleanup(func() { config.AppConfig = previous })
+}
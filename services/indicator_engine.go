@@ -0,0 +1,201 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+
+	"gorm.io/gorm"
+)
+
+// indicatorWarmupCandles là số nến đã lưu được đọc lại để dựng trạng thái chỉ báo khi chưa có snapshot hoặc bị đứt quãng
+const indicatorWarmupCandles = 250
+
+// IndicatorEngine giữ trạng thái chỉ báo của mỗi (symbol, interval) và cập nhật O(1) theo từng nến đã đóng
+// do các nguồn nến (REST, WebSocket) đẩy vào. Trạng thái được lưu vào indicator_snapshots sau mỗi lần cập nhật
+// để khởi động lại không phải tính lại từ đầu. Engine nil không làm gì và luôn trả về không có dữ liệu.
+// Mỗi (symbol, interval) có khoá riêng nên đọc/ghi snapshot và kho nến của một chuỗi không chặn các chuỗi khác.
+type IndicatorEngine struct {
+	mu           sync.Mutex // chỉ bảo vệ map series
+	priceRepo    models.PriceHistoryStore
+	snapshotRepo models.IndicatorSnapshotStore
+	series       map[candleCacheKey]*indicatorSeries
+}
+
+// indicatorSeries là trạng thái chỉ báo của một (symbol, interval), được bảo vệ bởi mu của chính nó
+type indicatorSeries struct {
+	mu     sync.Mutex
+	loaded bool // đã thử khôi phục từ snapshot chưa
+	set    *IndicatorSet
+}
+
+// NewIndicatorEngine tạo engine mới, dùng kho nến để warm up và IndicatorSnapshot để lưu trạng thái
+func NewIndicatorEngine(repos models.Repositories) *IndicatorEngine {
+	return &IndicatorEngine{
+		priceRepo:    repos.PriceHistory,
+		snapshotRepo: repos.IndicatorSnapshot,
+		series:       make(map[candleCacheKey]*indicatorSeries),
+	}
+}
+
+// lockSeries trả về chuỗi của key (tạo mới nếu chưa có) đã được khoá, caller phải Unlock
+func (e *IndicatorEngine) lockSeries(key candleCacheKey) *indicatorSeries {
+	e.mu.Lock()
+	series, ok := e.series[key]
+	if !ok {
+		series = &indicatorSeries{}
+		e.series[key] = series
+	}
+	e.mu.Unlock()
+	series.mu.Lock()
+	return series
+}
+
+// Update đưa các nến (tăng dần, có thể gồm nến chưa đóng) vào engine. Chỉ nến đã đóng và mới hơn
+// nến cuối đã nhận được áp dụng; nếu bị đứt quãng thì trạng thái được dựng lại từ kho nến.
+func (e *IndicatorEngine) Update(symbol, interval string, klines []models.KlineData) error {
+	if e == nil || len(klines) == 0 {
+		return nil
+	}
+	step, err := utils.IntervalDuration(interval)
+	if err != nil {
+		// Interval không có độ dài cố định (1M) không được theo dõi
+		return nil
+	}
+
+	key := candleCacheKey{symbol: symbol, interval: interval}
+	series := e.lockSeries(key)
+	defer series.mu.Unlock()
+	set := e.load(key, series, step.Milliseconds())
+
+	now := time.Now().UnixMilli()
+	applied := false
+	var updateErr error
+	for _, k := range klines {
+		if k.CloseTime >= now || (set != nil && k.OpenTime <= set.LastOpenTime) {
+			continue
+		}
+		if set == nil || k.OpenTime != set.LastOpenTime+set.Step {
+			// Chưa có trạng thái hoặc bị đứt quãng: dựng lại từ các nến đã lưu trước nến này
+			set = e.warmUp(symbol, interval, step.Milliseconds(), k.OpenTime)
+			series.set = set
+		}
+		if err := set.Update(k); err != nil {
+			updateErr = fmt.Errorf("lỗi cập nhật chỉ báo %s %s: %v", symbol, interval, err)
+			break
+		}
+		applied = true
+	}
+	if applied {
+		if err := e.save(symbol, interval, set); err != nil {
+			return err
+		}
+	}
+	return updateErr
+}
+
+// Get trả về giá trị chỉ báo tại nến current: nến đã đóng cuối cùng engine đã nhận, hoặc nến ngay sau nó
+// (thường là nến chưa đóng, được tính thử mà không đổi trạng thái). false nếu engine chưa đủ dữ liệu.
+func (e *IndicatorEngine) Get(symbol, interval string, current models.KlineData) (IndicatorValues, bool) {
+	if e == nil {
+		return IndicatorValues{}, false
+	}
+	step, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return IndicatorValues{}, false
+	}
+
+	key := candleCacheKey{symbol: symbol, interval: interval}
+	series := e.lockSeries(key)
+	defer series.mu.Unlock()
+	set := e.load(key, series, step.Milliseconds())
+	if set == nil || !set.Ready() {
+		return IndicatorValues{}, false
+	}
+	switch current.OpenTime {
+	case set.LastOpenTime:
+		return set.Values(), true
+	case set.LastOpenTime + set.Step:
+		values, err := set.Peek(current)
+		if err != nil {
+			return IndicatorValues{}, false
+		}
+		return values, true
+	}
+	return IndicatorValues{}, false
+}
+
+// load lấy trạng thái trong bộ nhớ, nếu chưa có thì khôi phục từ snapshot (chỉ thử một lần).
+// nil nếu chưa có trạng thái. Caller phải giữ khoá của series.
+func (e *IndicatorEngine) load(key candleCacheKey, series *indicatorSeries, step int64) *IndicatorSet {
+	if series.loaded {
+		return series.set
+	}
+	snapshot, err := e.snapshotRepo.Get(key.symbol, key.interval)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			// Lỗi đọc DB thì lần sau thử lại
+			log.Printf("⚠️ Lỗi đọc snapshot chỉ báo %s %s: %v", key.symbol, key.interval, err)
+			return nil
+		}
+		series.loaded = true
+		return nil
+	}
+	series.loaded = true
+	var set IndicatorSet
+	if err := json.Unmarshal([]byte(snapshot.State), &set); err != nil || set.Step != step {
+		log.Printf("⚠️ Snapshot chỉ báo %s %s không hợp lệ, dựng lại từ kho nến", key.symbol, key.interval)
+		return nil
+	}
+	series.set = &set
+	return &set
+}
+
+// warmUp dựng trạng thái từ tối đa indicatorWarmupCandles nến đã lưu ngay trước before (ms).
+// Đoạn nến bị đứt quãng thì chỉ giữ phần sau chỗ đứt, trả về trạng thái rỗng nếu không có nến liền trước before.
+func (e *IndicatorEngine) warmUp(symbol, interval string, step, before int64) *IndicatorSet {
+	set := NewIndicatorSet(step)
+	from := time.UnixMilli(before - indicatorWarmupCandles*step)
+	stored, err := e.priceRepo.GetRange(symbol, interval, from, time.UnixMilli(before))
+	if err != nil {
+		log.Printf("⚠️ Lỗi đọc kho nến %s %s để dựng chỉ báo: %v", symbol, interval, err)
+		return set
+	}
+	for _, k := range models.PriceHistoriesToKlines(stored) {
+		if set.Update(k) != nil {
+			set = NewIndicatorSet(step)
+			if set.Update(k) != nil {
+				set = NewIndicatorSet(step)
+			}
+		}
+	}
+	// Kho nến thiếu các nến ngay trước before thì không dùng được phần đã dựng
+	if set.LastOpenTime != before-step {
+		return NewIndicatorSet(step)
+	}
+	return set
+}
+
+// save ghi trạng thái vào indicator_snapshots
+func (e *IndicatorEngine) save(symbol, interval string, set *IndicatorSet) error {
+	state, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("lỗi mã hóa snapshot chỉ báo: %v", err)
+	}
+	snapshot := models.IndicatorSnapshot{
+		Symbol:   symbol,
+		Interval: interval,
+		OpenTime: time.UnixMilli(set.LastOpenTime),
+		State:    string(state),
+	}
+	if err := e.snapshotRepo.Save(&snapshot); err != nil {
+		return fmt.Errorf("lỗi lưu snapshot chỉ báo %s %s: %v", symbol, interval, err)
+	}
+	return nil
+}
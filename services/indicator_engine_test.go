@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"chatbtc/models"
+)
+
+// blockingSnapshotStore chặn Get của symbol slow cho tới khi release được đóng
+type blockingSnapshotStore struct {
+	models.IndicatorSnapshotStore
+	slow    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSnapshotStore) Get(symbol, interval string) (*models.IndicatorSnapshot, error) {
+	if symbol == s.slow {
+		close(s.started)
+		<-s.release
+	}
+	return s.IndicatorSnapshotStore.Get(symbol, interval)
+}
+
+func TestIndicatorEngineSeriesDoNotBlockEachOther(t *testing.T) {
+	repos := models.NewMemoryRepositories()
+	store := &blockingSnapshotStore{
+		IndicatorSnapshotStore: repos.IndicatorSnapshot,
+		slow:                   "SLOWUSDT",
+		started:                make(chan struct{}),
+		release:                make(chan struct{}),
+	}
+	repos.IndicatorSnapshot = store
+	engine := NewIndicatorEngine(repos)
+	klines := testKlines(latestHourStart(60), time.Hour, 60, risingPrice, nil)
+
+	slowDone := make(chan error)
+	go func() { slowDone <- engine.Update("SLOWUSDT", "1h", klines) }()
+	<-store.started
+
+	// Symbol khác vẫn cập nhật được trong khi SLOWUSDT đang chờ đọc snapshot
+	fastDone := make(chan error)
+	go func() { fastDone <- engine.Update("FASTUSDT", "1h", klines) }()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Fatalf("Update FASTUSDT: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Update FASTUSDT blocked by snapshot I/O of SLOWUSDT")
+	}
+	if _, ok := engine.Get("FASTUSDT", "1h", klines[len(klines)-1]); !ok {
+		t.Error("Get FASTUSDT: no values after update")
+	}
+
+	close(store.release)
+	if err := <-slowDone; err != nil {
+		t.Fatalf("Update SLOWUSDT: %v", err)
+	}
+	if _, ok := engine.Get("SLOWUSDT", "1h", klines[len(klines)-1]); !ok {
+		t.Error("Get SLOWUSDT: no values after update")
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"chatbtc/models"
+)
+
+// EMAState tính EMA tăng dần, mỗi giá mới O(1). Giống EMASeries: EMA đầu tiên là SMA của Period giá đầu.
+// Các field được export để lưu snapshot dạng JSON.
+type EMAState struct {
+	Period int     `json:"period"`
+	Count  int     `json:"count"`
+	Sum    float64 `json:"sum"`
+	Value  float64 `json:"value"`
+}
+
+// NewEMAState tạo EMA rỗng cho period
+func NewEMAState(period int) EMAState {
+	return EMAState{Period: period}
+}
+
+// Update thêm một giá mới
+func (e *EMAState) Update(price float64) {
+	e.Count++
+	if e.Count < e.Period {
+		e.Sum += price
+		return
+	}
+	if e.Count == e.Period {
+		e.Value = (e.Sum + price) / float64(e.Period)
+		e.Sum = 0
+		return
+	}
+	multiplier := 2.0 / float64(e.Period+1)
+	e.Value = (price * multiplier) + (e.Value * (1 - multiplier))
+}
+
+// Ready cho biết đã đủ Period giá để có EMA chưa
+func (e *EMAState) Ready() bool {
+	return e.Period > 0 && e.Count >= e.Period
+}
+
+// RSIState tính RSI làm mượt theo Wilder tăng dần, cho cùng kết quả với RSISeries
+type RSIState struct {
+	Period    int     `json:"period"`
+	Count     int     `json:"count"` // Số giá đã nhận
+	PrevClose float64 `json:"prev_close"`
+	AvgGain   float64 `json:"avg_gain"`
+	AvgLoss   float64 `json:"avg_loss"`
+	Value     float64 `json:"value"`
+}
+
+// NewRSIState tạo RSI rỗng cho period
+func NewRSIState(period int) RSIState {
+	return RSIState{Period: period}
+}
+
+// Update thêm một giá đóng cửa mới
+func (r *RSIState) Update(price float64) {
+	r.Count++
+	if r.Count == 1 {
+		r.PrevClose = price
+		return
+	}
+	change := price - r.PrevClose
+	r.PrevClose = price
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+	if r.Count-1 <= r.Period {
+		r.AvgGain += gain / float64(r.Period)
+		r.AvgLoss += loss / float64(r.Period)
+		if r.Count-1 < r.Period {
+			return
+		}
+	} else {
+		r.AvgGain = (r.AvgGain*float64(r.Period-1) + gain) / float64(r.Period)
+		r.AvgLoss = (r.AvgLoss*float64(r.Period-1) + loss) / float64(r.Period)
+	}
+
+	if r.AvgLoss == 0 {
+		r.Value = 100
+	} else {
+		r.Value = 100 - 100/(1+r.AvgGain/r.AvgLoss)
+	}
+}
+
+// Ready cho biết đã đủ Period+1 giá để có RSI chưa
+func (r *RSIState) Ready() bool {
+	return r.Period > 0 && r.Count > r.Period
+}
+
+// MACDState tính MACD, signal và histogram tăng dần, cho cùng kết quả với MACDSeries
+type MACDState struct {
+	Fast      EMAState `json:"fast"`
+	Slow      EMAState `json:"slow"`
+	Signal    EMAState `json:"signal"`
+	MACD      float64  `json:"macd"`
+	Histogram float64  `json:"histogram"`
+}
+
+// NewMACDState tạo MACD rỗng với tham số mặc định (12, 26, 9)
+func NewMACDState() MACDState {
+	return MACDState{
+		Fast:   NewEMAState(models.MACD_FAST),
+		Slow:   NewEMAState(models.MACD_SLOW),
+		Signal: NewEMAState(models.MACD_SIGNAL),
+	}
+}
+
+// Update thêm một giá đóng cửa mới, signal chỉ bắt đầu nhận giá trị khi MACD đã có
+func (m *MACDState) Update(price float64) {
+	m.Fast.Update(price)
+	m.Slow.Update(price)
+	if !m.Fast.Ready() || !m.Slow.Ready() {
+		return
+	}
+	m.MACD = m.Fast.Value - m.Slow.Value
+	m.Signal.Update(m.MACD)
+	if m.Signal.Ready() {
+		m.Histogram = m.MACD - m.Signal.Value
+	}
+}
+
+// Ready cho biết đã có đủ MACD, signal và histogram chưa
+func (m *MACDState) Ready() bool {
+	return m.Signal.Ready()
+}
+
+// ATRState tính ATR làm mượt theo Wilder tăng dần, cho cùng kết quả với ATRSeries
+type ATRState struct {
+	Period    int     `json:"period"`
+	Count     int     `json:"count"` // Số nến đã nhận
+	PrevClose float64 `json:"prev_close"`
+	Value     float64 `json:"value"`
+}
+
+// NewATRState tạo ATR rỗng cho period
+func NewATRState(period int) ATRState {
+	return ATRState{Period: period}
+}
+
+// Update thêm một nến mới
+func (a *ATRState) Update(high, low, close float64) {
+	a.Count++
+	if a.Count == 1 {
+		a.PrevClose = close
+		return
+	}
+	trueRange := math.Max(high-low, math.Max(math.Abs(high-a.PrevClose), math.Abs(low-a.PrevClose)))
+	a.PrevClose = close
+	if a.Count-1 <= a.Period {
+		a.Value += trueRange / float64(a.Period)
+	} else {
+		a.Value = (a.Value*float64(a.Period-1) + trueRange) / float64(a.Period)
+	}
+}
+
+// Ready cho biết đã đủ Period+1 nến để có ATR chưa
+func (a *ATRState) Ready() bool {
+	return a.Period > 0 && a.Count > a.Period
+}
+
+// IndicatorValues là giá trị các chỉ báo tại một nến
+type IndicatorValues struct {
+	Close         float64 `json:"close"`
+	RSI           float64 `json:"rsi"`
+	EMA9          float64 `json:"ema9"`
+	EMA21         float64 `json:"ema21"`
+	EMA50         float64 `json:"ema50"`
+	MACD          float64 `json:"macd"`
+	MACDSignal    float64 `json:"macd_signal"`
+	MACDHistogram float64 `json:"macd_histogram"`
+	ATR           float64 `json:"atr"`
+}
+
+// IndicatorSet là trạng thái các chỉ báo của một chuỗi nến (symbol, interval), được cập nhật bằng
+// từng nến đã đóng theo đúng thứ tự. Toàn bộ struct được lưu thành JSON trong indicator_snapshots.
+type IndicatorSet struct {
+	Step         int64     `json:"step"`           // Độ dài một nến (ms)
+	LastOpenTime int64     `json:"last_open_time"` // open_time của nến đã đóng cuối cùng, 0 nếu chưa có
+	Close        float64   `json:"close"`
+	EMA9         EMAState  `json:"ema9"`
+	EMA21        EMAState  `json:"ema21"`
+	EMA50        EMAState  `json:"ema50"`
+	RSI          RSIState  `json:"rsi"`
+	MACD         MACDState `json:"macd"`
+	ATR          ATRState  `json:"atr"`
+}
+
+// NewIndicatorSet tạo trạng thái rỗng cho chuỗi nến có độ dài mỗi nến là step (ms)
+func NewIndicatorSet(step int64) *IndicatorSet {
+	return &IndicatorSet{
+		Step:  step,
+		EMA9:  NewEMAState(models.EMA_SHORT),
+		EMA21: NewEMAState(models.EMA_MEDIUM),
+		EMA50: NewEMAState(models.EMA_LONG),
+		RSI:   NewRSIState(models.RSI_PERIOD),
+		MACD:  NewMACDState(),
+		ATR:   NewATRState(models.ATR_PERIOD),
+	}
+}
+
+// Update cập nhật các chỉ báo bằng nến đã đóng tiếp theo. Nến phải nối tiếp ngay nến cuối đã nhận,
+// nếu bị đứt quãng thì trả về lỗi và trạng thái giữ nguyên.
+func (s *IndicatorSet) Update(k models.KlineData) error {
+	if s.LastOpenTime != 0 && k.OpenTime != s.LastOpenTime+s.Step {
+		return fmt.Errorf("nến %d không nối tiếp nến %d", k.OpenTime, s.LastOpenTime)
+	}
+	_, high, low, close, err := klineOHLC(k)
+	if err != nil {
+		return err
+	}
+	s.EMA9.Update(close)
+	s.EMA21.Update(close)
+	s.EMA50.Update(close)
+	s.RSI.Update(close)
+	s.MACD.Update(close)
+	s.ATR.Update(high, low, close)
+	s.Close = close
+	s.LastOpenTime = k.OpenTime
+	return nil
+}
+
+// Ready cho biết mọi chỉ báo đã đủ dữ liệu chưa
+func (s *IndicatorSet) Ready() bool {
+	return s.EMA9.Ready() && s.EMA21.Ready() && s.EMA50.Ready() && s.RSI.Ready() && s.MACD.Ready() && s.ATR.Ready()
+}
+
+// Values trả về giá trị các chỉ báo tại nến đã đóng cuối cùng
+func (s *IndicatorSet) Values() IndicatorValues {
+	return IndicatorValues{
+		Close:         s.Close,
+		RSI:           s.RSI.Value,
+		EMA9:          s.EMA9.Value,
+		EMA21:         s.EMA21.Value,
+		EMA50:         s.EMA50.Value,
+		MACD:          s.MACD.MACD,
+		MACDSignal:    s.MACD.Signal.Value,
+		MACDHistogram: s.MACD.Histogram,
+		ATR:           s.ATR.Value,
+	}
+}
+
+// Peek trả về giá trị các chỉ báo nếu nến current (thường là nến chưa đóng) được thêm vào, không đổi trạng thái
+func (s *IndicatorSet) Peek(current models.KlineData) (IndicatorValues, error) {
+	next := *s
+	if err := next.Update(current); err != nil {
+		return IndicatorValues{}, err
+	}
+	return next.Values(), nil
+}
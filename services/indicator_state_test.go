@@ -0,0 +1,57 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"chatbtc/models"
+)
+
+func TestIndicatorSetMatchesSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := make([]models.KlineData, 0, 200)
+	closes := make([]float64, 0, 200)
+	for i := 0; i < 200; i++ {
+		open := 100 + 10*math.Sin(float64(i)/7)
+		close := 100 + 10*math.Sin(float64(i+1)/7) + float64(i%5)
+		klines = append(klines, testKline(start.Add(time.Duration(i)*time.Hour), time.Hour, open, close))
+		closes = append(closes, close)
+	}
+
+	ta := NewTechnicalAnalysisService()
+	rsi := ta.RSISeries(closes, models.RSI_PERIOD)
+	ema9 := ta.EMASeries(closes, models.EMA_SHORT)
+	ema21 := ta.EMASeries(closes, models.EMA_MEDIUM)
+	ema50 := ta.EMASeries(closes, models.EMA_LONG)
+	macd, signal, histogram := ta.MACDSeries(closes)
+
+	set := NewIndicatorSet(time.Hour.Milliseconds())
+	for i, k := range klines {
+		if err := set.Update(k); err != nil {
+			t.Fatalf("Update %d: %v", i, err)
+		}
+		if !set.Ready() {
+			continue
+		}
+		atr, err := averageTrueRange(klines[:i+1], models.ATR_PERIOD)
+		if err != nil {
+			t.Fatalf("averageTrueRange %d: %v", i, err)
+		}
+		got := set.Values()
+		pairs := map[string][2]float64{
+			"close": {got.Close, closes[i]}, "rsi": {got.RSI, rsi[i]},
+			"ema9": {got.EMA9, ema9[i]}, "ema21": {got.EMA21, ema21[i]}, "ema50": {got.EMA50, ema50[i]},
+			"macd": {got.MACD, macd[i]}, "macd_signal": {got.MACDSignal, signal[i]},
+			"macd_histogram": {got.MACDHistogram, histogram[i]}, "atr": {got.ATR, atr},
+		}
+		for name, p := range pairs {
+			if math.IsNaN(p[1]) || math.Abs(p[0]-p[1]) > 1e-9 {
+				t.Fatalf("candle %d %s: incremental %v, series %v", i, name, p[0], p[1])
+			}
+		}
+	}
+	if !set.Ready() {
+		t.Fatal("indicator set never became ready")
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -13,66 +14,125 @@ import (
 )
 
 // TechnicalAnalysisService cung cấp các phương thức tính toán chỉ báo kỹ thuật
-type TechnicalAnalysisService struct{}
+type TechnicalAnalysisService struct {
+	engine *IndicatorEngine
+}
 
 // NewTechnicalAnalysisService tạo instance mới của service
 func NewTechnicalAnalysisService() *TechnicalAnalysisService {
 	return &TechnicalAnalysisService{}
 }
 
-// CalculateRSI tính toán chỉ báo RSI (Relative Strength Index)
-func (s *TechnicalAnalysisService) CalculateRSI(prices []float64, period int) float64 {
-	if len(prices) < period+1 {
-		return 0
-	}
+// SetIndicatorEngine cho phép lấy chỉ báo của nến cuối từ engine thay vì tính lại trên chuỗi giá
+func (s *TechnicalAnalysisService) SetIndicatorEngine(engine *IndicatorEngine) {
+	s.engine = engine
+}
 
-	gains := make([]float64, len(prices)-1)
-	losses := make([]float64, len(prices)-1)
+// latestIndicators trả về chỉ báo của nến cuối, lấy từ engine nếu engine đang theo dõi chuỗi nến này
+// (interval đã biến đổi như 4h/ha không được theo dõi), nếu không thì tính lại trên closePrices
+func (s *TechnicalAnalysisService) latestIndicators(symbol, interval string, klines []models.KlineData, closePrices []float64) IndicatorValues {
+	if values, ok := s.engine.Get(symbol, interval, klines[len(klines)-1]); ok {
+		return values
+	}
+	macd, macdSignal, macdHistogram := s.CalculateMACD(closePrices)
+	atr, _ := averageTrueRange(klines, models.ATR_PERIOD)
+	return IndicatorValues{
+		Close:         closePrices[len(closePrices)-1],
+		RSI:           s.CalculateRSI(closePrices, models.RSI_PERIOD),
+		EMA9:          s.CalculateEMA(closePrices, models.EMA_SHORT),
+		EMA21:         s.CalculateEMA(closePrices, models.EMA_MEDIUM),
+		EMA50:         s.CalculateEMA(closePrices, models.EMA_LONG),
+		MACD:          macd,
+		MACDSignal:    macdSignal,
+		MACDHistogram: macdHistogram,
+		ATR:           atr,
+	}
+}
 
-	// Tính gain và loss
-	for i := 1; i < len(prices); i++ {
-		change := prices[i] - prices[i-1]
-		if change > 0 {
-			gains[i-1] = change
-			losses[i-1] = 0
-		} else {
-			gains[i-1] = 0
-			losses[i-1] = -change
+// marketIndicators trả về nến thật và chỉ báo của nến thật cuối cùng. Khi klines chưa biến đổi (source nil)
+// thì chính là klines và values, nếu không thì tính trên source với interval gốc (bỏ hậu tố như /ha).
+func (s *TechnicalAnalysisService) marketIndicators(symbol, interval string, klines, source []models.KlineData, values IndicatorValues) ([]models.KlineData, IndicatorValues, error) {
+	if source == nil {
+		return klines, values, nil
+	}
+	var closePrices []float64
+	for _, kline := range source {
+		price, err := strconv.ParseFloat(kline.Close, 64)
+		if err != nil {
+			continue
 		}
+		closePrices = append(closePrices, price)
+	}
+	if len(closePrices) == 0 {
+		return nil, IndicatorValues{}, fmt.Errorf("không có nến gốc cho %s", symbol)
 	}
+	baseInterval, _, _ := strings.Cut(interval, "/")
+	return source, s.latestIndicators(symbol, baseInterval, source, closePrices), nil
+}
 
-	// Tính trung bình gain và loss
-	avgGain := s.calculateSMA(gains[len(gains)-period:], period)
-	avgLoss := s.calculateSMA(losses[len(losses)-period:], period)
+// CalculateRSI tính RSI (Relative Strength Index) của nến cuối, làm mượt theo Wilder trên toàn bộ chuỗi giá
+func (s *TechnicalAnalysisService) CalculateRSI(prices []float64, period int) float64 {
+	return lastValue(s.RSISeries(prices, period))
+}
 
-	if avgLoss == 0 {
-		return 100
+// RSISeries tính RSI cho từng nến theo cách làm mượt của Wilder (giống các sàn):
+// trung bình gain/loss đầu tiên là SMA của period thay đổi giá, sau đó avg = (avg*(period-1) + giá trị mới) / period.
+// period phần tử đầu chưa đủ dữ liệu được gán NaN.
+func (s *TechnicalAnalysisService) RSISeries(prices []float64, period int) []float64 {
+	series := nanSeries(len(prices))
+	if period <= 0 || len(prices) < period+1 {
+		return series
 	}
 
-	rs := avgGain / avgLoss
-	rsi := 100 - (100 / (1 + rs))
+	var avgGain, avgLoss float64
+	for i := 1; i < len(prices); i++ {
+		change := prices[i] - prices[i-1]
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		if i <= period {
+			avgGain += gain / float64(period)
+			avgLoss += loss / float64(period)
+			if i < period {
+				continue
+			}
+		} else {
+			avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+			avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		}
 
-	return rsi
+		if avgLoss == 0 {
+			series[i] = 100
+		} else {
+			series[i] = 100 - 100/(1+avgGain/avgLoss)
+		}
+	}
+	return series
 }
 
-// CalculateEMA tính toán Exponential Moving Average
+// CalculateEMA tính toán Exponential Moving Average của nến cuối
 func (s *TechnicalAnalysisService) CalculateEMA(prices []float64, period int) float64 {
-	if len(prices) < period {
-		return 0
+	return lastValue(s.EMASeries(prices, period))
+}
+
+// EMASeries tính EMA cho từng nến, EMA đầu tiên là SMA của period giá đầu. period-1 phần tử đầu là NaN.
+// Giá trị NaN ở đầu chuỗi đầu vào (ví dụ chuỗi MACD) được bỏ qua.
+func (s *TechnicalAnalysisService) EMASeries(prices []float64, period int) []float64 {
+	series := nanSeries(len(prices))
+	offset := 0
+	for offset < len(prices) && math.IsNaN(prices[offset]) {
+		offset++
+	}
+	if period <= 0 || len(prices)-offset < period {
+		return series
 	}
 
-	// Multiplier
 	multiplier := 2.0 / float64(period+1)
-
-	// Tính SMA đầu tiên làm EMA ban đầu
-	ema := s.calculateSMA(prices[:period], period)
-
-	// Tính EMA cho các giá trị còn lại
-	for i := period; i < len(prices); i++ {
+	ema := s.calculateSMA(prices[offset:offset+period], period)
+	series[offset+period-1] = ema
+	for i := offset + period; i < len(prices); i++ {
 		ema = (prices[i] * multiplier) + (ema * (1 - multiplier))
+		series[i] = ema
 	}
-
-	return ema
+	return series
 }
 
 // calculateSMA tính Simple Moving Average
@@ -89,29 +149,49 @@ func (s *TechnicalAnalysisService) calculateSMA(prices []float64, period int) fl
 	return sum / float64(len(prices))
 }
 
-// CalculateMACD tính toán MACD (Moving Average Convergence Divergence)
+// CalculateMACD tính toán MACD (Moving Average Convergence Divergence) của nến cuối: macd, signal, histogram
 func (s *TechnicalAnalysisService) CalculateMACD(prices []float64) (float64, float64, float64) {
-	if len(prices) < 26 {
+	if len(prices) < models.MACD_SLOW {
 		return 0, 0, 0
 	}
+	macd, signal, histogram := s.MACDSeries(prices)
+	return lastValue(macd), lastValue(signal), lastValue(histogram)
+}
 
-	ema12 := s.CalculateEMA(prices, 12)
-	ema26 := s.CalculateEMA(prices, 26)
-
-	macd := ema12 - ema26
+// MACDSeries tính MACD (EMA12 - EMA26), signal (EMA9 của MACD) và histogram cho từng nến trong O(n).
+// Các phần tử chưa đủ dữ liệu là NaN.
+func (s *TechnicalAnalysisService) MACDSeries(prices []float64) (macd, signal, histogram []float64) {
+	fast := s.EMASeries(prices, models.MACD_FAST)
+	slow := s.EMASeries(prices, models.MACD_SLOW)
+	macd = make([]float64, len(prices))
+	for i := range prices {
+		// NaN khi một trong hai EMA chưa có giá trị
+		macd[i] = fast[i] - slow[i]
+	}
 
-	// Tính signal line (EMA của MACD)
-	var macdValues []float64
-	for i := 26; i < len(prices); i++ {
-		ema12 := s.CalculateEMA(prices[:i+1], 12)
-		ema26 := s.CalculateEMA(prices[:i+1], 26)
-		macdValues = append(macdValues, ema12-ema26)
+	signal = s.EMASeries(macd, models.MACD_SIGNAL)
+	histogram = make([]float64, len(prices))
+	for i := range prices {
+		histogram[i] = macd[i] - signal[i]
 	}
+	return macd, signal, histogram
+}
 
-	signal := s.CalculateEMA(macdValues, 9)
-	histogram := macd - signal
+// nanSeries tạo chuỗi n phần tử NaN
+func nanSeries(n int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	return series
+}
 
-	return macd, signal, histogram
+// lastValue trả về phần tử cuối của chuỗi chỉ báo, 0 nếu chuỗi rỗng hoặc chưa đủ dữ liệu
+func lastValue(series []float64) float64 {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return 0
+	}
+	return series[len(series)-1]
 }
 
 // analyzeVolume phân tích volume dựa trên ratio so với SMA
@@ -173,8 +253,10 @@ func (s *TechnicalAnalysisService) analyzeVolume(klines []models.KlineData) mode
 	}
 }
 
-// AnalyzeCrypto phân tích crypto với dữ liệu kline
-func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.KlineData, interval string) (string, error) {
+// AnalyzeCrypto phân tích crypto với dữ liệu kline. klines là chuỗi nến dùng cho xu hướng và tín hiệu chỉ báo,
+// có thể đã biến đổi (Heikin-Ashi, Renko); source là nến thật tương ứng, dùng cho giá hiện tại, volume,
+// biến động và mức stop-loss. source nil nghĩa là klines là nến thật.
+func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines, source []models.KlineData, interval string, info models.Symbol) (string, error) {
 	if len(klines) == 0 {
 		return "", fmt.Errorf("không có dữ liệu cho %s", symbol)
 	}
@@ -194,14 +276,20 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 	}
 
 	// Tính các chỉ báo
-	currentPrice := closePrices[len(closePrices)-1]
-	rsi := s.CalculateRSI(closePrices, models.RSI_PERIOD)
-	ema9 := s.CalculateEMA(closePrices, models.EMA_SHORT)
-	ema21 := s.CalculateEMA(closePrices, models.EMA_MEDIUM)
-	ema50 := s.CalculateEMA(closePrices, models.EMA_LONG)
+	signalPrice := closePrices[len(closePrices)-1]
+	values := s.latestIndicators(symbol, interval, klines, closePrices)
+	rsi, ema9, ema21, ema50 := values.RSI, values.EMA9, values.EMA21, values.EMA50
+
+	market, marketValues, err := s.marketIndicators(symbol, interval, klines, source, values)
+	if err != nil {
+		return "", err
+	}
+	currentPrice := marketValues.Close
 
-	// Phân tích volume
-	volumeAnalysis := s.analyzeVolume(klines)
+	// Phân tích volume và biến động trên nến thật
+	volumeAnalysis := s.analyzeVolume(market)
+	volatility := s.analyzeVolatility(market)
+	oscillators := s.analyzeOscillators(klines)
 
 	analysis := models.TrendAnalysis{
 		Signals: make([]string, 0),
@@ -217,42 +305,37 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 	}
 
 	// Hệ thống 3 EMA
-	if currentPrice > ema9 && ema9 > ema21 && ema21 > ema50 {
+	if signalPrice > ema9 && ema9 > ema21 && ema21 > ema50 {
 		analysis.Direction = "bullish"
-		// Điều chỉnh strength dựa trên volume
+		// Khuyến nghị dựa trên volume
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			analysis.Strength = "strong"
 			analysis.Signals = append(analysis.Signals, "🚀 **STRONG BULLISH**: Giá > EMA9 > EMA21 > EMA50")
 			analysis.Signals = append(analysis.Signals, "✅ Tất cả EMA đều hướng lên và xếp chồng đúng thứ tự")
 			analysis.Signals = append(analysis.Signals, "🔥 Volume cao xác nhận xu hướng mạnh")
 			analysis.Recommendation = "🟢 **MUA/GIỮ** - Xu hướng tăng mạnh được xác nhận bởi volume"
 		} else {
-			analysis.Strength = "moderate"
 			analysis.Signals = append(analysis.Signals, "📈 **MODERATE BULLISH**: Giá > EMA9 > EMA21 > EMA50")
 			analysis.Signals = append(analysis.Signals, "✅ Tất cả EMA đều hướng lên và xếp chồng đúng thứ tự")
 			analysis.Signals = append(analysis.Signals, "⚠️ Volume thấp - Cần theo dõi thêm")
 			analysis.Recommendation = "🟡 **CẨN THẬN MUA** - Xu hướng tăng nhưng volume chưa xác nhận"
 		}
 
-	} else if currentPrice < ema9 && ema9 < ema21 && ema21 < ema50 {
+	} else if signalPrice < ema9 && ema9 < ema21 && ema21 < ema50 {
 		analysis.Direction = "bearish"
-		// Điều chỉnh strength dựa trên volume
+		// Khuyến nghị dựa trên volume
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			analysis.Strength = "strong"
 			analysis.Signals = append(analysis.Signals, "⚠️ **STRONG BEARISH**: Giá < EMA9 < EMA21 < EMA50")
 			analysis.Signals = append(analysis.Signals, "❌ Tất cả EMA đều hướng xuống và xếp chồng đúng thứ tự")
 			analysis.Signals = append(analysis.Signals, "🔥 Volume cao xác nhận xu hướng giảm mạnh")
 			analysis.Recommendation = "🔴 **BÁN/ĐỨNG NGOÀI** - Xu hướng giảm mạnh được xác nhận bởi volume"
 		} else {
-			analysis.Strength = "moderate"
 			analysis.Signals = append(analysis.Signals, "📉 **MODERATE BEARISH**: Giá < EMA9 < EMA21 < EMA50")
 			analysis.Signals = append(analysis.Signals, "❌ Tất cả EMA đều hướng xuống và xếp chồng đúng thứ tự")
 			analysis.Signals = append(analysis.Signals, "⚠️ Volume thấp - Cần theo dõi thêm")
 			analysis.Recommendation = "🟡 **CẨN THẬN BÁN** - Xu hướng giảm nhưng volume chưa xác nhận"
 		}
-	} else if currentPrice > ema9 && currentPrice > ema21 && ema21 > ema50 {
+	} else if signalPrice > ema9 && signalPrice > ema21 && ema21 > ema50 {
 		analysis.Direction = "bullish"
-		analysis.Strength = "moderate"
 		analysis.Signals = append(analysis.Signals, "📈 **MODERATE BULLISH**: Giá trên EMA9 và EMA21")
 
 		if ema9 > ema21 {
@@ -266,9 +349,8 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		} else {
 			analysis.Recommendation = "🟡 **CẨN THẬN MUA** - Thiếu volume chưa xác nhận"
 		}
-	} else if currentPrice < ema9 && currentPrice < ema21 && ema21 < ema50 {
+	} else if signalPrice < ema9 && signalPrice < ema21 && ema21 < ema50 {
 		analysis.Direction = "bearish"
-		analysis.Strength = "moderate"
 		analysis.Signals = append(analysis.Signals, "📉 **MODERATE BEARISH**: Giá dưới EMA9 và EMA21")
 
 		if ema9 < ema21 {
@@ -284,13 +366,12 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		}
 	} else {
 		analysis.Direction = "sideways"
-		analysis.Strength = "weak"
 		analysis.Signals = append(analysis.Signals, "↔️ **SIDEWAYS**: EMA bị xoắn, giá dao động")
 
 		// Check for potential breakout signals
-		if currentPrice > ema50 {
+		if signalPrice > ema50 {
 			analysis.Signals = append(analysis.Signals, "🟢 Giá vẫn trên EMA50 - Xu hướng tăng dài hạn")
-		} else if currentPrice < ema50 {
+		} else if signalPrice < ema50 {
 			analysis.Signals = append(analysis.Signals, "🔴 Giá dưới EMA50 - Xu hướng giảm dài hạn")
 		}
 		// Volume can signal upcoming breakout
@@ -302,6 +383,23 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		}
 	}
 
+	// Độ mạnh xu hướng theo ADX, +DI/−DI cho biết phe nào đang chiếm ưu thế
+	if adx, plusDI, minusDI, ok := s.analyzeTrendStrength(klines); ok {
+		analysis.ADX, analysis.PlusDI, analysis.MinusDI = adx, plusDI, minusDI
+		analysis.Strength = trendStrength(adx)
+		if adx < models.ADX_WEAK {
+			if analysis.Direction != "sideways" {
+				analysis.Signals = append(analysis.Signals, fmt.Sprintf("⚠️ ADX %.1f < %d - Xu hướng EMA chưa đủ lực, dễ bị nhiễu", adx, models.ADX_WEAK))
+			}
+		} else if plusDI > minusDI {
+			analysis.Signals = append(analysis.Signals, fmt.Sprintf("🟢 +DI > −DI (%.1f > %.1f) - Phe mua chiếm ưu thế", plusDI, minusDI))
+		} else {
+			analysis.Signals = append(analysis.Signals, fmt.Sprintf("🔴 −DI > +DI (%.1f > %.1f) - Phe bán chiếm ưu thế", minusDI, plusDI))
+		}
+	} else {
+		analysis.Strength = "unknown"
+	}
+
 	// Golden Cross: EMA9 crosses above EMA21 (in uptrend confirmed by EMA50)
 	if ema9 > ema21 && ema21 > ema50 {
 		if volumeAnalysis.VolumeStrength == "STRONG" || volumeAnalysis.VolumeStrength == "EXTREME" {
@@ -337,15 +435,27 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		}
 	}
 
+	// Bollinger squeeze/expansion báo trước hoặc xác nhận biến động mạnh
+	if volatility.Squeeze {
+		analysis.Signals = append(analysis.Signals, "🔒 **VOLATILITY SQUEEZE**: Bollinger co hẹp - Chuẩn bị biến động mạnh")
+	} else if volatility.Expansion {
+		analysis.Signals = append(analysis.Signals, "💥 **VOLATILITY EXPANSION**: Bollinger mở rộng - Biến động đang tăng mạnh")
+	}
+
 	// Tạo thông báo
 	message := fmt.Sprintf("📊 **Phân tích kỹ thuật %s (%s)**\n\n", strings.ToUpper(symbol), strings.ToUpper(interval))
-	message += fmt.Sprintf("💰 **Giá hiện tại:** $%s\n\n", utils.FormatPriceN(currentPrice, 4))
+	message += fmt.Sprintf("💰 **Giá hiện tại:** %s\n\n", formatAnalysisPrice(currentPrice, info))
 
 	// Block HỆ THỐNG 3 EMA
-	message += fmt.Sprintf("📈 **EMA 9:** $%s\n", utils.FormatPriceN(ema9, 4))
-	message += fmt.Sprintf("📊 **EMA 21:** $%s\n", utils.FormatPriceN(ema21, 4))
-	message += fmt.Sprintf("📉 **EMA 50:** $%s\n", utils.FormatPriceN(ema50, 4))
-	message += fmt.Sprintf("🎯 **Xu hướng:** %s (%s)\n\n", strings.ToUpper(analysis.Direction), strings.ToUpper(analysis.Strength))
+	message += fmt.Sprintf("📈 **EMA 9:** %s\n", formatAnalysisPrice(ema9, info))
+	message += fmt.Sprintf("📊 **EMA 21:** %s\n", formatAnalysisPrice(ema21, info))
+	message += fmt.Sprintf("📉 **EMA 50:** %s\n", formatAnalysisPrice(ema50, info))
+	message += fmt.Sprintf("🎯 **Xu hướng:** %s (%s)\n", strings.ToUpper(analysis.Direction), strings.ToUpper(analysis.Strength))
+	if analysis.Strength != "unknown" {
+		message += fmt.Sprintf("💪 **ADX(%d):** %.1f (+DI %.1f / −DI %.1f)\n\n", models.ADX_PERIOD, analysis.ADX, analysis.PlusDI, analysis.MinusDI)
+	} else {
+		message += fmt.Sprintf("💪 **ADX(%d):** Chưa đủ %d nến\n\n", models.ADX_PERIOD, 2*models.ADX_PERIOD)
+	}
 
 	// Block tín hiệu 3 EMA
 	message += "**✨ Tín hiệu:**\n"
@@ -363,6 +473,49 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		message += "- 🟡 RSI: Trung tính\n"
 	}
 
+	// Block biến động: Bollinger Bands, ATR, Keltner Channels
+	message += "\n**📏 BIẾN ĐỘNG:**\n"
+	if volatility.State != "" {
+		message += fmt.Sprintf("- Bollinger(%d, %.0f): %s / %s / %s\n", models.BB_PERIOD, models.BB_STDDEV,
+			formatAnalysisPrice(volatility.BBUpper, info), formatAnalysisPrice(volatility.BBMiddle, info), formatAnalysisPrice(volatility.BBLower, info))
+		message += fmt.Sprintf("- %%B: %.2f | Bandwidth: %.2f%%\n", volatility.PercentB, volatility.Bandwidth*100)
+		message += fmt.Sprintf("- ATR(%d): %s (%.2f%% giá)\n", models.ATR_PERIOD, formatAnalysisPrice(volatility.ATR, info), volatility.ATR/currentPrice*100)
+		message += fmt.Sprintf("- Keltner(%d, %.1f×ATR): %s / %s\n", models.KELTNER_PERIOD, models.KELTNER_ATR_MULT,
+			formatAnalysisPrice(volatility.KeltnerUpper, info), formatAnalysisPrice(volatility.KeltnerLower, info))
+		switch volatility.State {
+		case "squeeze":
+			message += "- 🔒 Squeeze: Biến động co hẹp, thường đi trước breakout\n"
+		case "expansion":
+			message += "- 💥 Expansion: Biến động mở rộng, xu hướng đang tăng tốc\n"
+		default:
+			message += "- 🟡 Biến động bình thường\n"
+		}
+		if volatility.PercentB > 1 {
+			message += "- 🔴 Giá đóng trên dải Bollinger trên\n"
+		} else if volatility.PercentB < 0 {
+			message += "- 🟢 Giá đóng dưới dải Bollinger dưới\n"
+		}
+	} else {
+		message += "- Không đủ dữ liệu để tính biến động\n"
+	}
+
+	// Block Stochastic & Stochastic RSI
+	message += "\n**🔁 STOCHASTIC:**\n"
+	if oscillators.Ready {
+		message += fmt.Sprintf("- Stoch(%d, %d, %d): %%K %.2f / %%D %.2f\n", models.STOCH_PERIOD, models.STOCH_SMOOTH, models.STOCH_D_PERIOD,
+			oscillators.StochK, oscillators.StochD)
+		message += fmt.Sprintf("- StochRSI(%d, %d, %d, %d): %%K %.2f / %%D %.2f\n", models.STOCH_RSI_PERIOD, models.STOCH_PERIOD, models.STOCH_SMOOTH, models.STOCH_D_PERIOD,
+			oscillators.StochRSIK, oscillators.StochRSID)
+		for _, condition := range oscillators.Conditions {
+			message += fmt.Sprintf("- %s\n", describeOscillatorCondition(condition))
+		}
+		if len(oscillators.Conditions) == 0 {
+			message += "- 🟡 Stochastic & StochRSI: Trung tính\n"
+		}
+	} else {
+		message += "- Không đủ dữ liệu để tính Stochastic\n"
+	}
+
 	// Block khuyến nghị tổng hợp
 	message += "\n**💡 KHUYẾN NGHỊ TỔNG HỢP:**\n"
 	message += fmt.Sprintf("- %s\n", analysis.Recommendation)
@@ -385,14 +538,14 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 	// Block quản lý rủi ro
 	message += "\n**⚠️ QUẢN LÝ RỦI RO:**\n"
 	if analysis.Direction == "bullish" {
-		message += fmt.Sprintf("• Stop-loss: Dưới EMA21 (~$%s)\n", utils.FormatPriceN(ema21, 4))
+		message += fmt.Sprintf("• Stop-loss: Dưới EMA21 (~%s)\n", formatAnalysisPrice(marketValues.EMA21, info))
 		if volumeAnalysis.VolumeStrength == "STRONG" || volumeAnalysis.VolumeStrength == "EXTREME" {
 			message += "• Take-profit: Aggressive targets (volume support)\n"
 		} else {
 			message += "• Take-profit: Conservative targets (thiếu volume)\n"
 		}
 	} else if analysis.Direction == "bearish" {
-		message += fmt.Sprintf("• Stop-loss: Trên EMA21 (~$%s)\n", utils.FormatPriceN(ema21, 4))
+		message += fmt.Sprintf("• Stop-loss: Trên EMA21 (~%s)\n", formatAnalysisPrice(marketValues.EMA21, info))
 		if volumeAnalysis.VolumeStrength == "STRONG" || volumeAnalysis.VolumeStrength == "EXTREME" {
 			message += "• Target: Aggressive shorts (volume support)\n"
 		} else {
@@ -400,7 +553,7 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 		}
 	} else {
 		message += "• Chờ breakout khỏi vùng tích luỹ\n"
-		message += fmt.Sprintf("• Theo dõi: EMA50 ($%s)\n", utils.FormatPriceN(ema50, 4))
+		message += fmt.Sprintf("• Theo dõi: EMA50 (%s)\n", formatAnalysisPrice(marketValues.EMA50, info))
 		if volumeAnalysis.VolumeStrength == "STRONG" || volumeAnalysis.VolumeStrength == "EXTREME" {
 			message += "• ⚡ Volume cao = Breakout sắp diễn ra!\n"
 		}
@@ -425,6 +578,19 @@ func (s *TechnicalAnalysisService) AnalyzeCrypto(symbol string, klines []models.
 	return message, nil
 }
 
+// formatAnalysisPrice format giá theo tick size và quote asset của symbol.
+// Symbol chưa có tick size thì giữ 4 chữ số thập phân như trước.
+func formatAnalysisPrice(value float64, info models.Symbol) string {
+	formatted := utils.FormatPriceN(value, 4)
+	if info.TickSize != "" {
+		formatted = utils.FormatPriceTick(decimal.NewFromFloat(value), info.TickSize)
+	}
+	if info.QuoteAsset == "" || IsUSDQuote(info.QuoteAsset) {
+		return "$" + formatted
+	}
+	return formatted + " " + info.QuoteAsset
+}
+
 // AnalyzeSignals phân tích tín hiệu từ các chỉ báo (giữ lại cho backward compatibility)
 func (s *TechnicalAnalysisService) AnalyzeSignals(indicators models.TechnicalIndicators) []string {
 	var signals []string
@@ -488,8 +654,8 @@ func (s *TechnicalAnalysisService) AssessRisk(indicators models.TechnicalIndicat
 	}
 }
 
-// GetAnalysisData trả về dữ liệu phân tích chi tiết
-func (s *TechnicalAnalysisService) GetAnalysisData(symbol string, klines []models.KlineData, interval string) (*models.AnalysisData, error) {
+// GetAnalysisData trả về dữ liệu phân tích chi tiết, klines và source có cùng ý nghĩa như AnalyzeCrypto
+func (s *TechnicalAnalysisService) GetAnalysisData(symbol string, klines, source []models.KlineData, interval string) (*models.AnalysisData, error) {
 	if len(klines) == 0 {
 		return nil, fmt.Errorf("không có dữ liệu cho %s", symbol)
 	}
@@ -509,19 +675,25 @@ func (s *TechnicalAnalysisService) GetAnalysisData(symbol string, klines []model
 	}
 
 	// Tính các chỉ báo
-	currentPrice := closePrices[len(closePrices)-1]
-	rsi := s.CalculateRSI(closePrices, models.RSI_PERIOD)
-	ema9 := s.CalculateEMA(closePrices, models.EMA_SHORT)
-	ema21 := s.CalculateEMA(closePrices, models.EMA_MEDIUM)
-	ema50 := s.CalculateEMA(closePrices, models.EMA_LONG)
-	macd, macdSignal, _ := s.CalculateMACD(closePrices)
+	signalPrice := closePrices[len(closePrices)-1]
+	values := s.latestIndicators(symbol, interval, klines, closePrices)
+	rsi, ema9, ema21, ema50 := values.RSI, values.EMA9, values.EMA21, values.EMA50
+	macd, macdSignal := values.MACD, values.MACDSignal
+
+	market, marketValues, err := s.marketIndicators(symbol, interval, klines, source, values)
+	if err != nil {
+		return nil, err
+	}
+	currentPrice := marketValues.Close
 
-	// Phân tích volume
-	volumeAnalysis := s.analyzeVolume(klines)
+	// Phân tích volume và biến động trên nến thật
+	volumeAnalysis := s.analyzeVolume(market)
+	volatility := s.analyzeVolatility(market)
+	oscillators := s.analyzeOscillators(klines)
 
 	// Tính volume SMA
 	var volumes []float64
-	for _, k := range klines {
+	for _, k := range market {
 		v, err := strconv.ParseFloat(k.Volume, 64)
 		if err != nil {
 			continue
@@ -539,51 +711,52 @@ func (s *TechnicalAnalysisService) GetAnalysisData(symbol string, klines []model
 
 	// Xác định trend và signal
 	trend := "sideways"
-	power := "moderate"
+	power := "unknown"
 	signal := "neutral"
 	recommendation := "watch"
 	volumeSignal := "normal"
 
-	if currentPrice > ema9 && ema9 > ema21 && ema21 > ema50 {
+	if signalPrice > ema9 && ema9 > ema21 && ema21 > ema50 {
 		trend = "bullish"
 		signal = "buy"
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			power = "strong"
 			recommendation = "strong_buy"
 		} else {
 			recommendation = "cautious_buy"
 		}
-	} else if currentPrice < ema9 && ema9 < ema21 && ema21 < ema50 {
+	} else if signalPrice < ema9 && ema9 < ema21 && ema21 < ema50 {
 		trend = "bearish"
 		signal = "sell"
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			power = "strong"
 			recommendation = "strong_sell"
 		} else {
 			recommendation = "cautious_sell"
 		}
-	} else if currentPrice > ema9 && currentPrice > ema21 && ema21 > ema50 {
+	} else if signalPrice > ema9 && signalPrice > ema21 && ema21 > ema50 {
 		trend = "bullish"
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
 			recommendation = "strong_buy"
 		} else {
 			recommendation = "cautious_buy"
 		}
-	} else if currentPrice < ema9 && currentPrice < ema21 && ema21 < ema50 {
+	} else if signalPrice < ema9 && signalPrice < ema21 && ema21 < ema50 {
 		trend = "bearish"
 		if volumeAnalysis.VolumeStrength == "EXTREME" || volumeAnalysis.VolumeStrength == "STRONG" {
-			power = "strong"
 			recommendation = "strong_sell"
 		} else {
 			recommendation = "cautious_sell"
 		}
 	} else {
 		trend = "sideways"
-		power = "weak"
 		signal = "consolidation"
 		recommendation = "watch"
 	}
 
+	// Độ mạnh xu hướng theo ADX
+	if adx, _, _, ok := s.analyzeTrendStrength(klines); ok {
+		power = trendStrength(adx)
+	}
+
 	// Volume signal mapping
 	switch volumeAnalysis.VolumeSignal {
 	case "🔥 VOLUME EXPLOSION":
@@ -615,5 +788,8 @@ func (s *TechnicalAnalysisService) GetAnalysisData(symbol string, klines []model
 		Recommendation: recommendation,
 		VolumeSignal:   volumeSignal,
 		VolumeAnalysis: volumeAnalysis,
+
+		VolatilityAnalysis: volatility,
+		OscillatorAnalysis: oscillators,
 	}, nil
 }
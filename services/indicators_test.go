@@ -0,0 +1,32 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRSISeriesWilderReference(t *testing.T) {
+	// Bảng RSI14 mẫu theo phương pháp Wilder (StockCharts). Bảng gốc làm tròn trung bình lãi/lỗ
+	// đến 2 chữ số nên lệch tối đa khoảng 0.07 điểm
+	closes := []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28,
+		46.28, 46.00, 46.03, 46.41, 46.22, 45.64, 46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18,
+		44.22, 44.57, 43.42, 42.66, 43.13,
+	}
+	published := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+
+	rsi := NewTechnicalAnalysisService().RSISeries(closes, 14)
+	for i := 0; i < 14; i++ {
+		if !math.IsNaN(rsi[i]) {
+			t.Errorf("rsi[%d] = %v, want NaN before the first full period", i, rsi[i])
+		}
+	}
+	for j, want := range published {
+		if got := rsi[14+j]; math.Abs(got-want) > 0.1 {
+			t.Errorf("rsi[%d] = %.2f, want %.2f", 14+j, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"chatbtc/models"
+)
+
+// KlineQuarantine lưu các nến bị loại khi kiểm tra dữ liệu
+type KlineQuarantine interface {
+	CreateBatch(records []models.QuarantinedKline) error
+}
+
+// klineRejection là một dòng kline bị loại kèm lý do và payload gốc
+type klineRejection struct {
+	OpenTime int64
+	Reason   string
+	Raw      string
+}
+
+// decodeKlines parse response /api/v3/klines thành KlineData, không dùng type assertion nên
+// không panic với payload sai định dạng. Dòng không hợp lệ hoặc open_time không tăng dần bị loại riêng.
+func decodeKlines(body []byte) ([]models.KlineData, []klineRejection, error) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, nil, fmt.Errorf("lỗi khi parse JSON: %v", err)
+	}
+
+	klines := make([]models.KlineData, 0, len(rows))
+	var rejected []klineRejection
+	var lastOpenTime int64 // open_time lớn nhất đã gặp, kể cả của nến bị loại
+	for i, raw := range rows {
+		kline, err := decodeKlineRow(raw)
+		if err == nil {
+			if i > 0 && kline.OpenTime <= lastOpenTime {
+				err = fmt.Errorf("open_time %d không tăng dần (nến trước: %d)", kline.OpenTime, lastOpenTime)
+			} else {
+				lastOpenTime = kline.OpenTime
+				err = kline.Validate()
+			}
+		}
+		if err != nil {
+			rejected = append(rejected, klineRejection{OpenTime: kline.OpenTime, Reason: err.Error(), Raw: string(raw)})
+			continue
+		}
+		klines = append(klines, kline)
+	}
+	return klines, rejected, nil
+}
+
+// decodeKlineRow parse một dòng kline dạng mảng [openTime, open, high, low, close, volume, closeTime, ...]
+func decodeKlineRow(raw json.RawMessage) (models.KlineData, error) {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return models.KlineData{}, fmt.Errorf("dòng kline không phải mảng: %v", err)
+	}
+	if len(fields) < 11 {
+		return models.KlineData{}, fmt.Errorf("dòng kline thiếu trường: có %d, cần ít nhất 11", len(fields))
+	}
+
+	var kline models.KlineData
+	var trades int64
+	targets := []struct {
+		name  string
+		index int
+		value interface{}
+	}{
+		{"open_time", 0, &kline.OpenTime},
+		{"open", 1, &kline.Open},
+		{"high", 2, &kline.High},
+		{"low", 3, &kline.Low},
+		{"close", 4, &kline.Close},
+		{"volume", 5, &kline.Volume},
+		{"close_time", 6, &kline.CloseTime},
+		{"quote volume", 7, &kline.QuoteAssetVolume},
+		{"number of trades", 8, &trades},
+		{"taker buy base volume", 9, &kline.TakerBuyBaseAssetVolume},
+		{"taker buy quote volume", 10, &kline.TakerBuyQuoteAssetVolume},
+	}
+	for _, target := range targets {
+		if err := json.Unmarshal(fields[target.index], target.value); err != nil {
+			return kline, fmt.Errorf("trường %s không hợp lệ: %s", target.name, fields[target.index])
+		}
+	}
+	kline.NumberOfTrades = int(trades)
+	if len(fields) > 11 {
+		// Trường cuối Binance đánh dấu là bỏ qua, không bắt buộc đúng kiểu
+		_ = json.Unmarshal(fields[11], &kline.Ignore)
+	}
+	return kline, nil
+}
+
+// quarantineKlines ghi log và lưu các nến bị loại nếu có quarantine store
+func quarantineKlines(store KlineQuarantine, source, symbol, interval string, rejected []klineRejection) {
+	if len(rejected) == 0 {
+		return
+	}
+	log.Printf("⚠️ Loại %d nến không hợp lệ %s %s (%s), ví dụ: %s", len(rejected), symbol, interval, source, rejected[0].Reason)
+	if store == nil {
+		return
+	}
+
+	records := make([]models.QuarantinedKline, 0, len(rejected))
+	for _, r := range rejected {
+		records = append(records, models.QuarantinedKline{
+			Source:     source,
+			Symbol:     symbol,
+			Interval:   interval,
+			OpenTime:   r.OpenTime,
+			Reason:     r.Reason,
+			RawPayload: r.Raw,
+		})
+	}
+	if err := store.CreateBatch(records); err != nil {
+		log.Printf("Lỗi lưu nến bị loại %s %s: %v", symbol, interval, err)
+	}
+}
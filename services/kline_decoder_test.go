@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"chatbtc/models"
+)
+
+// klineRow tạo một dòng kline hợp lệ dạng JSON của /api/v3/klines
+func klineRow(openTime int64, open, high, low, close string) string {
+	return fmt.Sprintf(`[%d,"%s","%s","%s","%s","10",%d,"1000",5,"5","500","0"]`, openTime, open, high, low, close, openTime+3599999)
+}
+
+func TestDecodeKlines(t *testing.T) {
+	const hour = int64(3600000)
+	tests := []struct {
+		name         string
+		rows         []string
+		wantAccepted []int64
+		wantRejected int
+	}{
+		{"valid rows", []string{klineRow(0, "100", "102", "99", "101"), klineRow(hour, "101", "103", "100", "102")}, []int64{0, hour}, 0},
+		{"high below low", []string{klineRow(0, "100", "98", "99", "99")}, nil, 1},
+		{"close outside range", []string{klineRow(0, "100", "102", "99", "105")}, nil, 1},
+		{"negative volume", []string{`[0,"100","102","99","101","-10",3599999,"1000",5,"5","500","0"]`}, nil, 1},
+		{"duplicate open time", []string{klineRow(hour, "100", "102", "99", "101"), klineRow(hour, "101", "103", "100", "102")}, []int64{hour}, 1},
+		{"open time going backwards", []string{klineRow(2*hour, "100", "102", "99", "101"), klineRow(hour, "101", "103", "100", "102"), klineRow(3*hour, "101", "103", "100", "102")}, []int64{2 * hour, 3 * hour}, 1},
+		{"row is not an array", []string{`"oops"`, klineRow(hour, "100", "102", "99", "101")}, []int64{hour}, 1},
+		{"row missing fields", []string{`[0,"100","102"]`}, nil, 1},
+		{"number instead of string price", []string{`[0,100,"102","99","101","10",3599999,"1000",5,"5","500","0"]`}, nil, 1},
+		{"string open time", []string{`["0","100","102","99","101","10",3599999,"1000",5,"5","500","0"]`}, nil, 1},
+		{"null fields", []string{`[0,null,null,null,null,null,3599999,null,5,null,null,null]`}, nil, 1},
+		{"empty array", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "[" + strings.Join(tt.rows, ",") + "]"
+			klines, rejected, err := decodeKlines([]byte(body))
+			if err != nil {
+				t.Fatalf("decodeKlines: %v", err)
+			}
+			var openTimes []int64
+			for _, k := range klines {
+				openTimes = append(openTimes, k.OpenTime)
+			}
+			if fmt.Sprint(openTimes) != fmt.Sprint(tt.wantAccepted) || len(rejected) != tt.wantRejected {
+				t.Errorf("accepted %v, rejected %d %+v; want %v, %d", openTimes, len(rejected), rejected, tt.wantAccepted, tt.wantRejected)
+			}
+		})
+	}
+
+	for _, body := range []string{`{"code":-1121}`, `not json`, `[1,2`} {
+		if _, _, err := decodeKlines([]byte(body)); err == nil {
+			t.Errorf("decodeKlines(%s) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestBinanceClientQuarantinesRawPayload(t *testing.T) {
+	badRow := `[3600000,"100","98","99","99","10",7199999,"1000",5,"5","500","0"]`
+	body := "[" + klineRow(0, "100", "102", "99", "101") + "," + badRow + "]"
+	client := NewBinanceClient("https://api.binance.com", &http.Client{
+		Transport: &fakeRoundTripper{responses: []fakeResponse{{status: http.StatusOK, body: body}}},
+	})
+	quarantine := models.NewMemoryKlineQuarantineRepository()
+	client.SetQuarantine(quarantine)
+
+	klines, err := client.GetKlines("btcusdt", "1h", 2)
+	if err != nil {
+		t.Fatalf("GetKlines: %v", err)
+	}
+	if len(klines) != 1 || klines[0].OpenTime != 0 {
+		t.Errorf("klines = %+v, want only the valid candle", klines)
+	}
+
+	records, _ := quarantine.GetRecent(10)
+	if len(records) != 1 {
+		t.Fatalf("quarantined %d rows, want 1", len(records))
+	}
+	q := records[0]
+	// Lưu đúng dòng gốc trong response, không phải dữ liệu đã parse rồi marshal lại
+	if q.RawPayload != badRow || q.Source != "rest" || q.Symbol != "BTCUSDT" || q.Interval != "1h" || q.OpenTime != 3600000 {
+		t.Errorf("quarantined as %+v", q)
+	}
+}
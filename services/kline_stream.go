@@ -0,0 +1,315 @@
+package services
+
+import (
+	"chatbtc/config"
+	"chatbtc/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	maxStreamsPerConnection = 200              // Binance cho phép tối đa 1024 stream mỗi kết nối
+	streamSymbolRefresh     = 10 * time.Minute // Chu kỳ kiểm tra danh sách symbol thay đổi
+	streamReadTimeout       = 5 * time.Minute  // Không nhận được dữ liệu trong khoảng này thì kết nối lại
+	streamMinBackoff        = 1 * time.Second
+	streamMaxBackoff        = 2 * time.Minute
+)
+
+// klineStreamMessage là message từ combined stream của Binance
+type klineStreamMessage struct {
+	Stream string           `json:"stream"`
+	Data   klineStreamEvent `json:"data"`
+}
+
+// klineStreamEvent là payload của sự kiện <symbol>@kline_<interval>.
+// Binance dùng cả key chữ thường và chữ hoa (e/E, t/T...) nên phải khai báo đủ
+// để encoding/json không ghép nhầm key không phân biệt hoa thường.
+type klineStreamEvent struct {
+	EventType string         `json:"e"`
+	EventTime int64          `json:"E"`
+	Symbol    string         `json:"s"`
+	Kline     klineStreamBar `json:"k"`
+}
+
+type klineStreamBar struct {
+	OpenTime                 int64  `json:"t"`
+	CloseTime                int64  `json:"T"`
+	Symbol                   string `json:"s"`
+	Interval                 string `json:"i"`
+	FirstTradeID             int64  `json:"f"`
+	LastTradeID              int64  `json:"L"`
+	Open                     string `json:"o"`
+	Close                    string `json:"c"`
+	High                     string `json:"h"`
+	Low                      string `json:"l"`
+	Volume                   string `json:"v"`
+	NumberOfTrades           int    `json:"n"`
+	IsClosed                 bool   `json:"x"`
+	QuoteAssetVolume         string `json:"q"`
+	TakerBuyBaseAssetVolume  string `json:"V"`
+	TakerBuyQuoteAssetVolume string `json:"Q"`
+	Ignore                   string `json:"B"`
+}
+
+// toKlineData chuyển nến từ stream sang models.KlineData
+func (b klineStreamBar) toKlineData() models.KlineData {
+	return models.KlineData{
+		OpenTime:                 b.OpenTime,
+		Open:                     b.Open,
+		High:                     b.High,
+		Low:                      b.Low,
+		Close:                    b.Close,
+		Volume:                   b.Volume,
+		CloseTime:                b.CloseTime,
+		QuoteAssetVolume:         b.QuoteAssetVolume,
+		NumberOfTrades:           b.NumberOfTrades,
+		TakerBuyBaseAssetVolume:  b.TakerBuyBaseAssetVolume,
+		TakerBuyQuoteAssetVolume: b.TakerBuyQuoteAssetVolume,
+		Ignore:                   b.Ignore,
+	}
+}
+
+// KlineStreamService nhận nến qua WebSocket combined stream của Binance và
+// ghi các nến đã đóng vào AutoVolumeRecord thay cho việc poll REST mỗi giờ
+type KlineStreamService struct {
+	wsURL             string
+	interval          string
+	autoVolumeService *AutoVolumeService
+	symbolRepo        models.SymbolStore
+	quarantine        KlineQuarantine
+	cache             *CandleCache
+	stopChan          chan bool
+}
+
+// NewKlineStreamService tạo stream service với base URL WebSocket (ví dụ: wss://stream.binance.com:9443),
+// mọi cập nhật nến (kể cả nến chưa đóng) được nạp vào cache
+func NewKlineStreamService(wsURL string, autoVolumeService *AutoVolumeService, repos models.Repositories, cache *CandleCache) *KlineStreamService {
+	return &KlineStreamService{
+		wsURL:             strings.TrimSuffix(wsURL, "/"),
+		interval:          volumeInterval,
+		autoVolumeService: autoVolumeService,
+		symbolRepo:        repos.Symbol,
+		quarantine:        repos.KlineQuarantine,
+		cache:             cache,
+		stopChan:          make(chan bool),
+	}
+}
+
+// Start subscribe stream cho toàn bộ symbol và đăng ký lại khi danh sách symbol thay đổi
+func (s *KlineStreamService) Start() {
+	log.Println("Kline stream started")
+	ticker := time.NewTicker(streamSymbolRefresh)
+	defer ticker.Stop()
+
+	var current []string
+	var done chan struct{}
+	var wg sync.WaitGroup
+
+	stopConnections := func() {
+		if done != nil {
+			close(done)
+			wg.Wait()
+			done = nil
+		}
+	}
+
+	refresh := func() {
+		symbols, err := s.loadSymbols()
+		if err != nil {
+			log.Printf("Lỗi lấy danh sách symbol cho kline stream: %v", err)
+			return
+		}
+		if done != nil && slices.Equal(symbols, current) {
+			return
+		}
+
+		added := diffStrings(symbols, current)
+		log.Printf("Kline stream: đăng ký lại %d symbol (%d symbol mới)", len(symbols), len(added))
+		stopConnections()
+
+		// Nạp lịch sử qua REST cho symbol mới để đủ 22 nến khi phân tích
+		if len(added) > 0 {
+			go func() {
+				summary := s.autoVolumeService.FetchAndSaveSymbolsVolume(added)
+				log.Printf("Kline stream nạp dữ liệu ban đầu - %s", summary)
+			}()
+		}
+
+		current = symbols
+		done = make(chan struct{})
+		for start := 0; start < len(symbols); start += maxStreamsPerConnection {
+			end := start + maxStreamsPerConnection
+			if end > len(symbols) {
+				end = len(symbols)
+			}
+			wg.Add(1)
+			go func(chunk []string, done chan struct{}) {
+				defer wg.Done()
+				s.runConnection(chunk, done)
+			}(symbols[start:end], done)
+		}
+	}
+
+	refresh()
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-s.stopChan:
+			stopConnections()
+			log.Println("Kline stream stopped")
+			return
+		}
+	}
+}
+
+// Stop dừng tất cả kết nối WebSocket
+func (s *KlineStreamService) Stop() {
+	s.stopChan <- true
+}
+
+// loadSymbols lấy danh sách symbol đã sắp xếp để so sánh giữa các lần refresh
+func (s *KlineStreamService) loadSymbols() ([]string, error) {
+	symbols, err := s.symbolRepo.GetAllSymbols(config.AppConfig.QuoteAssets)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// runConnection giữ một kết nối cho nhóm symbol, tự kết nối lại với backoff cho đến khi done bị đóng
+func (s *KlineStreamService) runConnection(symbols []string, done chan struct{}) {
+	streams := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		streams = append(streams, fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), s.interval))
+	}
+	streamURL := fmt.Sprintf("%s/stream?streams=%s", s.wsURL, strings.Join(streams, "/"))
+
+	backoff := streamMinBackoff
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		received, err := s.consume(streamURL, done)
+		if received {
+			backoff = streamMinBackoff
+		}
+		if err != nil {
+			log.Printf("Kline stream (%d symbol) mất kết nối: %v. Kết nối lại sau %v", len(symbols), err, backoff)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// consume đọc message cho đến khi lỗi hoặc done bị đóng; received cho biết đã nhận được dữ liệu hay chưa
+func (s *KlineStreamService) consume(streamURL string, done chan struct{}) (received bool, err error) {
+	conn, err := websocket.Dial(streamURL, "", "http://localhost/")
+	if err != nil {
+		return false, err
+	}
+
+	// Đóng kết nối khi done bị đóng để ngắt lệnh Receive đang chờ
+	connDone := make(chan struct{})
+	defer close(connDone)
+	go func() {
+		select {
+		case <-done:
+			conn.Close()
+		case <-connDone:
+			conn.Close()
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		// Nhận message dạng byte rồi tự parse, một trường sai kiểu chỉ loại message đó chứ không ngắt kết nối
+		var raw []byte
+		if err := websocket.Message.Receive(conn, &raw); err != nil {
+			select {
+			case <-done:
+				return received, nil
+			default:
+				return received, err
+			}
+		}
+		received = true
+		s.handleMessage(raw)
+	}
+}
+
+// handleMessage xử lý một message từ stream, message không parse được hoặc nến đã đóng không hợp lệ
+// được đưa vào quarantine cùng nội dung gốc
+func (s *KlineStreamService) handleMessage(raw []byte) {
+	var msg klineStreamMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		quarantineKlines(s.quarantine, "stream", streamMessageSymbol(raw), s.interval, []klineRejection{{Reason: fmt.Sprintf("lỗi parse message: %v", err), Raw: string(raw)}})
+		return
+	}
+	if msg.Data.EventType != "kline" {
+		return
+	}
+	symbol := strings.ToUpper(msg.Data.Symbol)
+	kline := msg.Data.Kline.toKlineData()
+	if err := kline.Validate(); err != nil {
+		if msg.Data.Kline.IsClosed {
+			quarantineKlines(s.quarantine, "stream", symbol, s.interval, []klineRejection{{OpenTime: kline.OpenTime, Reason: err.Error(), Raw: string(raw)}})
+		}
+		return
+	}
+	s.cache.Put(symbol, s.interval, []models.KlineData{kline})
+	// Nến chưa đóng chỉ cập nhật cache
+	if !msg.Data.Kline.IsClosed {
+		return
+	}
+	if err := s.autoVolumeService.SaveClosedKline(symbol, kline); err != nil {
+		log.Printf("Lỗi lưu nến từ stream %s: %v", symbol, err)
+	}
+}
+
+// streamMessageSymbol lấy symbol từ tên stream (<symbol>@kline_<interval>) của message không parse được
+func streamMessageSymbol(raw []byte) string {
+	var msg struct {
+		Stream string `json:"stream"`
+	}
+	if json.Unmarshal(raw, &msg) != nil {
+		return ""
+	}
+	symbol, _, _ := strings.Cut(msg.Stream, "@")
+	return strings.ToUpper(symbol)
+}
+
+// diffStrings trả về các phần tử có trong a nhưng không có trong b
+func diffStrings(a, b []string) []string {
+	existing := make(map[string]bool, len(b))
+	for _, v := range b {
+		existing[v] = true
+	}
+	var result []string
+	for _, v := range a {
+		if !existing[v] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"chatbtc/models"
+)
+
+func TestKlineStreamHandleMessage(t *testing.T) {
+	avs, repos := newTestAutoVolumeService(t, newFakeExchange())
+	stream := NewKlineStreamService("", avs, repos, nil)
+
+	valid := `{"stream":"btcusdt@kline_1h","data":{"e":"kline","E":1700003600001,"s":"BTCUSDT","k":{"t":1700000000000,"T":1700003599999,"s":"BTCUSDT","i":"1h","o":"100","c":"101","h":"102","l":"99","v":"10","n":5,"x":true,"q":"1000","V":"5","Q":"500","B":"0"}}}`
+	badType := `{"stream":"ethusdt@kline_1h","data":{"e":"kline","s":"ETHUSDT","k":{"t":1700000000000,"o":"100","c":"101","h":"102","l":"99","n":"five","x":true}}}`
+	invalid := `{"stream":"solusdt@kline_1h","data":{"e":"kline","s":"SOLUSDT","k":{"t":1700000000000,"T":1700003599999,"o":"100","c":"101","h":"98","l":"99","v":"10","x":true,"q":"1000","V":"5","Q":"500"}}}`
+	openInvalid := `{"stream":"xrpusdt@kline_1h","data":{"e":"kline","s":"XRPUSDT","k":{"t":1700000000000,"o":"100","c":"101","h":"98","l":"99","x":false}}}`
+
+	for _, raw := range []string{valid, badType, invalid, openInvalid} {
+		stream.handleMessage([]byte(raw))
+	}
+
+	if records, _ := repos.AutoVolume.GetLastNBySymbol("BTCUSDT", 10); len(records) != 1 {
+		t.Errorf("saved %d BTCUSDT records, want 1", len(records))
+	}
+
+	quarantined, err := repos.KlineQuarantine.GetRecent(10)
+	if err != nil {
+		t.Fatalf("GetRecent: %v", err)
+	}
+	bySymbol := make(map[string]models.QuarantinedKline)
+	for _, q := range quarantined {
+		bySymbol[q.Symbol] = q
+	}
+	if len(quarantined) != 2 {
+		t.Fatalf("quarantined %d messages, want 2 (wrong type and invalid closed candle): %+v", len(quarantined), quarantined)
+	}
+	// Lưu đúng nội dung gốc nhận được, không phải dữ liệu đã parse rồi marshal lại
+	if q := bySymbol["ETHUSDT"]; q.RawPayload != badType || q.Source != "stream" {
+		t.Errorf("wrong-type message quarantined as %+v", q)
+	}
+	if q := bySymbol["SOLUSDT"]; q.RawPayload != invalid || q.OpenTime != 1700000000000 {
+		t.Errorf("invalid candle quarantined as %+v", q)
+	}
+}
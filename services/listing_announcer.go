@@ -0,0 +1,84 @@
+package services
+
+import (
+	"chatbtc/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// announceListingEvents gửi thông báo niêm yết/huỷ niêm yết lên channel cảnh báo
+func (s *FetcherService) announceListingEvents(events []models.SymbolHistory, symbols []models.Symbol) {
+	if s.notifier == nil {
+		return
+	}
+
+	symbolInfo := make(map[string]models.Symbol, len(symbols))
+	for _, symbol := range symbols {
+		symbolInfo[symbol.Symbol] = symbol
+	}
+
+	channelID := s.notifier.GetChannelID()
+	for _, event := range events {
+		info, exists := symbolInfo[event.Symbol]
+		if !exists {
+			// Symbol bị gỡ không còn trong exchangeInfo, lấy thông tin đã lưu
+			info = models.Symbol{Symbol: event.Symbol}
+			if stored, err := s.symbolRepo.GetBySymbol(event.Symbol); err == nil {
+				info = *stored
+			}
+		}
+		message, ok := formatListingAnnouncement(event, info)
+		if !ok {
+			continue
+		}
+		log.Printf("Thông báo thay đổi niêm yết %s: %s", event.Symbol, event.Event)
+		s.notifier.SendTelegramToChannel(channelID, message)
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// formatListingAnnouncement tạo nội dung thông báo cho một sự kiện symbol.
+// Trả về false nếu sự kiện không liên quan tới việc vào/ra trạng thái TRADING.
+func formatListingAnnouncement(event models.SymbolHistory, info models.Symbol) (string, bool) {
+	var title, note string
+	switch {
+	case event.Event == models.SymbolEventAdded && event.NewStatus == models.SymbolStatusTrading:
+		title = "🆕*[NEW LISTING]*"
+		note = "✅ Cặp giao dịch mới đã được mở trên Binance"
+	case event.Event == models.SymbolEventStatusChanged && event.NewStatus == models.SymbolStatusTrading:
+		title = "▶️*[TRADING RESUMED]*"
+		note = "✅ Cặp giao dịch đã được mở lại"
+	case event.OldStatus == models.SymbolStatusTrading && event.Event == models.SymbolEventRemoved:
+		title = "⛔*[DELISTING]*"
+		note = "🍎 Cặp giao dịch đã bị gỡ khỏi Binance"
+	case event.OldStatus == models.SymbolStatusTrading && event.Event == models.SymbolEventStatusChanged:
+		title = "⏸️*[TRADING HALTED]*"
+		note = "🍎 Cặp giao dịch đã ngừng giao dịch"
+	default:
+		return "", false
+	}
+
+	name := event.Symbol
+	if info.BaseAsset != "" && info.QuoteAsset != "" {
+		name = fmt.Sprintf("%s/%s", info.BaseAsset, info.QuoteAsset)
+	}
+	oldStatus := event.OldStatus
+	if oldStatus == "" {
+		oldStatus = "N/A"
+	}
+
+	loc := time.FixedZone("UTC+7", 7*60*60)
+	message := fmt.Sprintf("%s Symbol: *%s*\n"+
+		"📅 Time: %s\n"+
+		"📌 Status: %s → *%s*\n"+
+		"📊 %s",
+		title,
+		name,
+		event.CreatedAt.In(loc).Format("2006-01-02 15:04:05"),
+		oldStatus,
+		event.NewStatus,
+		note,
+	)
+	return message, true
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"chatbtc/models"
+)
+
+// Điều kiện dao động dùng cho /analyze và lọc cảnh báo (VOLUME_ALERT_CONDITIONS)
+const (
+	ConditionStochBullishCross    = "stoch_bullish_cross"    // %K cắt lên %D từ vùng quá bán
+	ConditionStochBearishCross    = "stoch_bearish_cross"    // %K cắt xuống %D từ vùng quá mua
+	ConditionStochOversold        = "stoch_oversold"         // %K dưới STOCH_OVERSOLD
+	ConditionStochOverbought      = "stoch_overbought"       // %K trên STOCH_OVERBOUGHT
+	ConditionStochRSIBullishCross = "stochrsi_bullish_cross" // %K StochRSI cắt lên %D từ vùng quá bán
+	ConditionStochRSIBearishCross = "stochrsi_bearish_cross" // %K StochRSI cắt xuống %D từ vùng quá mua
+	ConditionStochRSIOversold     = "stochrsi_oversold"      // %K StochRSI dưới STOCH_OVERSOLD
+	ConditionStochRSIOverbought   = "stochrsi_overbought"    // %K StochRSI trên STOCH_OVERBOUGHT
+)
+
+// oscillatorConditions là danh sách điều kiện hợp lệ theo thứ tự hiển thị
+var oscillatorConditions = []string{
+	ConditionStochBullishCross, ConditionStochBearishCross, ConditionStochOversold, ConditionStochOverbought,
+	ConditionStochRSIBullishCross, ConditionStochRSIBearishCross, ConditionStochRSIOversold, ConditionStochRSIOverbought,
+}
+
+// ParseOscillatorConditions đọc danh sách điều kiện phân tách bằng dấu phẩy, chuỗi rỗng trả về nil (không lọc)
+func ParseOscillatorConditions(raw string) ([]string, error) {
+	var conditions []string
+	for _, item := range strings.Split(raw, ",") {
+		condition := strings.ToLower(strings.TrimSpace(item))
+		if condition == "" {
+			continue
+		}
+		valid := false
+		for _, known := range oscillatorConditions {
+			if condition == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("điều kiện không hỗ trợ: %q (dùng %s)", condition, strings.Join(oscillatorConditions, ", "))
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// StochasticSeries tính Stochastic (%K, %D) cho từng nến: %K thô = 100 × (close - đáy) / (đỉnh - đáy) của period nến,
+// %K là SMA smoothK của %K thô, %D là SMA dPeriod của %K. Phần tử chưa đủ dữ liệu là NaN.
+func (s *TechnicalAnalysisService) StochasticSeries(highs, lows, closes []float64, period, smoothK, dPeriod int) (k, d []float64) {
+	raw := nanSeries(len(closes))
+	if period > 0 {
+		for i := period - 1; i < len(closes); i++ {
+			highest, lowest := math.Inf(-1), math.Inf(1)
+			for j := i - period + 1; j <= i; j++ {
+				highest = math.Max(highest, highs[j])
+				lowest = math.Min(lowest, lows[j])
+			}
+			switch {
+			case math.IsNaN(highest) || math.IsNaN(lowest) || math.IsNaN(closes[i]):
+				// Cửa sổ còn giá trị NaN (ví dụ phần đầu chuỗi RSI)
+			case highest > lowest:
+				raw[i] = 100 * (closes[i] - lowest) / (highest - lowest)
+			default:
+				// Giá đi ngang hoàn toàn, đặt ở giữa biên độ
+				raw[i] = 50
+			}
+		}
+	}
+	k = smaSeries(raw, smoothK)
+	d = smaSeries(k, dPeriod)
+	return k, d
+}
+
+// StochRSISeries tính Stochastic RSI: Stochastic áp dụng lên chuỗi RSISeries thay cho giá, cùng thang 0-100
+func (s *TechnicalAnalysisService) StochRSISeries(prices []float64, rsiPeriod, period, smoothK, dPeriod int) (k, d []float64) {
+	rsi := s.RSISeries(prices, rsiPeriod)
+	return s.StochasticSeries(rsi, rsi, rsi, period, smoothK, dPeriod)
+}
+
+// smaSeries tính SMA period cho từng phần tử, NaN nếu cửa sổ còn giá trị NaN
+func smaSeries(values []float64, period int) []float64 {
+	series := nanSeries(len(values))
+	if period <= 0 {
+		return series
+	}
+	for i := period - 1; i < len(values); i++ {
+		var sum float64
+		for _, value := range values[i-period+1 : i+1] {
+			sum += value
+		}
+		// Tổng có NaN thì kết quả là NaN
+		series[i] = sum / float64(period)
+	}
+	return series
+}
+
+// analyzeOscillators tính Stochastic và Stochastic RSI của nến cuối và các điều kiện đang khớp.
+// Điều kiện cắt chỉ tính khi %K cắt %D ở nến cuối và nến trước đó nằm trong vùng quá bán/quá mua.
+func (s *TechnicalAnalysisService) analyzeOscillators(klines []models.KlineData) models.OscillatorAnalysis {
+	highs, lows, closes := klineSeries(klines)
+	stochK, stochD := s.StochasticSeries(highs, lows, closes, models.STOCH_PERIOD, models.STOCH_SMOOTH, models.STOCH_D_PERIOD)
+	rsiK, rsiD := s.StochRSISeries(closes, models.STOCH_RSI_PERIOD, models.STOCH_PERIOD, models.STOCH_SMOOTH, models.STOCH_D_PERIOD)
+
+	last := len(closes) - 1
+	// Cần nến trước để xét cắt
+	if last < 1 || math.IsNaN(stochD[last-1]) || math.IsNaN(rsiD[last-1]) {
+		return models.OscillatorAnalysis{}
+	}
+
+	result := models.OscillatorAnalysis{
+		StochK:    stochK[last],
+		StochD:    stochD[last],
+		StochRSIK: rsiK[last],
+		StochRSID: rsiD[last],
+		Ready:     true,
+	}
+	result.Conditions = append(result.Conditions, oscillatorConditionsAt(stochK, stochD, last,
+		ConditionStochBullishCross, ConditionStochBearishCross, ConditionStochOversold, ConditionStochOverbought)...)
+	result.Conditions = append(result.Conditions, oscillatorConditionsAt(rsiK, rsiD, last,
+		ConditionStochRSIBullishCross, ConditionStochRSIBearishCross, ConditionStochRSIOversold, ConditionStochRSIOverbought)...)
+	return result
+}
+
+// oscillatorConditionsAt trả về các điều kiện (cắt lên, cắt xuống, quá bán, quá mua) khớp tại nến i
+func oscillatorConditionsAt(k, d []float64, i int, bullishCross, bearishCross, oversold, overbought string) []string {
+	var conditions []string
+	switch {
+	case k[i-1] <= d[i-1] && k[i] > d[i] && math.Min(k[i-1], d[i-1]) < models.STOCH_OVERSOLD:
+		conditions = append(conditions, bullishCross)
+	case k[i-1] >= d[i-1] && k[i] < d[i] && math.Max(k[i-1], d[i-1]) > models.STOCH_OVERBOUGHT:
+		conditions = append(conditions, bearishCross)
+	}
+	if k[i] < models.STOCH_OVERSOLD {
+		conditions = append(conditions, oversold)
+	} else if k[i] > models.STOCH_OVERBOUGHT {
+		conditions = append(conditions, overbought)
+	}
+	return conditions
+}
+
+// describeOscillatorCondition trả về mô tả điều kiện để hiển thị trong báo cáo và cảnh báo
+func describeOscillatorCondition(condition string) string {
+	switch condition {
+	case ConditionStochBullishCross:
+		return "🟢 Stochastic: %K cắt lên %D từ vùng quá bán"
+	case ConditionStochBearishCross:
+		return "🔴 Stochastic: %K cắt xuống %D từ vùng quá mua"
+	case ConditionStochOversold:
+		return "🟢 Stochastic: Quá bán"
+	case ConditionStochOverbought:
+		return "🔴 Stochastic: Quá mua"
+	case ConditionStochRSIBullishCross:
+		return "🟢 StochRSI: %K cắt lên %D từ vùng quá bán"
+	case ConditionStochRSIBearishCross:
+		return "🔴 StochRSI: %K cắt xuống %D từ vùng quá mua"
+	case ConditionStochRSIOversold:
+		return "🟢 StochRSI: Quá bán"
+	case ConditionStochRSIOverbought:
+		return "🔴 StochRSI: Quá mua"
+	}
+	return condition
+}
+
+// matchOscillatorConditions trả về các điều kiện trong wanted đang khớp
+func matchOscillatorConditions(analysis models.OscillatorAnalysis, wanted []string) []string {
+	var matched []string
+	for _, condition := range wanted {
+		for _, current := range analysis.Conditions {
+			if condition == current {
+				matched = append(matched, condition)
+				break
+			}
+		}
+	}
+	return matched
+}
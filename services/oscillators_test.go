@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStochasticSeries(t *testing.T) {
+	highs := []float64{10, 12, 14, 13, 13, 13}
+	lows := []float64{8, 9, 11, 10, 13, 13}
+	closes := []float64{9, 11, 13, 11, 13, 13}
+	k, d := NewTechnicalAnalysisService().StochasticSeries(highs, lows, closes, 3, 1, 2)
+
+	// Đỉnh/đáy cửa sổ: [8,14] -> 5/6, [9,14] -> 2/5, [10,14] -> 3/4, [10,13] -> 3/3
+	nan := math.NaN()
+	wantK := []float64{nan, nan, 100 * 5.0 / 6, 100 * 2.0 / 5, 100 * 3.0 / 4, 100}
+	wantD := []float64{nan, nan, nan, (wantK[2] + wantK[3]) / 2, (wantK[3] + wantK[4]) / 2, (wantK[4] + wantK[5]) / 2}
+	for i := range closes {
+		if !sameValue(k[i], wantK[i]) || !sameValue(d[i], wantD[i]) {
+			t.Errorf("index %d = %v/%v, want %v/%v", i, k[i], d[i], wantK[i], wantD[i])
+		}
+	}
+}
+
+func TestStochasticSeriesFlatWindow(t *testing.T) {
+	flat := repeatPrice(100, 5)
+	k, _ := NewTechnicalAnalysisService().StochasticSeries(flat, flat, flat, 3, 1, 1)
+	for i := 2; i < len(k); i++ {
+		if k[i] != 50 {
+			t.Errorf("k[%d] = %v, want 50 for a flat window", i, k[i])
+		}
+	}
+}
+
+func TestStochRSISeriesPropagatesRSIWarmUp(t *testing.T) {
+	prices := []float64{10, 11, 10, 12, 11, 13, 12, 14, 13, 15}
+	// RSI3 có từ index 3, Stochastic 3 nến từ index 5, làm mượt %K 2 -> 6, %D 2 -> 7
+	k, d := NewTechnicalAnalysisService().StochRSISeries(prices, 3, 3, 2, 2)
+	for i := range prices {
+		if got, want := math.IsNaN(k[i]), i < 6; got != want {
+			t.Errorf("k[%d] = %v, want NaN %v", i, k[i], want)
+		}
+		if got, want := math.IsNaN(d[i]), i < 7; got != want {
+			t.Errorf("d[%d] = %v, want NaN %v", i, d[i], want)
+		}
+	}
+}
+
+func TestOscillatorConditionsAt(t *testing.T) {
+	tests := []struct {
+		name string
+		k, d []float64
+		want []string
+	}{
+		{"bullish cross from oversold", []float64{10, 25}, []float64{15, 20}, []string{"bull"}},
+		{"bullish cross outside oversold", []float64{40, 55}, []float64{45, 50}, nil},
+		{"bearish cross from overbought", []float64{90, 75}, []float64{85, 78}, []string{"bear"}},
+		{"bearish cross outside overbought", []float64{60, 45}, []float64{55, 50}, nil},
+		{"oversold without cross", []float64{10, 12}, []float64{15, 14}, []string{"oversold"}},
+		{"bullish cross still oversold", []float64{5, 15}, []float64{8, 10}, []string{"bull", "oversold"}},
+		{"overbought without cross", []float64{90, 88}, []float64{85, 84}, []string{"overbought"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := oscillatorConditionsAt(tt.k, tt.d, 1, "bull", "bear", "oversold", "overbought")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conditions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOscillatorConditions(t *testing.T) {
+	got, err := ParseOscillatorConditions(" Stoch_Oversold, ,stochrsi_bullish_cross")
+	if err != nil {
+		t.Fatalf("ParseOscillatorConditions: %v", err)
+	}
+	if want := []string{ConditionStochOversold, ConditionStochRSIBullishCross}; !reflect.DeepEqual(got, want) {
+		t.Errorf("conditions = %v, want %v", got, want)
+	}
+	if got, err := ParseOscillatorConditions(""); err != nil || got != nil {
+		t.Errorf("empty = %v, %v; want nil, nil", got, err)
+	}
+	if _, err := ParseOscillatorConditions("stoch_oversold,macd_cross"); err == nil {
+		t.Error("expected an error for an unknown condition")
+	}
+}
+
+// sameValue so sánh hai số thực, coi hai giá trị NaN là bằng nhau
+func sameValue(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return almostEqual(a, b)
+}
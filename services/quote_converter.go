@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const quotePriceTTL = time.Minute // Thời gian cache giá quote asset theo USD
+
+// usdStablecoins được coi như 1 USD khi quy đổi
+var usdStablecoins = map[string]bool{
+	"USDT":  true,
+	"USDC":  true,
+	"FDUSD": true,
+	"TUSD":  true,
+	"BUSD":  true,
+	"DAI":   true,
+}
+
+type quotePrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// QuoteConverter quy đổi volume theo quote asset (BTC, FDUSD...) sang USD để so sánh giữa các cặp
+type QuoteConverter struct {
+	exchange ExchangeClient
+	mu       sync.Mutex
+	prices   map[string]quotePrice
+}
+
+// NewQuoteConverter tạo converter dùng ticker của sàn để lấy giá quote asset
+func NewQuoteConverter(exchange ExchangeClient) *QuoteConverter {
+	return &QuoteConverter{
+		exchange: exchange,
+		prices:   make(map[string]quotePrice),
+	}
+}
+
+// USDPrice trả về giá 1 đơn vị quote asset theo USD (qua cặp <QUOTE>USDT)
+func (c *QuoteConverter) USDPrice(quoteAsset string) (float64, error) {
+	quoteAsset = strings.ToUpper(quoteAsset)
+	if quoteAsset == "" || usdStablecoins[quoteAsset] {
+		return 1, nil
+	}
+
+	c.mu.Lock()
+	cached, ok := c.prices[quoteAsset]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < quotePriceTTL {
+		return cached.price, nil
+	}
+
+	ticker, err := c.exchange.GetTicker24h(quoteAsset + "USDT")
+	if err != nil {
+		return 0, fmt.Errorf("không lấy được giá %s/USDT: %v", quoteAsset, err)
+	}
+	price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("giá %s/USDT không hợp lệ: %q", quoteAsset, ticker.LastPrice)
+	}
+
+	c.mu.Lock()
+	c.prices[quoteAsset] = quotePrice{price: price, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return price, nil
+}
+
+// ToUSD quy đổi amount tính theo quote asset sang USD
+func (c *QuoteConverter) ToUSD(quoteAsset string, amount float64) (float64, error) {
+	price, err := c.USDPrice(quoteAsset)
+	if err != nil {
+		return 0, err
+	}
+	return amount * price, nil
+}
+
+// IsUSDQuote cho biết quote asset có được coi là USD hay không
+func IsUSDQuote(quoteAsset string) bool {
+	return usdStablecoins[strings.ToUpper(quoteAsset)]
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitSafetyRatio   = 0.9              // Chỉ dùng tối đa 90% weight mỗi phút
+	defaultRetryAfter      = 60 * time.Second // Thời gian chờ khi Binance không gửi Retry-After
+	rateLimitMaxRetries    = 3                // Số lần thử lại tối đa khi nhận 429
+	usedWeightHeader       = "X-MBX-USED-WEIGHT-1M"
+	defaultEndpointWeight  = 1
+	tickerAllSymbolsWeight = 80
+)
+
+// endpointWeights là request weight của các endpoint Binance mà bot sử dụng
+var endpointWeights = map[string]int{
+	"/api/v3/klines":       2,
+	"/api/v3/ticker/24hr":  2,
+	"/api/v3/exchangeInfo": 20,
+}
+
+// RateLimitBudget mô tả ngân sách request weight trong cửa sổ 1 phút hiện tại
+type RateLimitBudget struct {
+	UsedWeight     int            `json:"used_weight"`
+	Limit          int            `json:"limit"`
+	Remaining      int            `json:"remaining"`
+	WindowReset    time.Time      `json:"window_reset"`
+	BlockedUntil   time.Time      `json:"blocked_until,omitempty"`
+	EndpointWeight map[string]int `json:"endpoint_weight"`
+}
+
+// String format budget để ghi log
+func (b RateLimitBudget) String() string {
+	s := fmt.Sprintf("weight %d/%d (còn %d), reset lúc %s", b.UsedWeight, b.Limit, b.Remaining, b.WindowReset.Format("15:04:05"))
+	if !b.BlockedUntil.IsZero() {
+		s += fmt.Sprintf(", bị chặn đến %s", b.BlockedUntil.Format("15:04:05"))
+	}
+	return s
+}
+
+// RateLimitReporter được implement bởi các client có theo dõi rate limit
+type RateLimitReporter interface {
+	RateLimitBudget() RateLimitBudget
+}
+
+// RateLimitedTransport là http.RoundTripper dùng chung cho mọi request tới Binance.
+// Nó theo dõi weight theo endpoint, đồng bộ với header X-MBX-USED-WEIGHT-1M,
+// tạm dừng trước khi chạm giới hạn và tôn trọng Retry-After khi nhận 429/418.
+type RateLimitedTransport struct {
+	base  http.RoundTripper
+	limit int
+
+	mu             sync.Mutex
+	window         time.Time
+	usedWeight     int
+	blockedUntil   time.Time
+	endpointWeight map[string]int
+
+	// now và after là đồng hồ của transport (time.Now, time.After), được thay trong test
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+// NewRateLimitedTransport tạo transport với giới hạn weight mỗi phút (Binance spot mặc định 6000)
+func NewRateLimitedTransport(base http.RoundTripper, weightLimit int) *RateLimitedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RateLimitedTransport{
+		base:           base,
+		limit:          weightLimit,
+		endpointWeight: make(map[string]int),
+		now:            time.Now,
+		after:          time.After,
+	}
+}
+
+// RoundTrip thực hiện request sau khi đã giữ chỗ đủ weight
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	weight := requestWeight(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForBudget(req, weight); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.observe(resp)
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusTeapot {
+			return resp, nil
+		}
+
+		// 418 nghĩa là IP đã bị ban, không thử lại để tránh bị ban lâu hơn
+		if resp.StatusCode == http.StatusTeapot || attempt >= rateLimitMaxRetries || req.Body != nil {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// RateLimitBudget trả về ngân sách weight hiện tại
+func (t *RateLimitedTransport) RateLimitBudget() RateLimitBudget {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.rollWindow(now)
+
+	endpoints := make(map[string]int, len(t.endpointWeight))
+	for path, weight := range t.endpointWeight {
+		endpoints[path] = weight
+	}
+	budget := RateLimitBudget{
+		UsedWeight:     t.usedWeight,
+		Limit:          t.limit,
+		Remaining:      t.limit - t.usedWeight,
+		WindowReset:    t.window.Add(time.Minute),
+		EndpointWeight: endpoints,
+	}
+	if budget.Remaining < 0 {
+		budget.Remaining = 0
+	}
+	if now.Before(t.blockedUntil) {
+		budget.BlockedUntil = t.blockedUntil
+	}
+	return budget
+}
+
+// waitForBudget chờ đến khi giữ được weight cho request hoặc request bị huỷ
+func (t *RateLimitedTransport) waitForBudget(req *http.Request, weight int) error {
+	for {
+		wait := t.reserve(req.URL.Path, weight)
+		if wait <= 0 {
+			return nil
+		}
+		log.Printf("⏳ Rate limit Binance: tạm dừng %v trước khi gọi %s", wait.Round(time.Millisecond), req.URL.Path)
+
+		select {
+		case <-req.Context().Done():
+			return req.Context().Err()
+		case <-t.after(wait):
+		}
+	}
+}
+
+// reserve giữ chỗ weight nếu còn ngân sách, ngược lại trả về thời gian cần chờ
+func (t *RateLimitedTransport) reserve(path string, weight int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if now.Before(t.blockedUntil) {
+		return t.blockedUntil.Sub(now)
+	}
+	t.rollWindow(now)
+
+	threshold := int(float64(t.limit) * rateLimitSafetyRatio)
+	if t.usedWeight > 0 && t.usedWeight+weight > threshold {
+		return t.window.Add(time.Minute).Sub(now)
+	}
+
+	t.usedWeight += weight
+	t.endpointWeight[path] += weight
+	return 0
+}
+
+// observe cập nhật weight theo header của Binance và xử lý Retry-After
+func (t *RateLimitedTransport) observe(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.rollWindow(now)
+
+	if used, err := strconv.Atoi(resp.Header.Get(usedWeightHeader)); err == nil {
+		t.usedWeight = used
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		t.blockedUntil = now.Add(retryAfter)
+		log.Printf("🚫 Binance trả về %d, tạm dừng mọi request trong %v", resp.StatusCode, retryAfter)
+	}
+}
+
+// rollWindow reset bộ đếm khi sang phút mới (Binance tính weight theo từng phút)
+func (t *RateLimitedTransport) rollWindow(now time.Time) {
+	window := now.Truncate(time.Minute)
+	if window.After(t.window) {
+		t.window = window
+		t.usedWeight = 0
+		t.endpointWeight = make(map[string]int)
+	}
+}
+
+// requestWeight ước lượng weight của request theo endpoint
+func requestWeight(req *http.Request) int {
+	path := req.URL.Path
+	if path == "/api/v3/ticker/24hr" && req.URL.Query().Get("symbol") == "" {
+		return tickerAllSymbolsWeight
+	}
+	if weight, ok := endpointWeights[path]; ok {
+		return weight
+	}
+	return defaultEndpointWeight
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock là đồng hồ giả: after(d) tiến đồng hồ thêm d và trả về ngay
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waited []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waited = append(c.waited, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// fakeRoundTripper trả về lần lượt các response (status, header, body), response cuối được lặp lại
+type fakeRoundTripper struct {
+	responses []fakeResponse
+	calls     int
+}
+
+type fakeResponse struct {
+	status  int
+	headers map[string]string
+	body    string // rỗng thì trả về "{}"
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := f.responses[min(f.calls, len(f.responses)-1)]
+	f.calls++
+	header := make(http.Header)
+	for k, v := range r.headers {
+		header.Set(k, v)
+	}
+	body := r.body
+	if body == "" {
+		body = "{}"
+	}
+	return &http.Response{StatusCode: r.status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func newTestTransport(base http.RoundTripper, limit int) (*RateLimitedTransport, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)}
+	transport := NewRateLimitedTransport(base, limit)
+	transport.now = clock.Now
+	transport.after = clock.After
+	return transport, clock
+}
+
+func TestRateLimitedTransportReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		used     int
+		weight   int
+		wantWait bool
+	}{
+		{"first request always allowed", 0, 200, false},
+		{"below threshold", 80, 2, false},
+		{"reaches threshold", 88, 2, false},
+		{"passes 90 percent", 89, 2, true},
+		{"already at threshold", 90, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, clock := newTestTransport(&fakeRoundTripper{}, 100)
+			transport.rollWindow(clock.Now())
+			transport.usedWeight = tt.used
+
+			wait := transport.reserve("/api/v3/klines", tt.weight)
+			if !tt.wantWait {
+				if wait != 0 || transport.usedWeight != tt.used+tt.weight {
+					t.Errorf("reserve = %v, used %d; want no wait and used %d", wait, transport.usedWeight, tt.used+tt.weight)
+				}
+				return
+			}
+			// Chờ tới đầu phút sau, weight không bị giữ chỗ
+			if wait != 50*time.Second || transport.usedWeight != tt.used {
+				t.Errorf("reserve = %v, used %d; want 50s until the next window and used %d", wait, transport.usedWeight, tt.used)
+			}
+		})
+	}
+}
+
+func TestRateLimitedTransportRoundTrip(t *testing.T) {
+	tooMany := func(retryAfter int) fakeResponse {
+		return fakeResponse{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": strconv.Itoa(retryAfter)}}
+	}
+	tests := []struct {
+		name             string
+		url              string
+		responses        []fakeResponse
+		wantStatus       int
+		wantCalls        int
+		wantUsed         int // -1 là không kiểm tra
+		wantBlockedFor   time.Duration
+		wantEndpoint     string
+		wantEndpointUsed int
+	}{
+		{
+			name:       "used weight header updates usage",
+			url:        "https://api.binance.com/api/v3/klines?symbol=BTCUSDT",
+			responses:  []fakeResponse{{status: http.StatusOK, headers: map[string]string{usedWeightHeader: "1234"}}},
+			wantStatus: http.StatusOK, wantCalls: 1, wantUsed: 1234,
+			wantEndpoint: "/api/v3/klines", wantEndpointUsed: 2,
+		},
+		{
+			name:       "429 sets blockedUntil from Retry-After and retries",
+			url:        "https://api.binance.com/api/v3/klines?symbol=BTCUSDT",
+			responses:  []fakeResponse{tooMany(5), {status: http.StatusOK}},
+			wantStatus: http.StatusOK, wantCalls: 2, wantUsed: -1, wantBlockedFor: 5 * time.Second,
+		},
+		{
+			name:       "429 retried at most rateLimitMaxRetries times",
+			url:        "https://api.binance.com/api/v3/klines?symbol=BTCUSDT",
+			responses:  []fakeResponse{tooMany(1)},
+			wantStatus: http.StatusTooManyRequests, wantCalls: rateLimitMaxRetries + 1, wantUsed: -1,
+		},
+		{
+			name:       "418 is not retried",
+			url:        "https://api.binance.com/api/v3/klines?symbol=BTCUSDT",
+			responses:  []fakeResponse{{status: http.StatusTeapot, headers: map[string]string{"Retry-After": "120"}}, {status: http.StatusOK}},
+			wantStatus: http.StatusTeapot, wantCalls: 1, wantUsed: -1, wantBlockedFor: 120 * time.Second,
+		},
+		{
+			name:       "ticker for all symbols costs 80",
+			url:        "https://api.binance.com/api/v3/ticker/24hr",
+			responses:  []fakeResponse{{status: http.StatusOK}},
+			wantStatus: http.StatusOK, wantCalls: 1, wantUsed: 80,
+			wantEndpoint: "/api/v3/ticker/24hr", wantEndpointUsed: 80,
+		},
+		{
+			name:       "ticker for one symbol costs 2",
+			url:        "https://api.binance.com/api/v3/ticker/24hr?symbol=BTCUSDT",
+			responses:  []fakeResponse{{status: http.StatusOK}},
+			wantStatus: http.StatusOK, wantCalls: 1, wantUsed: 2,
+			wantEndpoint: "/api/v3/ticker/24hr", wantEndpointUsed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &fakeRoundTripper{responses: tt.responses}
+			transport, clock := newTestTransport(base, 6000)
+			start := clock.Now()
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || base.calls != tt.wantCalls {
+				t.Errorf("status %d after %d calls, want %d after %d", resp.StatusCode, base.calls, tt.wantStatus, tt.wantCalls)
+			}
+			if tt.wantUsed >= 0 && transport.usedWeight != tt.wantUsed {
+				t.Errorf("usedWeight = %d, want %d", transport.usedWeight, tt.wantUsed)
+			}
+			if tt.wantBlockedFor > 0 && !transport.blockedUntil.Equal(start.Add(tt.wantBlockedFor)) {
+				t.Errorf("blockedUntil = %v, want %v", transport.blockedUntil, start.Add(tt.wantBlockedFor))
+			}
+			if tt.wantEndpoint != "" && transport.endpointWeight[tt.wantEndpoint] != tt.wantEndpointUsed {
+				t.Errorf("endpoint weight %s = %d, want %d", tt.wantEndpoint, transport.endpointWeight[tt.wantEndpoint], tt.wantEndpointUsed)
+			}
+			// Mỗi lần thử lại sau 429 phải chờ hết Retry-After
+			if retries := base.calls - 1; tt.wantStatus != http.StatusTeapot && len(clock.waited) != retries {
+				t.Errorf("waited %d times for %d retries", len(clock.waited), retries)
+			}
+		})
+	}
+}
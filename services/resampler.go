@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const maxResampledInterval = 7 * 24 * time.Hour // Interval dựng lại lớn nhất, giới hạn số nến cơ sở phải tải
+
+// directIntervals là các interval lấy trực tiếp từ sàn, các interval khác được dựng lại từ nến 1m/1h
+var directIntervals = map[string]bool{
+	"1m": true, "3m": true, "5m": true, "15m": true, "30m": true,
+	"1h": true, "2h": true, "4h": true, "6h": true, "8h": true, "12h": true,
+	"1d": true, "3d": true, "1w": true,
+}
+
+// ValidateInterval kiểm tra interval có dùng được cho phân tích không (lấy trực tiếp hoặc dựng lại)
+func ValidateInterval(interval string) error {
+	if directIntervals[interval] {
+		return nil
+	}
+	_, _, err := resampleBase(interval)
+	return err
+}
+
+// resampleBase chọn interval cơ sở để dựng lại target: 1h nếu target chia hết cho 1h, ngược lại 1m
+func resampleBase(interval string) (string, time.Duration, error) {
+	target, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return "", 0, err
+	}
+	switch {
+	case target > maxResampledInterval:
+		return "", 0, fmt.Errorf("interval %s quá lớn, tối đa 7d", interval)
+	case target%time.Hour == 0:
+		return "1h", time.Hour, nil
+	case target%time.Minute == 0 && target > time.Minute:
+		return "1m", time.Minute, nil
+	default:
+		return "", 0, fmt.Errorf("interval %s phải là bội số của 1m", interval)
+	}
+}
+
+// ResampleKlines gộp các nến base (tăng dần, liên tiếp) thành nến target căn theo Unix epoch.
+// Nhóm chưa đủ nến chỉ được giữ khi là nhóm cuối cùng, chứa thời điểm now (nến target đang chạy)
+// và không bị thủng. Các nhóm bị bỏ (dữ liệu bắt đầu giữa chừng, thiếu nến) được trả về trong dropped.
+func ResampleKlines(klines []models.KlineData, base, target time.Duration, now time.Time) (result []models.KlineData, dropped []time.Time, err error) {
+	if base <= 0 || target < base || target%base != 0 {
+		return nil, nil, fmt.Errorf("không thể dựng nến %v từ nến %v", target, base)
+	}
+	perBucket := int(target / base)
+
+	for start := 0; start < len(klines); {
+		bucketStart := utils.AlignTime(time.UnixMilli(klines[start].OpenTime), target)
+		bucketEnd := bucketStart.Add(target).UnixMilli()
+		end := start
+		for end < len(klines) && klines[end].OpenTime < bucketEnd {
+			end++
+		}
+
+		bucket := klines[start:end]
+		// Nến base tăng dần và căn theo base nên nhóm liền mạch khi nến đầu và cuối khớp vị trí
+		contiguous := bucket[0].OpenTime == bucketStart.UnixMilli() &&
+			bucket[len(bucket)-1].OpenTime == bucketStart.Add(time.Duration(len(bucket)-1)*base).UnixMilli()
+		complete := contiguous && len(bucket) == perBucket
+		running := contiguous && end == len(klines) && !now.Before(bucketStart) && now.UnixMilli() < bucketEnd
+		if complete || running {
+			merged, err := mergeKlines(bucket, bucketStart, target)
+			if err != nil {
+				return nil, nil, err
+			}
+			result = append(result, merged)
+		} else {
+			dropped = append(dropped, bucketStart)
+		}
+		start = end
+	}
+	return result, dropped, nil
+}
+
+// mergeKlines gộp một nhóm nến thành một nến: open đầu, close cuối, high/low cực trị, volume cộng dồn
+func mergeKlines(bucket []models.KlineData, bucketStart time.Time, target time.Duration) (models.KlineData, error) {
+	var high, low decimal.Decimal
+	var volume, quoteVolume, takerBase, takerQuote decimal.Decimal
+	trades := 0
+	for i, k := range bucket {
+		values, err := parseDecimals(k.High, k.Low, k.Volume, k.QuoteAssetVolume, k.TakerBuyBaseAssetVolume, k.TakerBuyQuoteAssetVolume)
+		if err != nil {
+			return models.KlineData{}, fmt.Errorf("nến %d không hợp lệ: %v", k.OpenTime, err)
+		}
+		if i == 0 || values[0].GreaterThan(high) {
+			high = values[0]
+		}
+		if i == 0 || values[1].LessThan(low) {
+			low = values[1]
+		}
+		volume = volume.Add(values[2])
+		quoteVolume = quoteVolume.Add(values[3])
+		takerBase = takerBase.Add(values[4])
+		takerQuote = takerQuote.Add(values[5])
+		trades += k.NumberOfTrades
+	}
+
+	return models.KlineData{
+		OpenTime:                 bucketStart.UnixMilli(),
+		Open:                     bucket[0].Open,
+		High:                     high.String(),
+		Low:                      low.String(),
+		Close:                    bucket[len(bucket)-1].Close,
+		Volume:                   volume.String(),
+		CloseTime:                bucketStart.Add(target).UnixMilli() - 1,
+		QuoteAssetVolume:         quoteVolume.String(),
+		NumberOfTrades:           trades,
+		TakerBuyBaseAssetVolume:  takerBase.String(),
+		TakerBuyQuoteAssetVolume: takerQuote.String(),
+		Ignore:                   "0",
+	}, nil
+}
+
+// parseDecimals parse nhiều chuỗi số sang decimal
+func parseDecimals(values ...string) ([]decimal.Decimal, error) {
+	result := make([]decimal.Decimal, len(values))
+	for i, value := range values {
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("số không hợp lệ: %q", value)
+		}
+		result[i] = d
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResampleKlinesKeepsPartialBucketOnlyWhileRunning(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := testKlines(start, time.Hour, 9, risingPrice, nil) // 2 nến 4h đủ và 1 giờ của nến 4h thứ ba
+
+	result, dropped, err := ResampleKlines(klines, time.Hour, 4*time.Hour, start.Add(8*time.Hour+30*time.Minute))
+	if err != nil {
+		t.Fatalf("ResampleKlines: %v", err)
+	}
+	if len(result) != 3 || len(dropped) != 0 {
+		t.Fatalf("running bucket: got %d candles, dropped %v; want 3 candles", len(result), dropped)
+	}
+	if result[0].Open != "100" || result[0].Close != "104" || result[0].Volume != "40" {
+		t.Errorf("first 4h candle = %+v", result[0])
+	}
+
+	// Nến 4h cuối đã qua mà vẫn thiếu nến thì bị bỏ và được báo lại
+	result, dropped, err = ResampleKlines(klines, time.Hour, 4*time.Hour, start.Add(13*time.Hour))
+	if err != nil {
+		t.Fatalf("ResampleKlines: %v", err)
+	}
+	if len(result) != 2 || len(dropped) != 1 || !dropped[0].Equal(start.Add(8*time.Hour)) {
+		t.Errorf("stale partial bucket: got %d candles, dropped %v; want 2 candles and %v dropped", len(result), dropped, start.Add(8*time.Hour))
+	}
+}
+
+func TestResampleKlinesReportsBucketsWithHoles(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := testKlines(start, time.Hour, 12, risingPrice, nil)
+	klines = append(klines[:5], klines[6:]...) // thiếu nến 05:00
+
+	result, dropped, err := ResampleKlines(klines, time.Hour, 4*time.Hour, start.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("ResampleKlines: %v", err)
+	}
+	if len(result) != 2 || result[1].OpenTime != start.Add(8*time.Hour).UnixMilli() {
+		t.Errorf("got %d candles, want the 00:00 and 08:00 buckets", len(result))
+	}
+	if len(dropped) != 1 || !dropped[0].Equal(start.Add(4*time.Hour)) {
+		t.Errorf("dropped = %v, want [%v]", dropped, start.Add(4*time.Hour))
+	}
+}
+
+func TestNativeIntervalsAreNotResampled(t *testing.T) {
+	for _, interval := range []string{"3m", "2h", "6h", "8h", "12h", "3d"} {
+		if !directIntervals[interval] {
+			t.Errorf("%s should be fetched from the exchange", interval)
+		}
+	}
+	if err := ValidateInterval("10m"); err != nil {
+		t.Errorf("ValidateInterval(10m): %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"chatbtc/config"
+	"chatbtc/models"
+	"chatbtc/utils"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// minutesPerHour là số nến 1m cần có để gộp thành một nến 1h đầy đủ
+const minutesPerHour = 60
+
+// compactCheckpointInterval là interval của checkpoint lưu mốc đã gộp nến 1m của từng symbol, dùng chung
+// bảng backfill_checkpoints với start/end cố định tại epoch
+const compactCheckpointInterval = "compact_1m"
+
+// RetentionPolicy cấu hình thời gian giữ dữ liệu của từng bảng, 0 là giữ mãi
+type RetentionPolicy struct {
+	Analysis      time.Duration // analysis_records
+	Notifications time.Duration // notification_logs
+	Candles       time.Duration // price_histories, mọi interval
+	CompactMinute time.Duration // nến 1m cũ hơn được gộp thành 1h rồi xóa
+}
+
+// RetentionPolicyFromConfig đọc chính sách giữ dữ liệu từ cấu hình (đơn vị ngày)
+func RetentionPolicyFromConfig(cfg *config.Config) RetentionPolicy {
+	days := func(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+	return RetentionPolicy{
+		Analysis:      days(cfg.RetentionAnalysisDays),
+		Notifications: days(cfg.RetentionNotifyDays),
+		Candles:       days(cfg.RetentionCandleDays),
+		CompactMinute: days(cfg.CompactMinuteDays),
+	}
+}
+
+// RetentionReport là kết quả một lần dọn dữ liệu
+type RetentionReport struct {
+	StartedAt              time.Time `json:"started_at"`
+	Duration               string    `json:"duration"`
+	AnalysisDeleted        int64     `json:"analysis_deleted"`
+	NotificationsDeleted   int64     `json:"notifications_deleted"`
+	CandlesDeleted         int64     `json:"candles_deleted"`
+	SoftDeletedPurged      int64     `json:"soft_deleted_purged"`
+	MinuteCandlesCompacted int64     `json:"minute_candles_compacted"`
+	HourCandlesCreated     int       `json:"hour_candles_created"`
+	PartitionsCreated      []string  `json:"partitions_created,omitempty"`
+	PartitionsDropped      []string  `json:"partitions_dropped,omitempty"`
+	Errors                 []string  `json:"errors,omitempty"`
+}
+
+// String tóm tắt kết quả để ghi log
+func (r RetentionReport) String() string {
+	return fmt.Sprintf("analysis: -%d, notification: -%d, nến: -%d, xóa mềm: -%d, gộp 1m→1h: -%d/+%d, partition: +%d/-%d, lỗi: %d (%s)",
+		r.AnalysisDeleted, r.NotificationsDeleted, r.CandlesDeleted, r.SoftDeletedPurged,
+		r.MinuteCandlesCompacted, r.HourCandlesCreated, len(r.PartitionsCreated), len(r.PartitionsDropped),
+		len(r.Errors), r.Duration)
+}
+
+func (r *RetentionReport) addError(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Printf("⚠️ Retention: %s", message)
+	r.Errors = append(r.Errors, message)
+}
+
+// RetentionService xóa dữ liệu hết hạn và gộp nến 1m cũ thành nến 1h
+type RetentionService struct {
+	priceRepo           models.PriceHistoryStore
+	analysisRepo        models.AnalysisStore
+	notificationLogRepo models.NotificationLogStore
+	partitions          *models.PartitionManager
+	policy              RetentionPolicy
+
+	checkpointRepo models.BackfillCheckpointStore
+}
+
+// NewRetentionService tạo instance mới
+func NewRetentionService(repos models.Repositories, policy RetentionPolicy) *RetentionService {
+	return &RetentionService{
+		priceRepo:           repos.PriceHistory,
+		analysisRepo:        repos.Analysis,
+		notificationLogRepo: repos.NotificationLog,
+		policy:              policy,
+
+		checkpointRepo: repos.BackfillCheckpoint,
+	}
+}
+
+// SetPartitionManager bật bảo trì partition price_histories (tạo partition tháng tới, xóa partition hết hạn) mỗi lần chạy
+func (s *RetentionService) SetPartitionManager(partitions *models.PartitionManager) {
+	s.partitions = partitions
+}
+
+// Run chạy một lần dọn dữ liệu. Lỗi của từng bước được ghi vào report, các bước sau vẫn chạy tiếp.
+func (s *RetentionService) Run() RetentionReport {
+	now := time.Now()
+	report := RetentionReport{StartedAt: now}
+
+	series, err := s.priceRepo.ListSeries()
+	if err != nil {
+		report.addError("lỗi đọc danh sách chuỗi nến: %v", err)
+	}
+
+	// Gộp nến 1m trước khi áp dụng retention để nến 1h kịp được tạo
+	if s.policy.CompactMinute > 0 {
+		cutoff := utils.AlignTime(now.Add(-s.policy.CompactMinute), time.Hour)
+		for _, item := range series {
+			if item.Interval == "1m" {
+				s.compactMinuteCandles(item.Symbol, cutoff, &report)
+			}
+		}
+	}
+
+	// Xóa cả partition hết hạn nhanh hơn nhiều so với DELETE từng nến, phần còn lại do DeleteOldData xử lý
+	if s.partitions != nil {
+		partitions, err := s.partitions.Maintain(now, s.policy.Candles)
+		if err != nil {
+			report.addError("lỗi bảo trì partition: %v", err)
+		}
+		report.PartitionsCreated = partitions.Created
+		report.PartitionsDropped = partitions.Dropped
+	}
+
+	if s.policy.Candles > 0 {
+		cutoff := now.Add(-s.policy.Candles)
+		for _, item := range series {
+			deleted, err := s.priceRepo.DeleteOldData(item.Symbol, item.Interval, cutoff)
+			if err != nil {
+				report.addError("lỗi xóa nến cũ %s %s: %v", item.Symbol, item.Interval, err)
+				continue
+			}
+			report.CandlesDeleted += deleted
+		}
+	}
+
+	// Dọn các dòng đã xóa mềm trước đây, chúng không bao giờ được đọc lại
+	if purged, err := s.priceRepo.PurgeDeleted(); err != nil {
+		report.addError("lỗi dọn nến đã xóa mềm: %v", err)
+	} else {
+		report.SoftDeletedPurged += purged
+	}
+	if purged, err := s.analysisRepo.PurgeDeleted(); err != nil {
+		report.addError("lỗi dọn phân tích đã xóa mềm: %v", err)
+	} else {
+		report.SoftDeletedPurged += purged
+	}
+
+	if s.policy.Analysis > 0 {
+		deleted, err := s.analysisRepo.DeleteOlderThan(now.Add(-s.policy.Analysis))
+		if err != nil {
+			report.addError("lỗi xóa phân tích cũ: %v", err)
+		}
+		report.AnalysisDeleted = deleted
+	}
+
+	if s.policy.Notifications > 0 {
+		deleted, err := s.notificationLogRepo.DeleteOlderThan(now.Add(-s.policy.Notifications))
+		if err != nil {
+			report.addError("lỗi xóa log thông báo cũ: %v", err)
+		}
+		report.NotificationsDeleted = deleted
+	}
+
+	report.Duration = time.Since(now).Round(time.Millisecond).String()
+	return report
+}
+
+// compactMinuteCandles gộp nến 1m có open_time trước cutoff thành nến 1h, từng ngày một để giới hạn bộ nhớ.
+// Chỉ những giờ đủ 60 nến 1m và chưa có nến 1h mới được gộp. Nến 1m chỉ bị xóa ở những giờ đã có nến 1h
+// (vừa gộp hoặc có sẵn), giờ thiếu nến 1m được giữ nguyên cho tới khi hết hạn theo retention của kho nến.
+// Mốc đã xử lý được lưu vào checkpoint sau mỗi ngày, lần chạy sau bắt đầu từ mốc đó thay vì quét lại
+// từ nến 1m cũ nhất (thường là các giờ thiếu nến được giữ lại). Nến 1m ghi bù vào trước mốc sẽ không được gộp.
+func (s *RetentionService) compactMinuteCandles(symbol string, cutoff time.Time, report *RetentionReport) {
+	oldest, err := s.priceRepo.GetOldest(symbol, "1m")
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			report.addError("lỗi đọc nến 1m cũ nhất %s: %v", symbol, err)
+		}
+		return
+	}
+
+	epoch := time.Unix(0, 0).UTC()
+	checkpoint, err := s.checkpointRepo.GetOrCreate(symbol, compactCheckpointInterval, epoch, epoch)
+	if err != nil {
+		report.addError("lỗi đọc checkpoint gộp nến 1m %s: %v", symbol, err)
+		return
+	}
+
+	start := utils.AlignTime(oldest.OpenTime, 24*time.Hour)
+	if checkpoint.Cursor.After(start) {
+		start = checkpoint.Cursor
+	}
+	for ; start.Before(cutoff); start = checkpoint.Cursor {
+		end := utils.AlignTime(start, 24*time.Hour).Add(24 * time.Hour)
+		if end.After(cutoff) {
+			end = cutoff
+		}
+
+		created, covered, err := s.compactWindow(symbol, start, end)
+		if err != nil {
+			report.addError("lỗi gộp nến 1m %s ngày %s: %v", symbol, start.Format("2006-01-02"), err)
+			return
+		}
+		report.HourCandlesCreated += created
+
+		// Xóa theo từng đoạn giờ liền nhau đã có nến 1h
+		for i := 0; i < len(covered); {
+			j := i + 1
+			for j < len(covered) && covered[j] == covered[j-1]+time.Hour.Milliseconds() {
+				j++
+			}
+			from := time.UnixMilli(covered[i])
+			to := time.UnixMilli(covered[j-1]).Add(time.Hour)
+			deleted, err := s.priceRepo.DeleteRange(symbol, "1m", from, to)
+			if err != nil {
+				report.addError("lỗi xóa nến 1m đã gộp %s: %v", symbol, err)
+				return
+			}
+			report.MinuteCandlesCompacted += deleted
+			i = j
+		}
+
+		checkpoint.Cursor = end
+		if err := s.checkpointRepo.Save(checkpoint); err != nil {
+			report.addError("lỗi lưu checkpoint gộp nến 1m %s: %v", symbol, err)
+			return
+		}
+	}
+}
+
+// compactWindow gộp nến 1m trong [from, to) thành nến 1h. Trả về số nến 1h đã tạo và open_time (ms, tăng dần)
+// của các giờ có nến 1m đã có nến 1h tương ứng, tức nến 1m của những giờ này có thể xóa.
+func (s *RetentionService) compactWindow(symbol string, from, to time.Time) (int, []int64, error) {
+	minutes, err := s.priceRepo.GetRange(symbol, "1m", from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(minutes) == 0 {
+		return 0, nil, nil
+	}
+
+	hours, err := s.priceRepo.GetOpenTimes(symbol, "1h", from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	existing := make(map[int64]bool, len(hours))
+	for _, openTime := range hours {
+		existing[openTime.UnixMilli()] = true
+	}
+
+	buckets := make(map[int64][]models.KlineData)
+	var order []int64
+	for _, kline := range models.PriceHistoriesToKlines(minutes) {
+		hourStart := utils.AlignTime(time.UnixMilli(kline.OpenTime), time.Hour).UnixMilli()
+		if _, ok := buckets[hourStart]; !ok {
+			order = append(order, hourStart)
+		}
+		buckets[hourStart] = append(buckets[hourStart], kline)
+	}
+
+	var merged []models.KlineData
+	var covered []int64
+	for _, hourStart := range order {
+		bucket := buckets[hourStart]
+		if existing[hourStart] {
+			covered = append(covered, hourStart)
+			continue
+		}
+		if len(bucket) != minutesPerHour {
+			// Giờ thiếu nến 1m: không gộp và không xóa để tránh mất dữ liệu
+			continue
+		}
+		kline, err := mergeKlines(bucket, time.UnixMilli(hourStart), time.Hour)
+		if err != nil {
+			return 0, nil, err
+		}
+		merged = append(merged, kline)
+		covered = append(covered, hourStart)
+	}
+	if len(merged) > 0 {
+		if err := storeClosedKlines(s.priceRepo, symbol, "1h", merged); err != nil {
+			return 0, nil, err
+		}
+	}
+	return len(merged), covered, nil
+}
+
+// RetentionScheduler chạy dọn dữ liệu khi khởi động và sau mỗi interval
+type RetentionScheduler struct {
+	retentionService *RetentionService
+	interval         time.Duration
+	stopChan         chan bool
+
+	mu         sync.Mutex
+	lastReport *RetentionReport
+}
+
+// NewRetentionScheduler tạo scheduler mới
+func NewRetentionScheduler(retentionService *RetentionService, interval time.Duration) *RetentionScheduler {
+	return &RetentionScheduler{
+		retentionService: retentionService,
+		interval:         interval,
+		stopChan:         make(chan bool),
+	}
+}
+
+func (s *RetentionScheduler) Start() {
+	s.Run()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Run()
+		case <-s.stopChan:
+			log.Println("Retention scheduler stopped")
+			return
+		}
+	}
+}
+
+func (s *RetentionScheduler) Run() {
+	log.Println("🧹 Đang dọn dữ liệu cũ...")
+	report := s.retentionService.Run()
+	s.mu.Lock()
+	s.lastReport = &report
+	s.mu.Unlock()
+	log.Printf("🧹 Dọn dữ liệu xong - %s", report)
+}
+
+// LastReport trả về kết quả lần dọn gần nhất, nil nếu chưa chạy lần nào
+func (s *RetentionScheduler) LastReport() *RetentionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastReport
+}
+
+func (s *RetentionScheduler) Stop() {
+	s.stopChan <- true
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"chatbtc/models"
+	"chatbtc/utils"
+)
+
+func TestCompactMinuteCandlesKeepsIncompleteHours(t *testing.T) {
+	repos := models.NewMemoryRepositories()
+	start := utils.AlignTime(time.Now().Add(-10*24*time.Hour), 24*time.Hour).Add(2 * time.Hour)
+
+	// Giờ 0 đủ 60 nến, giờ 1 thiếu một nến, giờ 2 chỉ có 10 nến đầu: tổng 129 nến 1m
+	var minutes []models.KlineData
+	for i := 0; i < 130; i++ {
+		if i == 90 {
+			continue
+		}
+		openTime := start.Add(time.Duration(i) * time.Minute)
+		minutes = append(minutes, testKline(openTime, time.Minute, 100+float64(i), 101+float64(i)))
+	}
+	storeTestKlines(t, repos.PriceHistory, "BTCUSDT", "1m", minutes)
+
+	service := NewRetentionService(repos, RetentionPolicy{CompactMinute: 24 * time.Hour})
+	report := service.Run()
+	if len(report.Errors) > 0 {
+		t.Fatalf("Run errors: %v", report.Errors)
+	}
+	if report.HourCandlesCreated != 1 || report.MinuteCandlesCompacted != 60 {
+		t.Fatalf("created %d, compacted %d; want 1, 60", report.HourCandlesCreated, report.MinuteCandlesCompacted)
+	}
+
+	hours, _ := repos.PriceHistory.GetRange("BTCUSDT", "1h", start, start.Add(3*time.Hour))
+	if len(hours) != 1 || !hours[0].OpenTime.Equal(start) {
+		t.Fatalf("1h candles = %+v, want one at %v", hours, start)
+	}
+	if hours[0].Open != 100 || hours[0].Close != 160 || hours[0].Volume != 600 {
+		t.Errorf("1h candle open/close/volume = %v/%v/%v, want 100/160/600", hours[0].Open, hours[0].Close, hours[0].Volume)
+	}
+
+	// Nến 1m của giờ thiếu nến vẫn còn nguyên
+	remaining, _ := repos.PriceHistory.GetRange("BTCUSDT", "1m", start, start.Add(3*time.Hour))
+	if len(remaining) != 69 {
+		t.Fatalf("remaining 1m candles = %d, want 69", len(remaining))
+	}
+	if !remaining[0].OpenTime.Equal(start.Add(time.Hour)) {
+		t.Errorf("first remaining 1m candle at %v, want %v", remaining[0].OpenTime, start.Add(time.Hour))
+	}
+}
+
+func TestCompactMinuteCandlesDeletesHoursWithExistingHourCandle(t *testing.T) {
+	repos := models.NewMemoryRepositories()
+	start := utils.AlignTime(time.Now().Add(-10*24*time.Hour), 24*time.Hour)
+
+	minutes := testKlines(start, time.Minute, 30, func(int) (float64, float64) { return 100, 101 }, nil)
+	storeTestKlines(t, repos.PriceHistory, "ETHUSDT", "1m", minutes)
+	storeTestKlines(t, repos.PriceHistory, "ETHUSDT", "1h", []models.KlineData{testKline(start, time.Hour, 100, 101)})
+
+	report := NewRetentionService(repos, RetentionPolicy{CompactMinute: 24 * time.Hour}).Run()
+	if report.HourCandlesCreated != 0 || report.MinuteCandlesCompacted != 30 {
+		t.Fatalf("created %d, compacted %d; want 0, 30", report.HourCandlesCreated, report.MinuteCandlesCompacted)
+	}
+	if count, _ := repos.PriceHistory.GetCount("ETHUSDT", "1m"); count != 0 {
+		t.Errorf("1m candles left = %d, want 0", count)
+	}
+}
+
+// rangeCountingStore đếm số lần đọc nến 1m theo khoảng thời gian
+type rangeCountingStore struct {
+	models.PriceHistoryStore
+	minuteReads int
+}
+
+func (s *rangeCountingStore) GetRange(symbol, interval string, from, to time.Time) ([]models.PriceHistory, error) {
+	if interval == "1m" {
+		s.minuteReads++
+	}
+	return s.PriceHistoryStore.GetRange(symbol, interval, from, to)
+}
+
+func TestCompactMinuteCandlesResumesFromCheckpoint(t *testing.T) {
+	repos := models.NewMemoryRepositories()
+	store := &rangeCountingStore{PriceHistoryStore: repos.PriceHistory}
+	repos.PriceHistory = store
+	start := utils.AlignTime(time.Now().Add(-10*24*time.Hour), 24*time.Hour)
+
+	// Giờ thiếu nến được giữ lại nên luôn là nến 1m cũ nhất
+	minutes := testKlines(start, time.Minute, 30, func(int) (float64, float64) { return 100, 101 }, nil)
+	storeTestKlines(t, repos.PriceHistory, "BTCUSDT", "1m", minutes)
+
+	policy := RetentionPolicy{CompactMinute: 24 * time.Hour}
+	if report := NewRetentionService(repos, policy).Run(); len(report.Errors) > 0 {
+		t.Fatalf("Run errors: %v", report.Errors)
+	}
+	if store.minuteReads < 9 {
+		t.Fatalf("first run read %d windows, want one per day since the oldest candle", store.minuteReads)
+	}
+
+	// Lần chạy sau (kể cả service mới) không quét lại những ngày đã xử lý
+	store.minuteReads = 0
+	if report := NewRetentionService(repos, policy).Run(); len(report.Errors) > 0 {
+		t.Fatalf("Run errors: %v", report.Errors)
+	}
+	if store.minuteReads != 0 {
+		t.Errorf("second run read %d windows, want 0", store.minuteReads)
+	}
+	if count, _ := repos.PriceHistory.GetCount("BTCUSDT", "1m"); count != 30 {
+		t.Errorf("1m candles left = %d, want 30", count)
+	}
+}
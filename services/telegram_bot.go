@@ -2,6 +2,7 @@ package services
 
 import (
 	"chatbtc/config"
+	"chatbtc/models"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -14,21 +15,25 @@ import (
 	"chatbtc/utils"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
 	"golang.org/x/net/proxy"
 )
 
 // TelegramBotService quản lý bot Telegram
 type TelegramBotService struct {
-	bot        *tgbotapi.BotAPI
-	cryptoAPI  *CryptoAPIService
-	indicators *TechnicalAnalysisService
-	analysis   *AnalysisService
-	chatID     int64
-	channelID  string
+	bot            *tgbotapi.BotAPI
+	cryptoAPI      *CryptoAPIService
+	candles        *CandleService
+	indicators     *TechnicalAnalysisService
+	analysis       *AnalysisService
+	symbolRepo     models.SymbolStore
+	quoteConverter *QuoteConverter
+	chatID         int64
+	channelID      string
 }
 
-// NewTelegramBotService tạo instance mới của service
-func NewTelegramBotService() (*TelegramBotService, error) {
+// NewTelegramBotService tạo instance mới của service với các repository được truyền vào
+func NewTelegramBotService(exchange ExchangeClient, repos models.Repositories, cache *CandleCache) (*TelegramBotService, error) {
 	// Cấu hình proxy nếu được bật
 	var client *http.Client
 	if config.AppConfig.ProxyEnabled && config.AppConfig.ProxyURL != "" {
@@ -90,15 +95,24 @@ func NewTelegramBotService() (*TelegramBotService, error) {
 	}
 	channelID := "@yuealerts"
 	return &TelegramBotService{
-		bot:        bot,
-		cryptoAPI:  NewCryptoAPIService(),
-		indicators: NewTechnicalAnalysisService(),
-		analysis:   NewAnalysisService(),
-		chatID:     chatID,
-		channelID:  channelID,
+		bot:            bot,
+		cryptoAPI:      NewCryptoAPIService(exchange),
+		candles:        NewCandleService(exchange, repos.PriceHistory, cache),
+		indicators:     NewTechnicalAnalysisService(),
+		analysis:       NewAnalysisService(repos),
+		symbolRepo:     repos.Symbol,
+		quoteConverter: NewQuoteConverter(exchange),
+		chatID:         chatID,
+		channelID:      channelID,
 	}, nil
 }
 
+// SetIndicatorEngine cho /analyze lấy chỉ báo từ engine thay vì tính lại trên toàn bộ nến
+func (s *TelegramBotService) SetIndicatorEngine(engine *IndicatorEngine) {
+	s.candles.SetIndicatorEngine(engine)
+	s.indicators.SetIndicatorEngine(engine)
+}
+
 // StartBot khởi động bot
 func (s *TelegramBotService) StartBot() {
 	log.Println("🚀 Khởi động Crypto Analysis Bot...")
@@ -157,7 +171,17 @@ func (s *TelegramBotService) handleMessage(message *tgbotapi.Message) {
 		}
 		interval := parts[1]
 		symbol := strings.ToUpper(parts[2])
-		s.handleAnalyzeCommand(chatID, interval, symbol)
+		// Tham số thứ 3 (tuỳ chọn) là kiểu nến: ha, renko, renko:<box>
+		transformArg := ""
+		if len(parts) > 3 {
+			transformArg = parts[3]
+		}
+		transform, err := ParseCandleTransform(transformArg)
+		if err != nil {
+			s.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		s.handleAnalyzeCommand(chatID, interval, symbol, transform)
 	default:
 		s.sendMessage(chatID, "❌ Lệnh không hợp lệ. Gõ /help để xem danh sách lệnh.")
 	}
@@ -171,11 +195,32 @@ func (s *TelegramBotService) handlePriceCommand(chatID int64, symbol string) {
 		return
 	}
 
+	info := s.lookupSymbol(symbol)
+	quoteAsset := info.QuoteAsset
+
 	message := fmt.Sprintf("💰 **Giá %s**\n\n", strings.ToUpper(symbol))
-	message += fmt.Sprintf("💵 **Giá hiện tại:** $%s\n", utils.FormatPrice(price.CurrentPrice))
-	message += fmt.Sprintf("📈 **Thay đổi 24h:** %s (%s)\n",
-		utils.FormatPercentage(price.PriceChangePercentage24h), utils.FormatPrice(price.PriceChange24h))
-	message += fmt.Sprintf("📊 **Volume 24h:** $%s\n", utils.FormatVolume(price.Volume24h))
+	if IsUSDQuote(quoteAsset) {
+		message += fmt.Sprintf("💵 **Giá hiện tại:** $%s\n", utils.FormatPriceTick(price.CurrentPrice, info.TickSize))
+		message += fmt.Sprintf("📈 **Thay đổi 24h:** %s (%s)\n",
+			utils.FormatPercentage(price.PriceChangePercentage24h), utils.FormatPriceTick(price.PriceChange24h, info.TickSize))
+		message += fmt.Sprintf("📊 **Volume 24h:** $%s\n", utils.FormatVolume(price.Volume24h))
+	} else {
+		// Cặp không phải USD: hiển thị theo quote asset kèm giá trị quy đổi USD
+		message += fmt.Sprintf("💵 **Giá hiện tại:** %s %s", utils.FormatPriceTick(price.CurrentPrice, info.TickSize), quoteAsset)
+		if usdPrice, err := s.quoteConverter.USDPrice(quoteAsset); err == nil {
+			message += fmt.Sprintf(" (~$%s)", utils.FormatPrice(price.CurrentPrice.Mul(decimal.NewFromFloat(usdPrice))))
+		}
+		message += "\n"
+		message += fmt.Sprintf("📈 **Thay đổi 24h:** %s (%s %s)\n",
+			utils.FormatPercentage(price.PriceChangePercentage24h), utils.FormatPriceTick(price.PriceChange24h, info.TickSize), quoteAsset)
+		volume24h, _ := price.Volume24h.Float64()
+		if volumeUSD, err := s.quoteConverter.ToUSD(quoteAsset, volume24h); err == nil {
+			message += fmt.Sprintf("📊 **Volume 24h:** %s %s (~$%s)\n",
+				utils.FormatVolume(price.Volume24h), quoteAsset, utils.FormatVolume(decimal.NewFromFloat(volumeUSD)))
+		} else {
+			message += fmt.Sprintf("📊 **Volume 24h:** %s %s\n", utils.FormatVolume(price.Volume24h), quoteAsset)
+		}
+	}
 	loc := time.FixedZone("UTC+7", 7*60*60)
 	message += fmt.Sprintf("⏰ **Cập nhật:** %s", price.LastUpdated.In(loc).Format("15:04:05 02/01/2006"))
 
@@ -183,22 +228,17 @@ func (s *TelegramBotService) handlePriceCommand(chatID int64, symbol string) {
 }
 
 // handleAnalyzeCommand xử lý lệnh /analyze
-func (s *TelegramBotService) handleAnalyzeCommand(chatID int64, interval string, symbol string) {
-	// Validate interval
-	validIntervals := map[string]bool{
-		"1m": true, "5m": true, "15m": true, "30m": true,
-		"1h": true, "4h": true, "1d": true, "1w": true,
-	}
-
-	if !validIntervals[interval] {
-		s.sendMessage(chatID, "❌ Interval không hợp lệ. Các interval được hỗ trợ: 1m, 5m, 15m, 30m, 1h, 4h, 1d, 1w")
+func (s *TelegramBotService) handleAnalyzeCommand(chatID int64, interval string, symbol string, transform CandleTransform) {
+	// Validate interval: 1m, 5m, 15m, 30m, 1h, 4h, 1d, 1w lấy trực tiếp, interval khác dựng lại từ nến 1m/1h
+	if err := ValidateInterval(interval); err != nil {
+		s.sendMessage(chatID, fmt.Sprintf("❌ Interval không hợp lệ: %v. Ví dụ: 1m, 5m, 15m, 30m, 45m, 1h, 2h, 3h, 4h, 6h, 12h, 1d, 3d, 1w", err))
 		return
 	}
 
 	log.Printf("Bắt đầu phân tích symbol: %s với interval: %s", symbol, interval)
 
-	// Lấy dữ liệu kline (100 nến gần nhất)
-	klines, err := s.cryptoAPI.GetKlineData(symbol, interval, 100)
+	// Lấy dữ liệu kline (100 nến gần nhất), ưu tiên kho nến và chỉ lấy phần thiếu từ sàn
+	klines, err := s.candles.GetCandles(symbol, interval, transform.SourceLimit(100))
 	if err != nil {
 		s.sendMessage(chatID, fmt.Sprintf("❌ Lỗi khi lấy dữ liệu %s: %v", symbol, err))
 		return
@@ -206,8 +246,21 @@ func (s *TelegramBotService) handleAnalyzeCommand(chatID int64, interval string,
 
 	log.Printf("Đã lấy được %d điểm dữ liệu lịch sử với interval %s", len(klines), interval)
 
+	// Biến đổi nến (Heikin-Ashi, Renko) nếu được yêu cầu, kết quả lưu với interval dạng 4h/ha.
+	// Nến thật được giữ lại để tính giá hiện tại, volume và biến động.
+	var source []models.KlineData
+	if !transform.IsNone() {
+		source = klines
+		if klines, err = transform.Apply(source); err != nil {
+			s.sendMessage(chatID, fmt.Sprintf("❌ Lỗi khi dựng nến %s cho %s: %v", transform.Label(), symbol, err))
+			return
+		}
+		log.Printf("Dựng được %d nến %s", len(klines), transform.Label())
+		interval = interval + "/" + transform.Kind
+	}
+
 	// Phân tích với service indicators mới
-	analysis, err := s.indicators.AnalyzeCrypto(symbol, klines, interval)
+	analysis, err := s.indicators.AnalyzeCrypto(symbol, klines, source, interval, s.lookupSymbol(symbol))
 	if err != nil {
 		s.sendMessage(chatID, fmt.Sprintf("❌ Lỗi khi phân tích %s: %v", symbol, err))
 		return
@@ -217,38 +270,25 @@ func (s *TelegramBotService) handleAnalyzeCommand(chatID int64, interval string,
 	s.sendMessage(chatID, analysis)
 
 	// Lấy dữ liệu phân tích chi tiết để lưu vào database
-	analysisData, err := s.indicators.GetAnalysisData(symbol, klines, interval)
+	analysisData, err := s.indicators.GetAnalysisData(symbol, klines, source, interval)
 	if err != nil {
 		log.Printf("⚠️ Lỗi lấy dữ liệu phân tích: %v", err)
 		return
 	}
 
-	// Lưu kết quả phân tích vào database
-	if len(klines) > 0 {
-		latestKline := klines[len(klines)-1]
+	// Lưu kết quả phân tích vào database, giá và volume lấy từ nến thật
+	market := klines
+	if source != nil {
+		market = source
+	}
+	if len(market) > 0 {
+		latestKline := market[len(market)-1]
 
 		// Chuyển đổi kiểu dữ liệu
 		closePrice, _ := strconv.ParseFloat(latestKline.Close, 64)
 		volume, _ := strconv.ParseFloat(latestKline.QuoteAssetVolume, 64)
 
-		err := s.analysis.SaveAnalysis(
-			symbol,
-			interval,
-			closePrice,
-			volume,
-			analysisData.RSI,
-			analysisData.EMA9,
-			analysisData.EMA21,
-			analysisData.EMA50,
-			analysisData.MACD,
-			analysisData.MACDSignal,
-			analysisData.VolumeSMA,
-			analysisData.Trend,
-			analysisData.Power,
-			analysisData.Signal,
-			analysisData.Recommendation,
-			analysisData.VolumeSignal,
-		)
+		err := s.analysis.SaveAnalysis(closePrice, volume, analysisData)
 		if err != nil {
 			log.Printf("⚠️ Lỗi lưu phân tích: %v", err)
 		} else {
@@ -257,6 +297,23 @@ func (s *TelegramBotService) handleAnalyzeCommand(chatID int64, interval string,
 	}
 }
 
+// lookupSymbol lấy thông tin symbol đã lưu, nếu chưa có thì đoán quote asset theo cấu hình
+func (s *TelegramBotService) lookupSymbol(symbol string) models.Symbol {
+	if info, err := s.symbolRepo.GetBySymbol(symbol); err == nil {
+		return *info
+	}
+	info := models.Symbol{Symbol: symbol, QuoteAsset: "USDT"}
+	candidates := append(append([]string{}, config.AppConfig.QuoteAssets...), "USDT", "FDUSD", "USDC", "BTC", "ETH", "BNB")
+	for _, quoteAsset := range candidates {
+		if strings.HasSuffix(symbol, quoteAsset) && len(symbol) > len(quoteAsset) {
+			info.BaseAsset = strings.TrimSuffix(symbol, quoteAsset)
+			info.QuoteAsset = quoteAsset
+			break
+		}
+	}
+	return info
+}
+
 // sendWelcomeMessage gửi tin nhắn chào mừng
 func (s *TelegramBotService) sendWelcomeMessage(chatID int64) {
 	message := "🎉 **Chào mừng đến với Crypto Analysis Bot!**\n\n"
@@ -292,7 +349,8 @@ func (s *TelegramBotService) sendHelpMessage(chatID int64) {
 	message += "• `1h` - 1 giờ\n"
 	message += "• `4h` - 4 giờ\n"
 	message += "• `1d` - 1 ngày\n"
-	message += "• `1w` - 1 tuần\n\n"
+	message += "• `1w` - 1 tuần\n"
+	message += "• Interval khác (`45m`, `2h`, `3h`, `6h`, `12h`, `3d`...) được dựng lại từ nến 1m/1h, tối đa 7d\n\n"
 	message += "🔹 **Symbol được hỗ trợ:**\n"
 	message += "• BTCUSDT, ETHUSDT, ADAUSDT\n"
 	message += "• BNBUSDT, DOTUSDT, LINKUSDT\n"
@@ -303,7 +361,10 @@ func (s *TelegramBotService) sendHelpMessage(chatID int64) {
 	message += "💡 **Ví dụ sử dụng:**\n"
 	message += "• `/analyze 1h BTCUSDT` - Phân tích BTC theo nến 1h\n"
 	message += "• `/analyze 15m ETHUSDT` - Phân tích ETH theo nến 15m\n"
-	message += "• `/analyze 1d BNBUSDT` - Phân tích BNB theo nến 1 ngày\n\n"
+	message += "• `/analyze 1d BNBUSDT` - Phân tích BNB theo nến 1 ngày\n"
+	message += "• `/analyze 3h SOLUSDT` - Phân tích SOL theo nến 3h (dựng từ nến 1h)\n"
+	message += "• `/analyze 4h BTCUSDT ha` - Phân tích trên nến Heikin-Ashi\n"
+	message += "• `/analyze 1h ETHUSDT renko` - Phân tích trên brick Renko (box = ATR14, hoặc `renko:50` cho box cố định)\n\n"
 	message += "⚠️ **Lưu ý:**\n"
 	message += "• Chỉ mang tính chất tham khảo\n"
 	message += "• Không phải lời khuyên đầu tư\n"
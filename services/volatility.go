@@ -0,0 +1,157 @@
+package services
+
+import (
+	"math"
+
+	"chatbtc/models"
+)
+
+// BollingerSeries tính Bollinger Bands cho từng nến: middle là SMA period, upper/lower = middle ± multiplier
+// lần độ lệch chuẩn (tổng thể) của period giá. period-1 phần tử đầu là NaN.
+func (s *TechnicalAnalysisService) BollingerSeries(prices []float64, period int, multiplier float64) (middle, upper, lower []float64) {
+	middle, upper, lower = nanSeries(len(prices)), nanSeries(len(prices)), nanSeries(len(prices))
+	if period <= 0 {
+		return middle, upper, lower
+	}
+	for i := period - 1; i < len(prices); i++ {
+		window := prices[i-period+1 : i+1]
+		mean := s.calculateSMA(window, period)
+		var variance float64
+		for _, price := range window {
+			variance += (price - mean) * (price - mean)
+		}
+		deviation := math.Sqrt(variance / float64(period))
+		middle[i] = mean
+		upper[i] = mean + multiplier*deviation
+		lower[i] = mean - multiplier*deviation
+	}
+	return middle, upper, lower
+}
+
+// ATRSeries tính ATR làm mượt theo Wilder cho từng nến: ATR đầu tiên là trung bình period true range đầu,
+// sau đó atr = (atr*(period-1) + TR) / period. period phần tử đầu là NaN.
+func (s *TechnicalAnalysisService) ATRSeries(highs, lows, closes []float64, period int) []float64 {
+	return atrSeries(highs, lows, closes, period)
+}
+
+// atrSeries là phần tính của ATRSeries, dùng chung với averageTrueRange (Renko) để chỉ có một cách tính ATR
+func atrSeries(highs, lows, closes []float64, period int) []float64 {
+	series := nanSeries(len(closes))
+	if period <= 0 || len(closes) < period+1 {
+		return series
+	}
+
+	var atr float64
+	for i := 1; i < len(closes); i++ {
+		trueRange := math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+		if i <= period {
+			atr += trueRange / float64(period)
+			if i < period {
+				continue
+			}
+		} else {
+			atr = (atr*float64(period-1) + trueRange) / float64(period)
+		}
+		series[i] = atr
+	}
+	return series
+}
+
+// KeltnerSeries tính Keltner Channels cho từng nến: middle là EMA period của giá đóng cửa,
+// upper/lower = middle ± multiplier lần ATR(atrPeriod). Phần tử chưa đủ dữ liệu là NaN.
+func (s *TechnicalAnalysisService) KeltnerSeries(highs, lows, closes []float64, period, atrPeriod int, multiplier float64) (middle, upper, lower []float64) {
+	middle = s.EMASeries(closes, period)
+	atr := s.ATRSeries(highs, lows, closes, atrPeriod)
+	upper, lower = make([]float64, len(closes)), make([]float64, len(closes))
+	for i := range closes {
+		// NaN khi EMA hoặc ATR chưa có giá trị
+		upper[i] = middle[i] + multiplier*atr[i]
+		lower[i] = middle[i] - multiplier*atr[i]
+	}
+	return middle, upper, lower
+}
+
+// klineSeries tách giá high, low, close của các nến, bỏ qua nến có giá không parse được
+func klineSeries(klines []models.KlineData) (highs, lows, closes []float64) {
+	for _, k := range klines {
+		_, high, low, close, err := klineOHLC(k)
+		if err != nil {
+			continue
+		}
+		highs = append(highs, high)
+		lows = append(lows, low)
+		closes = append(closes, close)
+	}
+	return highs, lows, closes
+}
+
+// analyzeVolatility tính Bollinger Bands, ATR, Keltner Channels của nến cuối và phát hiện biến động:
+//   - squeeze: dải Bollinger nằm trong dải Keltner, hoặc bandwidth thấp nhất trong SQUEEZE_LOOKBACK nến trước
+//   - expansion: không squeeze, bandwidth đang tăng và vừa thoát squeeze hoặc gấp EXPANSION_RATIO lần trung bình
+func (s *TechnicalAnalysisService) analyzeVolatility(klines []models.KlineData) models.VolatilityAnalysis {
+	highs, lows, closes := klineSeries(klines)
+	n := len(closes)
+	if n < models.BB_PERIOD+1 || n < models.KELTNER_PERIOD+1 || n < models.ATR_PERIOD+2 {
+		return models.VolatilityAnalysis{}
+	}
+
+	bbMiddle, bbUpper, bbLower := s.BollingerSeries(closes, models.BB_PERIOD, models.BB_STDDEV)
+	atr := s.ATRSeries(highs, lows, closes, models.ATR_PERIOD)
+	kcMiddle, kcUpper, kcLower := s.KeltnerSeries(highs, lows, closes, models.KELTNER_PERIOD, models.ATR_PERIOD, models.KELTNER_ATR_MULT)
+
+	bandwidth := nanSeries(n)
+	for i := range closes {
+		if bbMiddle[i] != 0 {
+			bandwidth[i] = (bbUpper[i] - bbLower[i]) / bbMiddle[i]
+		}
+	}
+	insideKeltner := func(i int) bool {
+		// So sánh với NaN luôn false nên nến chưa đủ dữ liệu không bị tính là squeeze
+		return bbUpper[i] < kcUpper[i] && bbLower[i] > kcLower[i]
+	}
+
+	last := n - 1
+	result := models.VolatilityAnalysis{
+		BBUpper:       bbUpper[last],
+		BBMiddle:      bbMiddle[last],
+		BBLower:       bbLower[last],
+		PercentB:      0.5,
+		Bandwidth:     bandwidth[last],
+		ATR:           atr[last],
+		KeltnerUpper:  kcUpper[last],
+		KeltnerMiddle: kcMiddle[last],
+		KeltnerLower:  kcLower[last],
+	}
+	if bbUpper[last] > bbLower[last] {
+		result.PercentB = (closes[last] - bbLower[last]) / (bbUpper[last] - bbLower[last])
+	}
+
+	// Bandwidth thấp nhất và trung bình của các nến trước trong SQUEEZE_LOOKBACK
+	lowest, sum, count := math.Inf(1), 0.0, 0
+	for i := last - 1; i >= 0 && i >= last-models.SQUEEZE_LOOKBACK; i-- {
+		if math.IsNaN(bandwidth[i]) {
+			break
+		}
+		lowest = math.Min(lowest, bandwidth[i])
+		sum += bandwidth[i]
+		count++
+	}
+	lowestBandwidth := count >= models.BB_PERIOD && bandwidth[last] <= lowest
+
+	result.Squeeze = insideKeltner(last) || lowestBandwidth
+	if !result.Squeeze && count > 0 && bandwidth[last] > bandwidth[last-1] {
+		result.Expansion = insideKeltner(last-1) || bandwidth[last] >= models.EXPANSION_RATIO*sum/float64(count)
+	}
+
+	switch {
+	case math.IsNaN(result.Bandwidth) || math.IsNaN(result.KeltnerUpper):
+		return models.VolatilityAnalysis{}
+	case result.Squeeze:
+		result.State = "squeeze"
+	case result.Expansion:
+		result.State = "expansion"
+	default:
+		result.State = "normal"
+	}
+	return result
+}
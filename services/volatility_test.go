@@ -0,0 +1,116 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"chatbtc/models"
+)
+
+// chainedCloses trả về giá nến i từ chuỗi giá đóng cửa, open là close của nến trước
+func chainedCloses(closes []float64) func(i int) (float64, float64) {
+	return func(i int) (float64, float64) {
+		return closes[max(i-1, 0)], closes[i]
+	}
+}
+
+// closeKlines tạo nến 1h từ chuỗi giá đóng cửa
+func closeKlines(closes []float64) []models.KlineData {
+	return testKlines(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Hour, len(closes), chainedCloses(closes), nil)
+}
+
+func repeatPrice(price float64, n int) []float64 {
+	prices := make([]float64, n)
+	for i := range prices {
+		prices[i] = price
+	}
+	return prices
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBollingerSeries(t *testing.T) {
+	// Ví dụ kinh điển: trung bình 5, độ lệch chuẩn tổng thể 2
+	prices := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	middle, upper, lower := NewTechnicalAnalysisService().BollingerSeries(prices, 8, 2)
+	for i := 0; i < 7; i++ {
+		if !math.IsNaN(middle[i]) || !math.IsNaN(upper[i]) || !math.IsNaN(lower[i]) {
+			t.Errorf("index %d = %v/%v/%v, want NaN before the first full window", i, middle[i], upper[i], lower[i])
+		}
+	}
+	if !almostEqual(middle[7], 5) || !almostEqual(upper[7], 9) || !almostEqual(lower[7], 1) {
+		t.Errorf("bands = %v/%v/%v, want 5/9/1", middle[7], upper[7], lower[7])
+	}
+}
+
+func TestKeltnerSeries(t *testing.T) {
+	// Giá đứng yên ở 100, mỗi nến high/low ±1: EMA = 100, ATR = 2
+	n := 25
+	closes := repeatPrice(100, n)
+	highs, lows := repeatPrice(101, n), repeatPrice(99, n)
+	middle, upper, lower := NewTechnicalAnalysisService().KeltnerSeries(highs, lows, closes, 20, 14, 1.5)
+	if !math.IsNaN(upper[18]) || !math.IsNaN(lower[18]) {
+		t.Errorf("index 18 = %v/%v, want NaN before EMA20 is ready", upper[18], lower[18])
+	}
+	if !almostEqual(middle[24], 100) || !almostEqual(upper[24], 103) || !almostEqual(lower[24], 97) {
+		t.Errorf("channel = %v/%v/%v, want 100/103/97", middle[24], upper[24], lower[24])
+	}
+}
+
+func TestAverageTrueRangeUsesATRSeries(t *testing.T) {
+	klines := closeKlines([]float64{100, 104, 101, 108, 107, 103})
+	highs, lows, closes := klineSeries(klines)
+	series := NewTechnicalAnalysisService().ATRSeries(highs, lows, closes, 3)
+	// TR: 6, 5, 9, 3, 6 -> ATR3 đầu tiên (6+5+9)/3, sau đó làm mượt Wilder
+	want := []float64{math.NaN(), math.NaN(), math.NaN(), 20.0 / 3, (20.0/3*2 + 3) / 3}
+	want = append(want, (want[4]*2+6)/3)
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(series[i]) || (!math.IsNaN(want[i]) && !almostEqual(series[i], want[i])) {
+			t.Errorf("ATRSeries[%d] = %v, want %v", i, series[i], want[i])
+		}
+	}
+	if atr, err := averageTrueRange(klines, 3); err != nil || !almostEqual(atr, want[5]) {
+		t.Errorf("averageTrueRange = %v, %v; want %v", atr, err, want[5])
+	}
+}
+
+func TestAnalyzeVolatilityStates(t *testing.T) {
+	// Dao động mạnh rồi đi ngang: dải Bollinger co lại nằm trong Keltner
+	var wide []float64
+	for i := 0; i < 60; i++ {
+		wide = append(wide, 100+10*float64(i%2))
+	}
+	squeeze := append(append([]float64{}, wide...), repeatPrice(105, 25)...)
+	// Thoát đi ngang bằng một cây nến bứt phá
+	expansion := append(append([]float64{}, squeeze...), 125)
+	// Dao động hình sin biên độ tăng dần đều
+	var normal []float64
+	for i := 0; i < 90; i++ {
+		normal = append(normal, 100+10*math.Sin(float64(i)/3)*(1+float64(i)/90))
+	}
+
+	ta := NewTechnicalAnalysisService()
+	tests := []struct {
+		name      string
+		closes    []float64
+		state     string
+		squeeze   bool
+		expansion bool
+	}{
+		{"squeeze", squeeze, "squeeze", true, false},
+		{"expansion", expansion, "expansion", false, true},
+		{"normal", normal, "normal", false, false},
+		{"not enough candles", squeeze[:20], "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ta.analyzeVolatility(closeKlines(tt.closes))
+			if result.State != tt.state || result.Squeeze != tt.squeeze || result.Expansion != tt.expansion {
+				t.Errorf("state %q squeeze %v expansion %v, want %q %v %v", result.State, result.Squeeze, result.Expansion, tt.state, tt.squeeze, tt.expansion)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errSymbolSkipped được worker trả về khi symbol bị bỏ qua (không có dữ liệu, không đủ nến...)
+var errSymbolSkipped = errors.New("symbol bị bỏ qua")
+
+// skipSymbol tạo lỗi đánh dấu symbol bị bỏ qua kèm lý do
+func skipSymbol(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errSymbolSkipped, fmt.Sprintf(format, args...))
+}
+
+// RunSummary tổng kết một lượt xử lý nhiều symbol
+type RunSummary struct {
+	Name     string
+	Total    int
+	Fetched  int
+	Failed   int
+	Skipped  int
+	Errors   map[string]error
+	Duration time.Duration
+
+	// Duplicates là số symbol trùng lặp bị loại trước khi chạy, không tính vào Total
+	// nên Fetched + Failed + Skipped luôn bằng Total
+	Duplicates int
+}
+
+// String format summary để ghi log
+func (s RunSummary) String() string {
+	summary := fmt.Sprintf("%s: %d symbol, thành công %d, lỗi %d, bỏ qua %d, thời gian %v",
+		s.Name, s.Total, s.Fetched, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond))
+	if s.Duplicates > 0 {
+		summary += fmt.Sprintf(", loại %d symbol trùng", s.Duplicates)
+	}
+	if s.Failed == 0 {
+		return summary
+	}
+
+	// Chỉ liệt kê tối đa 10 lỗi để log không quá dài
+	symbols := make([]string, 0, len(s.Errors))
+	for symbol, err := range s.Errors {
+		if !errors.Is(err, errSymbolSkipped) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	var details []string
+	for i, symbol := range symbols {
+		if i == 10 {
+			details = append(details, fmt.Sprintf("... và %d lỗi khác", len(symbols)-10))
+			break
+		}
+		details = append(details, fmt.Sprintf("%s: %v", symbol, s.Errors[symbol]))
+	}
+	return summary + "\n- " + strings.Join(details, "\n- ")
+}
+
+// runSymbolPool chạy fn cho từng symbol với tối đa workers goroutine song song.
+// Các request tới Binance đi qua RateLimitedTransport nên worker sẽ tự chờ khi gần chạm giới hạn.
+func runSymbolPool(name string, symbols []string, workers int, fn func(symbol string) error) RunSummary {
+	start := time.Now()
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Loại bỏ symbol trùng lặp
+	seen := make(map[string]bool, len(symbols))
+	var unique []string
+	for _, symbol := range symbols {
+		if !seen[symbol] {
+			seen[symbol] = true
+			unique = append(unique, symbol)
+		}
+	}
+
+	summary := RunSummary{
+		Name:       name,
+		Total:      len(unique),
+		Errors:     make(map[string]error),
+		Duplicates: len(symbols) - len(unique),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range jobs {
+				err := fn(symbol)
+				mu.Lock()
+				switch {
+				case err == nil:
+					summary.Fetched++
+				case errors.Is(err, errSymbolSkipped):
+					summary.Skipped++
+					summary.Errors[symbol] = err
+				default:
+					summary.Failed++
+					summary.Errors[symbol] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, symbol := range unique {
+		jobs <- symbol
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary.Duration = time.Since(start)
+	return summary
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunSymbolPoolCountsAddUpToTotal(t *testing.T) {
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BTCUSDT", "SOLUSDT", "XRPUSDT", "ETHUSDT"}
+	summary := runSymbolPool("test", symbols, 3, func(symbol string) error {
+		switch symbol {
+		case "SOLUSDT":
+			return skipSymbol("không có dữ liệu")
+		case "XRPUSDT":
+			return errors.New("lỗi mạng")
+		}
+		return nil
+	})
+
+	if summary.Total != 4 || summary.Duplicates != 2 {
+		t.Errorf("Total = %d, Duplicates = %d; want 4 and 2", summary.Total, summary.Duplicates)
+	}
+	if summary.Fetched != 2 || summary.Skipped != 1 || summary.Failed != 1 {
+		t.Errorf("Fetched/Skipped/Failed = %d/%d/%d, want 2/1/1", summary.Fetched, summary.Skipped, summary.Failed)
+	}
+	if sum := summary.Fetched + summary.Skipped + summary.Failed; sum != summary.Total {
+		t.Errorf("Fetched+Skipped+Failed = %d, want Total %d", sum, summary.Total)
+	}
+}
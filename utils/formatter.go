@@ -99,3 +99,40 @@ func FormatElements(elements ...string) string {
 	}
 	return strings.Join(validElements, ", ")
 }
+
+// TickDecimals trả về số chữ số thập phân có nghĩa của tick size (ví dụ "0.01000000" -> 2)
+func TickDecimals(tickSize string) int {
+	trimmed := strings.TrimRight(strings.TrimSpace(tickSize), "0")
+	idx := strings.Index(trimmed, ".")
+	if idx < 0 {
+		return 0
+	}
+	return len(trimmed) - idx - 1
+}
+
+// FormatPriceTick làm tròn giá theo tick size của sàn và format với dấu phẩy ngăn cách.
+// Nếu tick size không hợp lệ (symbol chưa có filter) thì dùng FormatPrice.
+func FormatPriceTick(price decimal.Decimal, tickSize string) string {
+	tick, err := decimal.NewFromString(strings.TrimSpace(tickSize))
+	if err != nil || !tick.IsPositive() {
+		return FormatPrice(price)
+	}
+
+	rounded := price.DivRound(tick, 0).Mul(tick)
+	formatted := rounded.StringFixed(int32(TickDecimals(tickSize)))
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign = "-"
+		formatted = formatted[1:]
+	}
+	parts := strings.Split(formatted, ".")
+	integerPart := parts[0]
+	for i := len(integerPart) - 3; i > 0; i -= 3 {
+		integerPart = integerPart[:i] + "," + integerPart[i:]
+	}
+	if len(parts) > 1 {
+		return sign + integerPart + "." + parts[1]
+	}
+	return sign + integerPart
+}
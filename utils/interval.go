@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// IntervalDuration chuyển interval dạng Binance (1m, 4h, 1d, 1w...) sang time.Duration.
+// Interval theo tháng (1M) không có độ dài cố định nên trả về lỗi.
+func IntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("interval không hợp lệ: %q", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("interval không hợp lệ: %q", interval)
+	}
+
+	var unit time.Duration
+	switch interval[len(interval)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("interval không hỗ trợ: %q", interval)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// AlignTime làm tròn xuống t theo bội số của d tính từ Unix epoch (cách Binance chia nến)
+func AlignTime(t time.Time, d time.Duration) time.Time {
+	step := d.Milliseconds()
+	if step <= 0 {
+		return t
+	}
+	ms := t.UnixMilli()
+	return time.UnixMilli(ms - ((ms%step)+step)%step).UTC()
+}